@@ -0,0 +1,160 @@
+// actions.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ActionHandler implements a server action. Vraćena vrednost se serijalizuje
+// u "result" polje odgovora; *ValidationError se vraća klijentu kao 400.
+type ActionHandler func(ctx context.Context, call *ActionCall) (interface{}, error)
+
+// ActionDefinition describes a named server function attached to a module
+// (po uzoru na Jam.py serverske funkcije).
+type ActionDefinition struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	OnRecord    bool               `json:"on_record"`       // Akcija se poziva nad pojedinačnim zapisom
+	Params      []ColumnDefinition `json:"params"`          // Parametri se validiraju kao payload (po DBColumnName)
+	Roles       []string           `json:"roles,omitempty"` // Uloge kojima je akcija dozvoljena; prazno = svima
+	Handler     ActionHandler      `json:"-"`
+}
+
+// ActionCall carries everything an action handler needs.
+type ActionCall struct {
+	Module   *ModuleDefinition
+	RecordID string                 // Prazno za akcije na nivou modula
+	Record   map[string]interface{} // Zaključan zapis (SELECT ... FOR UPDATE) za akcije nad zapisom
+	Params   map[string]interface{}
+	User     *User
+	Dataset  *SQLDataset
+	Tx       *sql.Tx // Transakcija u kojoj se akcija izvršava
+}
+
+// ActionParam is a shorthand for declaring an action parameter.
+func ActionParam(name, paramType, validation string) ColumnDefinition {
+	return ColumnDefinition{
+		ID:           name,
+		Name:         name,
+		DBColumnName: name,
+		Type:         paramType,
+		Validation:   validation,
+		IsEditable:   true,
+		IsVisible:    true,
+	}
+}
+
+// ActionRegistry stores server actions keyed by module ID and action name.
+type ActionRegistry struct {
+	mu      sync.RWMutex
+	config  *AppConfig
+	actions map[string]map[string]*ActionDefinition
+}
+
+// NewActionRegistry creates an empty action registry.
+func NewActionRegistry(config *AppConfig) *ActionRegistry {
+	return &ActionRegistry{config: config, actions: make(map[string]map[string]*ActionDefinition)}
+}
+
+// Register attaches an action to a module. Ponovna registracija istog imena menja akciju.
+func (r *ActionRegistry) Register(moduleID string, action ActionDefinition) error {
+	if action.Name == "" || action.Handler == nil {
+		return fmt.Errorf("akcija za modul '%s' mora imati ime i handler", moduleID)
+	}
+	if r.config.GetModuleByID(moduleID) == nil {
+		return fmt.Errorf("modul sa ID '%s' nije pronađen za akciju '%s'", moduleID, action.Name)
+	}
+
+	// Regex pravila parametara se kompiliraju kao i za kolone modula
+	r.config.compileColumnRegexes(action.Params)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.actions[moduleID] == nil {
+		r.actions[moduleID] = make(map[string]*ActionDefinition)
+	}
+	r.actions[moduleID][action.Name] = &action
+	return nil
+}
+
+// Get returns the named action of a module, or nil.
+func (r *ActionRegistry) Get(moduleID, name string) *ActionDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.actions[moduleID][name]
+}
+
+// List returns the actions of a module sorted by name.
+func (r *ActionRegistry) List(moduleID string) []*ActionDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]*ActionDefinition, 0, len(r.actions[moduleID]))
+	for _, action := range r.actions[moduleID] {
+		list = append(list, action)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ListAllowed returns the module's actions the user is allowed to call.
+// Korisnik bez dozvole za čitanje modula nema dostupnih akcija, a akcije nad
+// zapisom traže i dozvolu za izmenu (kao RunAction handler).
+func (r *ActionRegistry) ListAllowed(moduleID string, user *User) []*ActionDefinition {
+	allowed := make([]*ActionDefinition, 0)
+	moduleDef := r.config.GetModuleByID(moduleID)
+	if moduleDef == nil || !moduleDef.UserCan(user, PermRead) {
+		return allowed
+	}
+	for _, action := range r.List(moduleID) {
+		if action.OnRecord && !moduleDef.UserCan(user, PermUpdate) {
+			continue
+		}
+		if user.HasAnyRole(action.Roles) {
+			allowed = append(allowed, action)
+		}
+	}
+	return allowed
+}
+
+// RunAction executes an action inside a transaction. Za akcije nad zapisom,
+// zapis se zaključava i prosleđuje handler-u.
+func (s *SQLDataset) RunAction(ctx context.Context, moduleDef *ModuleDefinition, action *ActionDefinition, recordID string, params map[string]interface{}) (interface{}, error) {
+	var result interface{}
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		call := &ActionCall{
+			Module:   moduleDef,
+			RecordID: recordID,
+			Params:   params,
+			User:     UserFromContext(ctx),
+			Dataset:  s,
+			Tx:       tx,
+		}
+
+		if action.OnRecord {
+			pkCol := s.getPrimaryKeyColumn(moduleDef)
+			if pkCol == nil {
+				return fmt.Errorf("modul '%s' nema definisan primarni ključ za akciju '%s'", moduleDef.Name, action.Name)
+			}
+			record, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeActive)
+			if err == sql.ErrNoRows {
+				return errRecordNotFound("zapis sa ID '%s' nije pronađen u modulu '%s'", recordID, moduleDef.Name)
+			}
+			if err != nil {
+				return err
+			}
+			call.Record = record
+		}
+
+		var err error
+		result, err = action.Handler(ctx, call)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,145 @@
+// actions_test.go
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testActionsServer pravi server bez baze sa modulom čije čitanje i izmena traže uloge.
+func testActionsServer(t *testing.T) *APIServer {
+	t.Helper()
+	module := testEmployeesModule()
+	module.Permissions = map[string][]string{PermRead: {"staff", "hr"}, PermUpdate: {"hr"}}
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{module.ID: module}, Rules: NewRuleRegistry(), compiledRegexes: map[string]*regexp.Regexp{}}
+	return NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+}
+
+func TestActionRegistryRegister(t *testing.T) {
+	s := testActionsServer(t)
+	noop := func(ctx context.Context, call *ActionCall) (interface{}, error) { return nil, nil }
+
+	if err := s.Actions.Register("test_employees", ActionDefinition{Name: "bez_handlera"}); err == nil {
+		t.Error("akcija bez handler-a je registrovana")
+	}
+	if err := s.Actions.Register("nepostojeci", ActionDefinition{Name: "x", Handler: noop}); err == nil {
+		t.Error("akcija nepostojećeg modula je registrovana")
+	}
+	if err := s.Actions.Register("test_employees", ActionDefinition{Name: "povisica", Handler: noop,
+		Params: []ColumnDefinition{ActionParam("iznos", "integer", "required,regex:^[0-9]+$")}}); err != nil {
+		t.Fatal(err)
+	}
+	if action := s.Actions.Get("test_employees", "povisica"); action == nil {
+		t.Error("registrovana akcija nije pronađena")
+	}
+	if _, ok := s.config.GetCompiledRegex("^[0-9]+$"); !ok {
+		t.Error("regex pravilo parametra nije kompilirano")
+	}
+}
+
+func TestActionRegistryListAllowed(t *testing.T) {
+	s := testActionsServer(t)
+	noop := func(ctx context.Context, call *ActionCall) (interface{}, error) { return nil, nil }
+	for _, action := range []ActionDefinition{
+		{Name: "izvestaj", Handler: noop},
+		{Name: "zakljucaj", Handler: noop, Roles: []string{"admin"}},
+		{Name: "povisica", Handler: noop, OnRecord: true},
+	} {
+		if err := s.Actions.Register("test_employees", action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		user *User
+		want string
+	}{
+		{nil, ""},
+		{&User{ID: "gost", Roles: []string{"guest"}}, ""},
+		{&User{ID: "ana", Roles: []string{"staff"}}, "izvestaj"},
+		{&User{ID: "hr", Roles: []string{"hr"}}, "izvestaj,povisica"},
+		{&User{ID: "root", Roles: []string{"hr", "admin"}}, "izvestaj,povisica,zakljucaj"},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, action := range s.Actions.ListAllowed("test_employees", tt.user) {
+			names = append(names, action.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("ListAllowed(%v) = %q, očekivano %q", tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestRunActionChecksPermissionsAndParams(t *testing.T) {
+	s := testActionsServer(t)
+	noop := func(ctx context.Context, call *ActionCall) (interface{}, error) { return nil, nil }
+	for _, action := range []ActionDefinition{
+		{Name: "izvestaj", Handler: noop, Params: []ColumnDefinition{ActionParam("godina", "integer", "required")}},
+		{Name: "zakljucaj", Handler: noop, Roles: []string{"admin"}},
+		{Name: "povisica", Handler: noop, OnRecord: true},
+	} {
+		if err := s.Actions.Register("test_employees", action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		body   string
+		roles  string
+		status int
+	}{
+		{"/api/modules/test_employees/actions/izvestaj", `{"godina": 2024}`, "guest", http.StatusForbidden},
+		{"/api/modules/test_employees/actions/zakljucaj", "", "staff", http.StatusForbidden},
+		{"/api/modules/test_employees/1/actions/povisica", "", "staff", http.StatusForbidden},
+		{"/api/modules/test_employees/actions/izvestaj", `{}`, "staff", http.StatusBadRequest},
+		{"/api/modules/test_employees/actions/izvestaj", `{"godina": "nije broj"}`, "staff", http.StatusBadRequest},
+		{"/api/modules/test_employees/actions/nepostojeca", "", "staff", http.StatusNotFound},
+		{"/api/modules/test_employees/actions/povisica", "", "hr", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := serveTest(s, http.MethodPost, tt.path, tt.body, tt.roles, nil)
+		if rec.Code != tt.status {
+			t.Errorf("%s (%s, uloge %q): status %d, očekivano %d; telo %s", tt.path, tt.body, tt.roles, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	if rec := serveTest(s, http.MethodGet, "/api/modules/test_employees/actions", "", "guest", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("lista akcija bez dozvole za čitanje: status %d, očekivano 403", rec.Code)
+	}
+}
+
+func TestRunRecordAction(t *testing.T) {
+	module := testEmployeesModule()
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, name TEXT NOT NULL, salary INT, version INT NOT NULL DEFAULT 1")
+	if _, err := ds.db.Exec("INSERT INTO test_employees (id, name, salary) VALUES (1, 'Ana', 1000)"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAPIServer(ds.config, ds)
+	err := s.Actions.Register(module.ID, ActionDefinition{Name: "povisica", OnRecord: true,
+		Params: []ColumnDefinition{ActionParam("iznos", "integer", "required")},
+		Handler: func(ctx context.Context, call *ActionCall) (interface{}, error) {
+			_, err := call.Tx.ExecContext(ctx, "UPDATE test_employees SET salary = salary + $1 WHERE id = $2", call.Params["iznos"], call.Record["id"])
+			return call.Record["name"], err
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serveTest(s, http.MethodPost, "/api/modules/test_employees/1/actions/povisica", `{"iznos": 500}`, "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"result":"Ana"`) {
+		t.Errorf("akcija nad zapisom: status %d, telo %s", rec.Code, rec.Body)
+	}
+	var salary int
+	if err := ds.db.QueryRow("SELECT salary FROM test_employees WHERE id = 1").Scan(&salary); err != nil || salary != 1500 {
+		t.Errorf("plata posle akcije: %d, %v", salary, err)
+	}
+	if rec := serveTest(s, http.MethodPost, "/api/modules/test_employees/99/actions/povisica", `{"iznos": 1}`, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("akcija nad nepostojećim zapisom: status %d, očekivano 404; telo %s", rec.Code, rec.Body)
+	}
+}
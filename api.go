@@ -2,11 +2,16 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"strconv" // Potrebno za strconv.Atoi
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -17,6 +22,7 @@ type APIServer struct {
 	config  *AppConfig
 	dataset *SQLDataset
 	router  *mux.Router
+	Actions *ActionRegistry // Serverske akcije (RPC funkcije) po modulima
 }
 
 // NewAPIServer kreira novu instancu APIServer-a.
@@ -25,6 +31,7 @@ func NewAPIServer(config *AppConfig, dataset *SQLDataset) *APIServer {
 		config:  config,
 		dataset: dataset,
 		router:  mux.NewRouter(),
+		Actions: NewActionRegistry(config),
 	}
 	s.InitRoutes() // Inicijalizuj rute odmah po kreiranju servera
 	return s
@@ -32,12 +39,41 @@ func NewAPIServer(config *AppConfig, dataset *SQLDataset) *APIServer {
 
 // InitRoutes inicijalizuje sve API rute.
 func (s *APIServer) InitRoutes() {
+	s.router.Use(requestIDMiddleware, s.languageMiddleware, userMiddleware)
+	s.router.NotFoundHandler = requestIDMiddleware(s.languageMiddleware(http.HandlerFunc(notFoundHandler)))
+	s.router.MethodNotAllowedHandler = requestIDMiddleware(s.languageMiddleware(http.HandlerFunc(methodNotAllowedHandler)))
+
 	s.router.HandleFunc("/api/modules", s.GetAllModules).Methods("GET")
+	// Specifične rute moraju biti registrovane pre generičkih /{moduleID}/{recordID} ruta
+	s.router.HandleFunc("/api/modules/{moduleID}/schema", s.GetModuleSchema).Methods("GET")
+	s.router.HandleFunc("/api/modules/{moduleID}/actions", s.ListModuleActions).Methods("GET")
+	s.router.HandleFunc("/api/modules/{moduleID}/lookups/{column}", s.SearchLookupOptions).Methods("GET")
+	s.router.HandleFunc("/api/modules/{moduleID}/events", s.StreamModuleEvents).Methods("GET")
+	s.router.HandleFunc("/api/modules/{moduleID}/export", s.ExportModuleRecords).Methods("GET")
+	s.router.HandleFunc("/api/modules/{moduleID}/import", s.ImportModuleRecords).Methods("POST")
+	s.router.HandleFunc("/api/modules/{moduleID}/bulk", s.idempotent(s.BulkCreateRecords)).Methods("POST")
+	s.router.HandleFunc("/api/modules/{moduleID}/bulk", s.idempotent(s.BulkUpdateRecords)).Methods("PATCH")
+	s.router.HandleFunc("/api/modules/{moduleID}/bulk", s.idempotent(s.BulkDeleteRecords)).Methods("DELETE")
+	s.router.HandleFunc("/api/modules/{moduleID}/upsert", s.idempotent(s.UpsertModuleRecords)).Methods("POST")
+	s.router.HandleFunc("/api/modules/{moduleID}/actions/{name}", s.RunAction).Methods("POST")
+	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}/actions/{name}", s.RunAction).Methods("POST")
 	s.router.HandleFunc("/api/modules/{moduleID}", s.GetModuleRecords).Methods("GET")
 	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}", s.GetSingleRecord).Methods("GET")
-	s.router.HandleFunc("/api/modules/{moduleID}", s.CreateRecord).Methods("POST")
+	s.router.HandleFunc("/api/modules/{moduleID}", s.idempotent(s.CreateRecord)).Methods("POST")
 	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}", s.UpdateRecord).Methods("PUT")
+	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}", s.PatchRecord).Methods("PATCH")
 	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}", s.DeleteRecord).Methods("DELETE")
+	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}/history", s.GetRecordHistory).Methods("GET")
+	s.router.HandleFunc("/api/modules/{moduleID}/{recordID}/restore", s.RestoreRecord).Methods("POST")
+
+	s.router.HandleFunc("/api/batch", s.idempotent(s.RunBatch)).Methods("POST")
+	s.router.HandleFunc("/api/openapi.json", s.GetOpenAPI).Methods("GET")
+
+	s.router.HandleFunc("/api/webhooks", s.ListWebhooks).Methods("GET")
+	s.router.HandleFunc("/api/webhooks", s.CreateWebhook).Methods("POST")
+	s.router.HandleFunc("/api/webhooks/{webhookID}", s.DeleteWebhook).Methods("DELETE")
+	s.router.HandleFunc("/api/webhooks/{webhookID}/deliveries", s.GetWebhookDeliveries).Methods("GET")
+	s.router.HandleFunc("/api/webhooks/{webhookID}/deliveries/{deliveryID}/retry", s.RetryWebhookDelivery).Methods("POST")
 }
 
 // Start pokreće HTTP server.
@@ -62,18 +98,23 @@ func (s *APIServer) GetAllModules(w http.ResponseWriter, req *http.Request) {
 		Type     string   `json:"type"`
 		Children []UINode `json:"children,omitempty"`
 		Icon     string   `json:"icon,omitempty"`
+		Actions  []string `json:"actions,omitempty"` // Serverske akcije dostupne korisniku
 	}
 
 	var appRoot *UINode = nil
 	groupNodes := make(map[string]UINode)
 	moduleNodes := make(map[string]UINode)
+	lang := languageFromContext(req.Context())
 
 	for _, moduleDef := range s.config.Modules { // Koristimo s.config
 		node := UINode{
 			ID:   moduleDef.ID,
-			Name: moduleDef.Name,
+			Name: localize(moduleDef.Name, moduleDef.NameI18n, lang),
 			Type: moduleDef.Type,
 		}
+		for _, action := range s.Actions.ListAllowed(moduleDef.ID, UserFromContext(req.Context())) {
+			node.Actions = append(node.Actions, action.Name)
+		}
 
 		if moduleDef.Type == "root" {
 			appRoot = &node
@@ -114,7 +155,7 @@ func (s *APIServer) GetAllModules(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(appRoot); err != nil {
 		log.Printf("ERROR: Greška pri enkodiranju tree strukture modula: %v", err)
-		http.Error(w, "Interna serverska greška pri vraćanju modula", http.StatusInternalServerError)
+		writeError(w, "Interna serverska greška pri vraćanju modula", http.StatusInternalServerError)
 		return
 	}
 	log.Println("INFO: Vraćena tree struktura modula.")
@@ -127,19 +168,31 @@ func (s *APIServer) GetModuleRecords(w http.ResponseWriter, req *http.Request) {
 
 	moduleDef := s.config.GetModuleByID(moduleID) // Koristimo s.config
 	if moduleDef == nil {
-		http.Error(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermRead) {
 		return
 	}
 
-	records, err := s.dataset.GetRecords(moduleDef, req.URL.Query()) // Koristimo s.dataset
+	ctx, ok := expandContext(w, req, moduleDef)
+	if !ok {
+		return
+	}
+
+	records, err := s.dataset.GetRecords(ctx, moduleDef, req.URL.Query()) // Koristimo s.dataset
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri dohvatanju zapisa za modul '%s': %v", moduleID, err), http.StatusInternalServerError)
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri dohvatanju zapisa za modul '%s'", moduleID), err)
 		return
 	}
+	hideUnreadableColumns(moduleDef, UserFromContext(req.Context()), records...)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(records); err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri enkodiranju zapisa: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, "Greška pri enkodiranju zapisa", err)
 		return
 	}
 	log.Printf("INFO: Vraćeno %d zapisa za modul '%s'.", len(records), moduleID)
@@ -153,32 +206,45 @@ func (s *APIServer) GetSingleRecord(w http.ResponseWriter, req *http.Request) {
 
 	moduleDef := s.config.GetModuleByID(moduleID) // Koristimo s.config
 	if moduleDef == nil {
-		http.Error(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermRead) {
 		return
 	}
 
-	pkCol := s.dataset.getPrimaryKeyColumn(moduleDef) // Koristimo s.dataset
-	var parsedRecordID interface{}
-	if pkCol != nil && pkCol.Type == "integer" {
-		id, err := strconv.Atoi(recordID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Nevažeći ID zapisa za modul '%s': %v", moduleID, err), http.StatusBadRequest)
-			return
-		}
-		parsedRecordID = id
-	} else {
-		parsedRecordID = recordID
+	parsedRecordID, err := s.parseRecordID(moduleDef, recordID)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Nevažeći ID zapisa za modul '%s': %v", moduleID, err), http.StatusBadRequest)
+		return
 	}
 
-	record, err := s.dataset.GetRecordByID(moduleDef, parsedRecordID) // Koristimo s.dataset
+	ctx, ok := expandContext(w, req, moduleDef)
+	if !ok {
+		return
+	}
+
+	record, err := s.dataset.GetRecordByID(ctx, moduleDef, parsedRecordID, deletedScopeFromQuery(req.URL.Query())) // Koristimo s.dataset
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri dohvatanju zapisa sa ID '%s' za modul '%s': %v", recordID, moduleID, err), http.StatusInternalServerError)
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri dohvatanju zapisa sa ID '%s' za modul '%s'", recordID, moduleID), err)
 		return
 	}
 
+	if etag := recordETag(moduleDef, record); etag != "" {
+		w.Header().Set("ETag", etag)
+		if inm := req.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	hideUnreadableColumns(moduleDef, UserFromContext(req.Context()), record)
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(record); err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri enkodiranju zapisa: %v", err), http.StatusInternalServerError)
+		writeInternalError(w, "Greška pri enkodiranju zapisa", err)
 		return
 	}
 	log.Printf("INFO: Vraćen zapis sa ID '%v' za modul '%s'.", parsedRecordID, moduleID)
@@ -191,38 +257,56 @@ func (s *APIServer) CreateRecord(w http.ResponseWriter, req *http.Request) { //
 
 	moduleDef := s.config.GetModuleByID(moduleID) // Koristimo s.config
 	if moduleDef == nil {
-		http.Error(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermCreate) {
 		return
 	}
 
 	var payload map[string]interface{}
 	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
+		writeError(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Validacija payload-a - validatePayload je i dalje samostalna funkcija, ali joj prosleđujemo s.config
-	if err := validatePayload(payload, moduleDef.Columns, s.config); err != nil {
-		http.Error(w, fmt.Sprintf("Greška validacije payload-a: %v", err), http.StatusBadRequest)
+	// Validacija payload-a, uključujući pravila koja čitaju bazu (unique, exists)
+	if err := s.dataset.validateRecord(req.Context(), validationTarget{Module: moduleDef}, payload); err != nil {
+		if !writeClientError(w, err) {
+			writeInternalError(w, fmt.Sprintf("Greška pri validaciji zapisa za modul '%s'", moduleID), err)
+		}
 		return
 	}
 
-	newID, err := s.dataset.CreateRecord(moduleDef, payload) // Koristimo s.dataset
+	newID, err := s.dataset.CreateRecord(req.Context(), moduleDef, payload) // Koristimo s.dataset
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri kreiranju zapisa za modul '%s': %v", moduleID, err), http.StatusInternalServerError)
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri kreiranju zapisa za modul '%s'", moduleID), err)
 		return
 	}
 
+	response := map[string]interface{}{"message": "Zapis uspešno kreiran", "id": newID}
+	// Vraćamo i sačuvan zapis, uključujući vrednosti koje je popunio server (npr. created_at)
+	if record, etag := s.loadRecordForResponse(req.Context(), moduleDef, newID); record != nil {
+		response["record"] = record
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{"message": "Zapis uspešno kreiran", "id": newID}); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("ERROR: Greška pri enkodiranju odgovora za CreateRecord: %v", err)
-		http.Error(w, "Interna serverska greška", http.StatusInternalServerError)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
 	}
 	log.Printf("INFO: Kreiran zapis sa ID '%v' za modul '%s'.", newID, moduleID)
 }
 
-// UpdateRecord handles requests to update an existing record for a module.
+// UpdateRecord handles PUT requests: full replacement of an existing record.
+// Obavezna polja moraju biti poslata, a izostavljena opciona polja se vraćaju na podrazumevane vrednosti.
 func (s *APIServer) UpdateRecord(w http.ResponseWriter, req *http.Request) { // Metoda APIServera
 	vars := mux.Vars(req)
 	moduleID := vars["moduleID"]
@@ -230,34 +314,128 @@ func (s *APIServer) UpdateRecord(w http.ResponseWriter, req *http.Request) { //
 
 	moduleDef := s.config.GetModuleByID(moduleID) // Koristimo s.config
 	if moduleDef == nil {
-		http.Error(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermUpdate) {
 		return
 	}
 
 	var payload map[string]interface{}
 	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
+		writeError(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
 		return
 	}
 
 	// Validacija payload-a
-	if err := validatePayload(payload, moduleDef.Columns, s.config); err != nil {
-		http.Error(w, fmt.Sprintf("Greška validacije payload-a: %v", err), http.StatusBadRequest)
+	if err := s.dataset.validateRecord(req.Context(), validationTarget{Module: moduleDef, RecordID: recordID}, payload); err != nil {
+		if !writeClientError(w, err) {
+			writeInternalError(w, fmt.Sprintf("Greška pri validaciji zapisa za modul '%s'", moduleID), err)
+		}
 		return
 	}
 
-	err := s.dataset.UpdateRecord(moduleDef, recordID, payload) // Koristimo s.dataset
+	updated, err := s.dataset.ReplaceRecord(req.Context(), moduleDef, recordID, payload, req.Header.Get("If-Match")) // Koristimo s.dataset
+	s.writeUpdateResult(w, req, moduleDef, recordID, updated, err)
+}
+
+// PatchRecord handles PATCH requests: partial update of an existing record.
+// Prihvata JSON Merge Patch (application/json ili application/merge-patch+json), gde se
+// validiraju samo poslata polja, a null briše vrednost, i JSON Patch (application/json-patch+json).
+func (s *APIServer) PatchRecord(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	moduleID := vars["moduleID"]
+	recordID := vars["recordID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermUpdate) {
+		return
+	}
+
+	mediaType := "application/json"
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			writeError(w, fmt.Sprintf("Nevažeći Content-Type '%s'.", ct), http.StatusUnsupportedMediaType)
+			return
+		}
+		mediaType = parsed
+	}
+
+	var updated map[string]interface{}
+	var err error
+	switch mediaType {
+	case "application/json", mergePatchContentType:
+		var payload map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			writeError(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
+			return
+		}
+		// Validiraju se samo poslata polja; "required" ne dozvoljava null
+		target := validationTarget{Module: moduleDef, RecordID: recordID, Partial: true}
+		if err := s.dataset.validateRecord(req.Context(), target, payload); err != nil {
+			if !writeClientError(w, err) {
+				writeInternalError(w, fmt.Sprintf("Greška pri validaciji zapisa za modul '%s'", moduleID), err)
+			}
+			return
+		}
+		updated, err = s.dataset.UpdateRecord(req.Context(), moduleDef, recordID, payload, req.Header.Get("If-Match"))
+	case jsonPatchContentType:
+		var ops []JSONPatchOperation
+		if err := json.NewDecoder(req.Body).Decode(&ops); err != nil {
+			writeError(w, fmt.Sprintf("Greška pri dekodiranju JSON Patch-a: %v", err), http.StatusBadRequest)
+			return
+		}
+		updated, err = s.dataset.PatchRecord(req.Context(), moduleDef, recordID, ops, req.Header.Get("If-Match"))
+	default:
+		w.Header().Set("Accept-Patch", strings.Join([]string{"application/json", mergePatchContentType, jsonPatchContentType}, ", "))
+		writeError(w, fmt.Sprintf("Nepodržan Content-Type '%s' za PATCH.", mediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	s.writeUpdateResult(w, req, moduleDef, recordID, updated, err)
+}
+
+// writeUpdateResult vraća odgovor na PUT/PATCH: ažuriran zapis sa ETag-om, ili odgovarajuću grešku.
+func (s *APIServer) writeUpdateResult(w http.ResponseWriter, req *http.Request, moduleDef *ModuleDefinition, recordID string, updated map[string]interface{}, err error) {
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri ažuriranju zapisa sa ID '%s' za modul '%s': %v", recordID, moduleID, err), http.StatusInternalServerError)
+		var conflict *PreconditionFailedError
+		if errors.As(err, &conflict) {
+			s.writePreconditionFailed(w, req, moduleDef, recordID, conflict)
+			return
+		}
+		if errors.Is(err, errPatchTestFailed) {
+			writeErrorCode(w, ErrCodePatchTestFailed, fmt.Sprintf("Greška pri primeni JSON Patch-a: %v", err), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, errPatchColumnHidden) {
+			writeErrorCode(w, ErrCodeForbidden, fmt.Sprintf("Greška pri primeni JSON Patch-a: %v", err), http.StatusForbidden)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri ažuriranju zapisa sa ID '%s' za modul '%s'", recordID, moduleDef.ID), err)
 		return
 	}
 
+	if etag := recordETag(moduleDef, updated); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	response := map[string]interface{}{"message": "Zapis uspešno ažuriran"}
+	if record, _ := s.loadRecordForResponse(req.Context(), moduleDef, updated[s.dataset.getPrimaryKeyColumn(moduleDef).DBColumnName]); record != nil {
+		response["record"] = record
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Zapis uspešno ažuriran"}); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("ERROR: Greška pri enkodiranju odgovora za UpdateRecord: %v", err)
-		http.Error(w, "Interna serverska greška", http.StatusInternalServerError)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
 	}
-	log.Printf("INFO: Ažuriran zapis sa ID '%s' za modul '%s'.", recordID, moduleID)
+	log.Printf("INFO: Ažuriran zapis sa ID '%s' za modul '%s'.", recordID, moduleDef.ID)
 }
 
 // DeleteRecord handles requests to delete an existing record for a module.
@@ -268,20 +446,259 @@ func (s *APIServer) DeleteRecord(w http.ResponseWriter, req *http.Request) { //
 
 	moduleDef := s.config.GetModuleByID(moduleID) // Koristimo s.config
 	if moduleDef == nil {
-		http.Error(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermDelete) {
 		return
 	}
 
-	err := s.dataset.DeleteRecord(moduleDef, recordID) // Koristimo s.dataset
+	err := s.dataset.DeleteRecord(req.Context(), moduleDef, recordID, req.Header.Get("If-Match")) // Koristimo s.dataset
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Greška pri brisanju zapisa sa ID '%s' za modul '%s': %v", recordID, moduleID, err), http.StatusInternalServerError)
+		var conflict *PreconditionFailedError
+		if errors.As(err, &conflict) {
+			s.writePreconditionFailed(w, req, moduleDef, recordID, conflict)
+			return
+		}
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri brisanju zapisa sa ID '%s' za modul '%s'", recordID, moduleID), err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Zapis uspešno obrisan"}); err != nil {
 		log.Printf("ERROR: Greška pri enkodiranju odgovora za DeleteRecord: %v", err)
-		http.Error(w, "Interna serverska greška", http.StatusInternalServerError)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
 	}
 	log.Printf("INFO: Obrisan zapis sa ID '%s' za modul '%s'.", recordID, moduleID)
 }
+
+// parseRecordID konvertuje ID zapisa iz URL-a u tip primarnog ključa modula.
+func (s *APIServer) parseRecordID(moduleDef *ModuleDefinition, recordID string) (interface{}, error) {
+	pkCol := s.dataset.getPrimaryKeyColumn(moduleDef)
+	if pkCol != nil && pkCol.Type == "integer" {
+		return strconv.Atoi(recordID)
+	}
+	return recordID, nil
+}
+
+// authorize proverava da li korisnik sme da izvrši operaciju nad modulom i vraća 403 ako ne sme.
+func (s *APIServer) authorize(w http.ResponseWriter, req *http.Request, moduleDef *ModuleDefinition, operation string) bool {
+	if moduleDef.UserCan(UserFromContext(req.Context()), operation) {
+		return true
+	}
+	writeError(w, fmt.Sprintf("Nemate dozvolu za operaciju '%s' nad modulom '%s'.", operation, moduleDef.ID), http.StatusForbidden)
+	log.Printf("WARNING: Odbijen pristup: korisnik '%s', operacija '%s', modul '%s'.", userIDFromContext(req.Context()), operation, moduleDef.ID)
+	return false
+}
+
+// loadRecordForResponse dohvata sačuvan zapis (sa proširenim lookup-ima) za telo odgovora,
+// bez kolona koje korisnik ne sme da čita, i njegov ETag (računat pre uklanjanja kolona).
+// Greška se samo loguje, jer je sama izmena već uspešno potvrđena.
+func (s *APIServer) loadRecordForResponse(ctx context.Context, moduleDef *ModuleDefinition, id interface{}) (map[string]interface{}, string) {
+	record, err := s.dataset.GetRecordByID(ctx, moduleDef, id, ScopeActive)
+	if err != nil {
+		log.Printf("WARNING: Greška pri dohvatanju sačuvanog zapisa '%v' za odgovor (modul '%s'): %v", id, moduleDef.ID, err)
+		return nil, ""
+	}
+	etag := recordETag(moduleDef, record)
+	hideUnreadableColumns(moduleDef, UserFromContext(ctx), record)
+	return record, etag
+}
+
+// writePreconditionFailed vraća 412 sa trenutnim stanjem zapisa, da bi klijent mogao da razreši konflikt.
+func (s *APIServer) writePreconditionFailed(w http.ResponseWriter, req *http.Request, moduleDef *ModuleDefinition, recordID string, conflict *PreconditionFailedError) {
+	body := newErrorBody(http.StatusPreconditionFailed, ErrCodePreconditionFailed, conflict.Error())
+	if parsedRecordID, err := s.parseRecordID(moduleDef, recordID); err == nil {
+		if current, err := s.dataset.GetRecordByID(req.Context(), moduleDef, parsedRecordID, ScopeActive); err == nil {
+			hideUnreadableColumns(moduleDef, UserFromContext(req.Context()), current)
+			body.Current = current
+		} else {
+			log.Printf("WARNING: Greška pri dohvatanju trenutnog zapisa '%s' za 412 odgovor: %v", recordID, err)
+		}
+	}
+
+	if conflict.CurrentETag != "" {
+		w.Header().Set("ETag", conflict.CurrentETag)
+	}
+	writeErrorBody(w, body)
+	log.Printf("INFO: Konflikt verzija za zapis '%s' u modulu '%s'.", recordID, moduleDef.ID)
+}
+
+// RestoreRecord handles requests to undelete a soft-deleted record.
+func (s *APIServer) RestoreRecord(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	moduleID := vars["moduleID"]
+	recordID := vars["recordID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermDelete) {
+		return
+	}
+	if !moduleDef.HasSoftDelete() {
+		writeError(w, fmt.Sprintf("Modul '%s' ne podržava vraćanje obrisanih zapisa.", moduleID), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.dataset.RestoreRecord(req.Context(), moduleDef, recordID); err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri vraćanju zapisa sa ID '%s' za modul '%s'", recordID, moduleID), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Zapis uspešno vraćen"}); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora za RestoreRecord: %v", err)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
+	}
+	log.Printf("INFO: Vraćen obrisan zapis sa ID '%s' za modul '%s'.", recordID, moduleID)
+}
+
+// GetRecordHistory handles requests to browse the audit trail of a single record.
+func (s *APIServer) GetRecordHistory(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	moduleID := vars["moduleID"]
+	recordID := vars["recordID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermRead) {
+		return
+	}
+
+	limit, offset := -1, 0
+	if v := req.URL.Query().Get("_limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			writeError(w, fmt.Sprintf("Nevažeća vrednost za _limit: '%s'", v), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+	if v := req.URL.Query().Get("_offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			writeError(w, fmt.Sprintf("Nevažeća vrednost za _offset: '%s'", v), http.StatusBadRequest)
+			return
+		}
+		offset = o
+	}
+
+	entries, err := s.dataset.GetRecordHistory(req.Context(), moduleDef, recordID, limit, offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, fmt.Sprintf("Zapis sa ID '%s' nije pronađen u modulu '%s'.", recordID, moduleID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeInternalError(w, fmt.Sprintf("Greška pri dohvatanju istorije zapisa sa ID '%s' za modul '%s'", recordID, moduleID), err)
+		return
+	}
+	hideUnreadableChanges(moduleDef, UserFromContext(req.Context()), entries)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		writeInternalError(w, "Greška pri enkodiranju istorije", err)
+		return
+	}
+	log.Printf("INFO: Vraćeno %d audit zapisa za zapis '%s' modula '%s'.", len(entries), recordID, moduleID)
+}
+
+// ListModuleActions handles requests to list the server actions of a module available to the caller.
+// Korisnik bez dozvole za čitanje modula ne vidi ni njegove akcije.
+func (s *APIServer) ListModuleActions(w http.ResponseWriter, req *http.Request) {
+	moduleID := mux.Vars(req)["moduleID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+
+	if !s.authorize(w, req, moduleDef, PermRead) {
+		return
+	}
+
+	actions := s.Actions.ListAllowed(moduleID, UserFromContext(req.Context()))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(actions); err != nil {
+		writeInternalError(w, "Greška pri enkodiranju akcija", err)
+		return
+	}
+	log.Printf("INFO: Vraćeno %d akcija za modul '%s'.", len(actions), moduleID)
+}
+
+// RunAction handles requests to call a server action on a module or on a single record.
+// Pored uloga akcije, traži se dozvola za čitanje modula, a za akcije nad zapisom i za izmenu.
+func (s *APIServer) RunAction(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	moduleID := vars["moduleID"]
+	recordID := vars["recordID"] // Prazno za akcije na nivou modula
+	name := vars["name"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+
+	action := s.Actions.Get(moduleID, name)
+	if action == nil || action.OnRecord != (recordID != "") {
+		writeError(w, fmt.Sprintf("Akcija '%s' nije pronađena za modul '%s'.", name, moduleID), http.StatusNotFound)
+		return
+	}
+	// Akcija nad zapisom dobija zaključan zapis i obično ga menja
+	if !s.authorize(w, req, moduleDef, PermRead) || (action.OnRecord && !s.authorize(w, req, moduleDef, PermUpdate)) {
+		return
+	}
+	if !UserFromContext(req.Context()).HasAnyRole(action.Roles) {
+		writeError(w, fmt.Sprintf("Nemate dozvolu za akciju '%s' nad modulom '%s'.", name, moduleID), http.StatusForbidden)
+		return
+	}
+
+	params := map[string]interface{}{}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+			writeError(w, fmt.Sprintf("Greška pri dekodiranju parametara akcije: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	for _, param := range action.Params {
+		if _, ok := params[param.DBColumnName]; !ok && param.DefaultValue != nil {
+			params[param.DBColumnName] = param.DefaultValue
+		}
+	}
+	if err := s.dataset.validateRecord(req.Context(), validationTarget{Columns: action.Params}, params); err != nil {
+		if !writeClientError(w, err) {
+			writeInternalError(w, fmt.Sprintf("Greška pri validaciji parametara akcije '%s'", name), err)
+		}
+		return
+	}
+
+	result, err := s.dataset.RunAction(req.Context(), moduleDef, action, recordID, params)
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri izvršavanju akcije '%s' za modul '%s'", name, moduleID), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"result": result}); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora za akciju '%s': %v", name, err)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
+	}
+	log.Printf("INFO: Izvršena akcija '%s' za modul '%s' (zapis '%s').", name, moduleID, recordID)
+}
@@ -17,6 +17,7 @@ type AppConfig struct {
 	Modules               map[string]*ModuleDefinition
 	LookupTables          map[string]map[interface{}]map[string]interface{} // Not currently used but good to keep if planned
 	compiledRegexes       map[string]*regexp.Regexp                         // Mapa za prekompilirane regex-e
+	Rules                 *RuleRegistry                                     // Pravila validacije; Go kod može da registruje nova
 	ReverseLookupMappings map[string]map[interface{}]string                 // Not currently used but good to keep if planned
 }
 
@@ -25,6 +26,7 @@ func NewAppConfig(cfg *Config) (*AppConfig, error) {
 	appCfg := &AppConfig{
 		Config:  *cfg, // Direktno dodeljivanje Config strukture
 		Modules: make(map[string]*ModuleDefinition),
+		Rules:   NewRuleRegistry(),
 	}
 
 	if err := appCfg.LoadModules(); err != nil {
@@ -35,6 +37,12 @@ func NewAppConfig(cfg *Config) (*AppConfig, error) {
 	appCfg.ResolveSubmoduleReferences() // Resolve submodule references
 	appCfg.CompileRegexes()             // Kompilira regex obrasce
 
+	if cfg.I18n.MessagesPath != "" {
+		if err := messages.LoadDir(cfg.I18n.MessagesPath); err != nil {
+			return nil, fmt.Errorf("greška pri učitavanju kataloga poruka: %w", err)
+		}
+	}
+
 	return appCfg, nil
 }
 
@@ -66,6 +74,7 @@ func (ac *AppConfig) LoadModules() error {
 				continue
 			}
 
+			validateUniqueKeys(&moduleDef)
 			ac.Modules[moduleDef.ID] = &moduleDef
 			log.Printf("INFO: Učitan modul: %s (ID: %s)", moduleDef.Name, moduleDef.ID)
 		}
@@ -95,6 +104,28 @@ func (ac *AppConfig) ResolveModuleLookups() {
 				if lookupModule := ac.GetModuleByID(col.LookupModuleID); lookupModule != nil {
 					col.LookupModule = lookupModule
 					log.Printf("INFO: Razrešen lookup za kolonu '%s' u modulu '%s' -> Modul '%s'", col.Name, module.ID, lookupModule.ID)
+					for _, field := range lookupTemplateFields(col.LookupDisplayTemplate) {
+						if getColumnByDBName(lookupModule.Columns, field) == nil {
+							log.Printf("WARNING: Polje '%s' iz šablona prikaza kolone '%s' u modulu '%s' ne postoji u modulu '%s', šablon se ne koristi.", field, col.Name, module.ID, lookupModule.ID)
+							col.LookupDisplayTemplate = ""
+							break
+						}
+					}
+					fields := col.LookupFields[:0]
+					for _, field := range col.LookupFields {
+						if getColumnByDBName(lookupModule.Columns, field) == nil || field == "id" || field == "name" {
+							log.Printf("WARNING: Nevažeće dodatno lookup polje '%s' za kolonu '%s' u modulu '%s', preskačem.", field, col.Name, module.ID)
+							continue
+						}
+						fields = append(fields, field)
+					}
+					col.LookupFields = fields
+					for lookupCol, formField := range col.LookupFilters {
+						if getColumnByDBName(lookupModule.Columns, lookupCol) == nil || getColumnByDBName(module.Columns, formField) == nil {
+							log.Printf("WARNING: Nevažeći lookup filter '%s' -> '%s' za kolonu '%s' u modulu '%s', preskačem.", lookupCol, formField, col.Name, module.ID)
+							delete(col.LookupFilters, lookupCol)
+						}
+					}
 				} else {
 					log.Printf("WARNING: Lookup modul sa ID '%s' nije pronađen za kolonu '%s' u modulu '%s'.", col.LookupModuleID, col.Name, module.ID)
 				}
@@ -129,20 +160,20 @@ func (ac *AppConfig) CompileRegexes() {
 	ac.compileAndStoreRegex("emailValidation", `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
 	for _, module := range ac.Modules {
-		for _, col := range module.Columns {
-			if strings.Contains(col.Validation, "regex:") {
-				parts := strings.Split(col.Validation, ",")
-				for _, part := range parts {
-					rule := strings.TrimSpace(part)
-					if strings.HasPrefix(rule, "regex:") {
-						pattern := strings.TrimPrefix(rule, "regex:")
-						ac.compileAndStoreRegex(pattern, pattern) // Koristi pattern kao ključ i vrednost
-					}
-				}
+		ac.compileColumnRegexes(module.Columns)
+	}
+	log.Println("INFO: Regex obrasci uspešno kompilirani.")
+}
+
+// compileColumnRegexes kompilira regex pravila iz validacije datih kolona.
+func (ac *AppConfig) compileColumnRegexes(columns []ColumnDefinition) {
+	for _, col := range columns {
+		for _, rule := range col.ValidationRules() {
+			if rule.Name == "regex" {
+				ac.compileAndStoreRegex(rule.Arg, rule.Arg) // Koristi pattern kao ključ i vrednost
 			}
 		}
 	}
-	log.Println("INFO: Regex obrasci uspešno kompilirani.")
 }
 
 // GetDatabaseConfig retrieves the DatabaseConfig from AppConfig.
@@ -0,0 +1,218 @@
+// audit.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const defaultAuditTable = "audit_log"
+
+// Operacije koje se beleže u audit log.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// FieldChange holds the old and new value of a single changed column.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// AuditEntry is a single row of the audit log.
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	ModuleID  string                 `json:"module_id"`
+	RecordID  string                 `json:"record_id"`
+	Operation string                 `json:"operation"`
+	UserID    *string                `json:"user_id"`
+	ChangedAt time.Time              `json:"changed_at"`
+	Changes   map[string]FieldChange `json:"changes"`
+	TxID      int64                  `json:"-"` // Transakcija koja je upisala zapis (xid8)
+	Horizon   int64                  `json:"-"` // Pri upisu: najstarija transakcija koja tada još nije bila završena
+}
+
+// auditColumns su kolone audit tabele u redosledu koji queryAuditEntries skenira.
+const auditColumns = "id, module_id, record_id, operation, user_id, changed_at, changes, tx_id::text::bigint"
+
+// ensureAuditTable kreira audit tabelu ako ne postoji.
+func (s *SQLDataset) ensureAuditTable() error {
+	table := s.config.Config.AuditTable
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			module_id TEXT NOT NULL,
+			record_id TEXT NOT NULL,
+			operation TEXT NOT NULL,
+			user_id TEXT,
+			changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			changes JSONB NOT NULL DEFAULT '{}',
+			tx_id XID8 NOT NULL DEFAULT pg_current_xact_id()
+		)`, table),
+		// Tabele iz ranijih verzija (kursor SSE događaja je transakcija, vidi GetAuditEntriesSince)
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS tx_id XID8 NOT NULL DEFAULT pg_current_xact_id()", table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_module_record_idx ON %s (module_id, record_id, changed_at)", table, table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_module_tx_idx ON %s (module_id, tx_id)", table, table),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("greška pri kreiranju audit tabele '%s': %w", table, err)
+		}
+	}
+	return nil
+}
+
+// diffRecords vraća kolone čija se vrednost razlikuje između dva zapisa.
+// Nedostajući zapis (nil) tretira se kao prazan, pa create/delete beleže sve kolone.
+func diffRecords(before, after map[string]interface{}) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for col, newVal := range after {
+		oldVal := before[col]
+		if !reflect.DeepEqual(oldVal, newVal) {
+			changes[col] = FieldChange{Old: oldVal, New: newVal}
+		}
+	}
+	for col, oldVal := range before {
+		if _, ok := after[col]; !ok && oldVal != nil {
+			changes[col] = FieldChange{Old: oldVal, New: nil}
+		}
+	}
+	return changes
+}
+
+// writeAudit upisuje audit zapis u okviru iste transakcije kao i sama izmena
+// i vraća upisani zapis (njegov ID je i ID događaja za SSE, a Horizon kursor za nastavak).
+func (s *SQLDataset) writeAudit(ctx context.Context, q queryer, moduleDef *ModuleDefinition, recordID interface{}, operation string, before, after map[string]interface{}) (*AuditEntry, error) {
+	entry := &AuditEntry{
+		ModuleID:  moduleDef.ID,
+		RecordID:  fmt.Sprint(recordID),
+		Operation: operation,
+		Changes:   diffRecords(before, after),
+	}
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri serijalizaciji audit izmena: %w", err)
+	}
+
+	var userID interface{}
+	if id := userIDFromContext(ctx); id != "" {
+		userID = id
+		entry.UserID = &id
+	}
+
+	// Horizon nije veći od sopstvene transakcije, da kursor ne bi preskočio njene naredne izmene
+	query := fmt.Sprintf(`INSERT INTO %s (module_id, record_id, operation, user_id, changes) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, changed_at, tx_id::text::bigint, LEAST(pg_snapshot_xmin(pg_current_snapshot()), tx_id)::text::bigint`, s.config.Config.AuditTable)
+	if err := q.QueryRowContext(ctx, query, entry.ModuleID, entry.RecordID, operation, userID, string(changes)).Scan(&entry.ID, &entry.ChangedAt, &entry.TxID, &entry.Horizon); err != nil {
+		return nil, fmt.Errorf("greška pri upisu audit zapisa za modul '%s', ID '%v': %w", moduleDef.ID, recordID, err)
+	}
+	return entry, nil
+}
+
+// GetRecordHistory returns the audit trail of a record, newest first.
+// Sa row-level filterom istorija je dostupna samo za zapis koji korisnik vidi (i soft-obrisan);
+// za zapis van filtera ili trajno obrisan vraća sql.ErrNoRows.
+func (s *SQLDataset) GetRecordHistory(ctx context.Context, moduleDef *ModuleDefinition, recordID string, limit, offset int) ([]AuditEntry, error) {
+	if visible, err := s.recordVisible(ctx, moduleDef, recordID); err != nil {
+		return nil, err
+	} else if !visible {
+		return nil, sql.ErrNoRows
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE module_id = $1 AND record_id = $2 ORDER BY changed_at DESC, id DESC`,
+		auditColumns, s.config.Config.AuditTable)
+	args := []interface{}{moduleDef.ID, recordID}
+	if limit >= 0 {
+		query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, offset)
+	}
+
+	log.Printf("DEBUG: Executing history query: %s with parameters: %v", query, args)
+
+	entries, err := queryAuditEntries(ctx, s.conn(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri dohvatanju istorije zapisa '%s' za modul '%s': %w", recordID, moduleDef.ID, err)
+	}
+	return entries, nil
+}
+
+// GetAuditEntriesSince returns the module's committed audit entries written by transactions
+// not older than horizon, by ID, together with the snapshot the entries were read in.
+// ID-evi se dodeljuju pri upisu, a ne pri potvrdi, pa ne mogu biti kursor: transakcija sa
+// manjim ID-em može biti potvrđena posle one sa većim. Snimak govori koje su transakcije
+// uključene, da bi se izostavile iz događaja koji stignu posle čitanja.
+func (s *SQLDataset) GetAuditEntriesSince(ctx context.Context, moduleID string, horizon int64, limit int) ([]AuditEntry, *pgSnapshot, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("greška pri započinjanju transakcije: %w", err)
+	}
+	defer tx.Rollback()
+
+	// U REPEATABLE READ oba upita koriste isti snimak
+	var snapshotText string
+	if err := tx.QueryRowContext(ctx, "SELECT pg_current_snapshot()::text").Scan(&snapshotText); err != nil {
+		return nil, nil, fmt.Errorf("greška pri čitanju snimka transakcija: %w", err)
+	}
+	snapshot, err := parsePGSnapshot(snapshotText)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE module_id = $1 AND tx_id >= $2::text::xid8 ORDER BY id LIMIT $3`,
+		auditColumns, s.config.Config.AuditTable)
+	entries, err := queryAuditEntries(ctx, tx, query, moduleID, strconv.FormatInt(horizon, 10), limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, snapshot, nil
+}
+
+// getAuditEntry vraća jedan audit zapis po ID-u.
+func (s *SQLDataset) getAuditEntry(ctx context.Context, id int64) (*AuditEntry, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", auditColumns, s.config.Config.AuditTable)
+	entries, err := queryAuditEntries(ctx, s.conn(ctx), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("audit zapis '%d' nije pronađen", id)
+	}
+	return &entries[0], nil
+}
+
+// queryAuditEntries izvršava upit nad audit tabelom (kolone auditColumns) i skenira redove.
+func queryAuditEntries(ctx context.Context, q queryer, query string, args ...interface{}) ([]AuditEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var entry AuditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.ModuleID, &entry.RecordID, &entry.Operation, &entry.UserID, &entry.ChangedAt, &changes, &entry.TxID); err != nil {
+			return nil, fmt.Errorf("greška pri skeniranju audit reda: %w", err)
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, fmt.Errorf("greška pri parsiranju audit izmena (ID %d): %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("greška nakon iteracije kroz audit redove: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,89 @@
+// audit_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAuditLogAndHistory(t *testing.T) {
+	module := testEmployeesModule()
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, name TEXT NOT NULL, salary INT, version INT NOT NULL DEFAULT 1")
+	clearAudit := func() { ds.db.Exec("DELETE FROM "+ds.config.Config.AuditTable+" WHERE module_id = $1", module.ID) }
+	clearAudit()
+	t.Cleanup(clearAudit)
+	s := NewAPIServer(ds.config, ds)
+
+	for _, step := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/modules/test_employees", `{"name": "Ana", "salary": 1000}`},
+		{http.MethodPatch, "/api/modules/test_employees/1", `{"salary": 1200}`},
+		{http.MethodPatch, "/api/modules/test_employees/1", `{"name": "Ana Petrović"}`},
+	} {
+		if rec := serveTest(s, step.method, step.path, step.body, "hr", nil); rec.Code >= 300 {
+			t.Fatalf("%s %s: status %d, telo %s", step.method, step.path, rec.Code, rec.Body)
+		}
+	}
+
+	history := func(path, roles string) []AuditEntry {
+		t.Helper()
+		rec := serveTest(s, http.MethodGet, path, "", roles, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, telo %s", path, rec.Code, rec.Body)
+		}
+		var entries []AuditEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	entries := history("/api/modules/test_employees/1/history", "hr")
+	if len(entries) != 3 {
+		t.Fatalf("broj audit zapisa: %d, očekivano 3: %+v", len(entries), entries)
+	}
+	for i, op := range []string{AuditUpdate, AuditUpdate, AuditCreate} {
+		if entries[i].Operation != op || entries[i].RecordID != "1" || entries[i].UserID == nil || *entries[i].UserID != "tester" {
+			t.Errorf("zapis %d: %+v, očekivana operacija %q korisnika tester", i, entries[i], op)
+		}
+	}
+	if change, ok := entries[0].Changes["name"]; !ok || change.Old != "Ana" || change.New != "Ana Petrović" {
+		t.Errorf("izmena imena: %+v", entries[0].Changes)
+	}
+	if _, ok := entries[0].Changes["salary"]; ok {
+		t.Errorf("nepromenjena kolona je zabeležena: %+v", entries[0].Changes)
+	}
+	if change := entries[1].Changes["salary"]; change.Old != float64(1000) || change.New != float64(1200) {
+		t.Errorf("izmena plate: %+v", entries[1].Changes)
+	}
+	if change, ok := entries[2].Changes["name"]; !ok || change.Old != nil || change.New != "Ana" {
+		t.Errorf("kreiranje: %+v", entries[2].Changes)
+	}
+
+	// Bez uloge hr izmene plate se ne vide, a stranice se biraju sa _limit/_offset
+	for _, entry := range history("/api/modules/test_employees/1/history", "staff") {
+		if _, ok := entry.Changes["salary"]; ok {
+			t.Errorf("istorija otkriva platu korisniku bez uloge hr: %+v", entry)
+		}
+	}
+	if page := history("/api/modules/test_employees/1/history?_limit=1&_offset=1", "hr"); len(page) != 1 || page[0].ID != entries[1].ID {
+		t.Errorf("druga strana istorije: %+v", page)
+	}
+	if rec := serveTest(s, http.MethodGet, "/api/modules/test_employees/1/history?_limit=-1", "", "hr", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("nevažeći _limit: status %d, očekivano 400", rec.Code)
+	}
+
+	// Brisanje se beleži u istoj transakciji, a istorija trajno obrisanog zapisa nije dostupna
+	if rec := serveTest(s, http.MethodDelete, "/api/modules/test_employees/1", "", "hr", nil); rec.Code != http.StatusOK {
+		t.Fatalf("DELETE: status %d, telo %s", rec.Code, rec.Body)
+	}
+	var deletes int
+	if err := ds.db.QueryRow("SELECT count(*) FROM "+ds.config.Config.AuditTable+" WHERE module_id = $1 AND record_id = '1' AND operation = $2",
+		module.ID, AuditDelete).Scan(&deletes); err != nil || deletes != 1 {
+		t.Errorf("audit zapis brisanja: %d, %v", deletes, err)
+	}
+	if rec := serveTest(s, http.MethodGet, "/api/modules/test_employees/1/history", "", "hr", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("istorija obrisanog zapisa: status %d, očekivano 404", rec.Code)
+	}
+}
@@ -0,0 +1,70 @@
+// auth.go
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Zaglavlja preko kojih reverse proxy (ili gateway za autentifikaciju) prosleđuje
+// identitet korisnika. Sam framework ne radi autentifikaciju.
+const (
+	userIDHeader    = "X-User-ID"
+	userRolesHeader = "X-User-Roles"
+)
+
+// User represents the authenticated user acting on a request.
+type User struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx carrying the given user.
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the user stored in ctx, or nil for anonymous requests.
+func UserFromContext(ctx context.Context) *User {
+	if ctx == nil {
+		return nil
+	}
+	user, _ := ctx.Value(userContextKey{}).(*User)
+	return user
+}
+
+// userIDFromContext vraća ID korisnika ili prazan string za anonimne zahteve.
+func userIDFromContext(ctx context.Context) string {
+	if user := UserFromContext(ctx); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// userFromRequest čita identitet korisnika iz zaglavlja zahteva.
+func userFromRequest(req *http.Request) *User {
+	id := strings.TrimSpace(req.Header.Get(userIDHeader))
+	if id == "" {
+		return nil
+	}
+	user := &User{ID: id}
+	for _, role := range strings.Split(req.Header.Get(userRolesHeader), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			user.Roles = append(user.Roles, role)
+		}
+	}
+	return user
+}
+
+// userMiddleware smešta korisnika iz zaglavlja u kontekst zahteva.
+func userMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if user := userFromRequest(req); user != nil {
+			req = req.WithContext(WithUser(req.Context(), user))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
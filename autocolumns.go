@@ -0,0 +1,44 @@
+// autocolumns.go
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Uloge automatski održavanih kolona (ColumnDefinition.Auto).
+const (
+	AutoCreatedAt = "created_at"
+	AutoUpdatedAt = "updated_at"
+	AutoCreatedBy = "created_by"
+	AutoUpdatedBy = "updated_by"
+)
+
+// IsAuto reports whether the column is filled by the server and ignored in payloads.
+func (c *ColumnDefinition) IsAuto() bool {
+	return c.Auto != ""
+}
+
+// autoColumnValue vraća vrednost koju server upisuje u automatsku kolonu.
+// Drugi rezultat je false ako kolona ne treba da se menja u datoj operaciji
+// (npr. created_at pri ažuriranju).
+func autoColumnValue(ctx context.Context, colDef *ColumnDefinition, now time.Time, creating bool) (interface{}, bool) {
+	switch colDef.Auto {
+	case AutoCreatedAt:
+		return now, creating
+	case AutoUpdatedAt:
+		return now, true
+	case AutoCreatedBy, AutoUpdatedBy:
+		if colDef.Auto == AutoCreatedBy && !creating {
+			return nil, false
+		}
+		if id := userIDFromContext(ctx); id != "" {
+			return id, true
+		}
+		return nil, true
+	default:
+		log.Printf("WARNING: Nepoznata auto uloga '%s' za kolonu '%s', preskačem.", colDef.Auto, colDef.Name)
+		return nil, false
+	}
+}
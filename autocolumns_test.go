@@ -0,0 +1,95 @@
+// autocolumns_test.go
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoColumnValue(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	ctx := WithUser(context.Background(), &User{ID: "ana"})
+
+	tests := []struct {
+		auto     string
+		ctx      context.Context
+		creating bool
+		want     interface{}
+		set      bool
+	}{
+		{AutoCreatedAt, ctx, true, now, true},
+		{AutoCreatedAt, ctx, false, now, false},
+		{AutoUpdatedAt, ctx, true, now, true},
+		{AutoUpdatedAt, ctx, false, now, true},
+		{AutoCreatedBy, ctx, true, "ana", true},
+		{AutoCreatedBy, ctx, false, nil, false},
+		{AutoUpdatedBy, ctx, false, "ana", true},
+		{AutoUpdatedBy, context.Background(), false, nil, true},
+		{"nepoznata", ctx, true, nil, false},
+	}
+	for _, tt := range tests {
+		colDef := &ColumnDefinition{DBColumnName: tt.auto, Name: tt.auto, Auto: tt.auto}
+		got, set := autoColumnValue(tt.ctx, colDef, now, tt.creating)
+		if got != tt.want || set != tt.set {
+			t.Errorf("autoColumnValue(%q, creating=%v) = %v, %v; očekivano %v, %v", tt.auto, tt.creating, got, set, tt.want, tt.set)
+		}
+	}
+}
+
+func TestAutoColumnsAreFilledByServer(t *testing.T) {
+	module := &ModuleDefinition{ID: "test_auto_notes", Name: "Beleške", Type: "table", DBTableName: "test_auto_notes",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsReadOnly: true},
+			{DBColumnName: "text", Name: "Tekst", Type: "string", IsEditable: true},
+			{DBColumnName: "created_at", Name: "Kreirano", Type: "datetime", Auto: AutoCreatedAt},
+			{DBColumnName: "updated_at", Name: "Izmenjeno", Type: "datetime", Auto: AutoUpdatedAt},
+			{DBColumnName: "created_by", Name: "Kreirao", Type: "string", Auto: AutoCreatedBy},
+			{DBColumnName: "updated_by", Name: "Izmenio", Type: "string", Auto: AutoUpdatedBy},
+		}}
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, text TEXT, created_at TIMESTAMPTZ, updated_at TIMESTAMPTZ, created_by TEXT, updated_by TEXT")
+	s := NewAPIServer(ds.config, ds)
+
+	type autoValues struct {
+		createdAt, updatedAt time.Time
+		createdBy, updatedBy string
+	}
+	read := func() autoValues {
+		t.Helper()
+		var v autoValues
+		if err := ds.db.QueryRow("SELECT created_at, updated_at, created_by, updated_by FROM test_auto_notes WHERE id = 1").
+			Scan(&v.createdAt, &v.updatedAt, &v.createdBy, &v.updatedBy); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	forged := `"created_at": "2000-01-01T00:00:00Z", "updated_at": "2000-01-01T00:00:00Z", "created_by": "napadač", "updated_by": "napadač"`
+
+	before := time.Now().Add(-time.Minute)
+	if rec := serveTest(s, http.MethodPost, "/api/modules/test_auto_notes", `{"text": "prva", `+forged+`}`, "", nil); rec.Code != http.StatusCreated {
+		t.Fatalf("POST: status %d, telo %s", rec.Code, rec.Body)
+	}
+	created := read()
+	if created.createdBy != "tester" || created.updatedBy != "tester" || created.createdAt.Before(before) || created.updatedAt.Before(before) {
+		t.Errorf("posle kreiranja: %+v", created)
+	}
+
+	other := map[string]string{userIDHeader: "marko"}
+	for _, step := range []struct{ method, body string }{
+		{http.MethodPatch, `{"text": "druga", ` + forged + `}`},
+		{http.MethodPut, `{"text": "treća", ` + forged + `}`},
+	} {
+		if rec := serveTest(s, step.method, "/api/modules/test_auto_notes/1", step.body, "", other); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, telo %s", step.method, rec.Code, rec.Body)
+		}
+		updated := read()
+		if updated.createdBy != "tester" || !updated.createdAt.Equal(created.createdAt) {
+			t.Errorf("%s je promenio podatke o kreiranju: %+v", step.method, updated)
+		}
+		if updated.updatedBy != "marko" || updated.updatedAt.Before(created.updatedAt) {
+			t.Errorf("%s nije popunio podatke o izmeni: %+v", step.method, updated)
+		}
+	}
+}
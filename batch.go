@@ -0,0 +1,440 @@
+// batch.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ograničenja za /api/batch zahtev.
+const (
+	batchMaxOperations = 50
+	batchMaxBodySize   = 4 << 20 // 4MB
+
+	recordedResponseMaxSize = 8 << 20 // Najveći odgovor koji responseRecorder drži u memoriji (8MB)
+)
+
+// BatchOperation is a single API call executed as part of a batch.
+// Putanja i telo mogu da sadrže reference na ranije rezultate, npr. "${op1.id}" ili "${0.record.name}".
+type BatchOperation struct {
+	ID      string            `json:"id,omitempty"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"` // npr. If-Match
+}
+
+// BatchRequest is the body of POST /api/batch.
+type BatchRequest struct {
+	Transaction bool             `json:"transaction"` // Sve operacije u jednoj transakciji (sve ili ništa)
+	Operations  []BatchOperation `json:"operations"`
+}
+
+// BatchResult is the response of a single batched operation.
+type BatchResult struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// BatchResponse is the body returned by POST /api/batch.
+type BatchResponse struct {
+	Transaction bool          `json:"transaction"`
+	RolledBack  bool          `json:"rolled_back,omitempty"`
+	Results     []BatchResult `json:"results"`
+}
+
+// errResponseTooLarge vraća responseRecorder kada odgovor pređe recordedResponseMaxSize.
+var errResponseTooLarge = errors.New("odgovor je prevelik")
+
+// errBatchRolledBack signalizira withTx-u da poništi transakcioni batch; detalji su u rezultatima.
+var errBatchRolledBack = errors.New("batch je poništen")
+
+// Zaglavlja odgovora koja se prenose u rezultat operacije.
+var batchResultHeaders = []string{"ETag", "Location"}
+
+// batchRefPattern prepoznaje reference "${opID.putanja.do.polja}".
+var batchRefPattern = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)*)\}`)
+
+// responseRecorder beleži odgovor handler-a u memoriji (batch operacije, idempotentni zahtevi).
+// Telo je ograničeno na recordedResponseMaxSize; upis preko granice vraća grešku handler-u.
+type responseRecorder struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header)}
+}
+
+func (w *responseRecorder) Header() http.Header { return w.header }
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.overflow || w.body.Len()+len(p) > recordedResponseMaxSize {
+		w.overflow = true
+		return 0, errResponseTooLarge
+	}
+	return w.body.Write(p)
+}
+
+// result pretvara zabeleženi odgovor u BatchResult; JSON telo se parsira da bi
+// kasnije operacije mogle da ga referenciraju.
+func (w *responseRecorder) result(id string) BatchResult {
+	if w.overflow {
+		body := newErrorBody(http.StatusInternalServerError, "", fmt.Sprintf("Odgovor operacije je veći od %d bajtova.", recordedResponseMaxSize))
+		body.RequestID = w.header.Get(requestIDHeader)
+		return BatchResult{ID: id, Status: http.StatusInternalServerError, Body: ErrorResponse{Error: body}}
+	}
+	res := BatchResult{ID: id, Status: w.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	for _, name := range batchResultHeaders {
+		if v := w.header.Get(name); v != "" {
+			if res.Headers == nil {
+				res.Headers = make(map[string]string)
+			}
+			res.Headers[name] = v
+		}
+	}
+	if w.body.Len() == 0 {
+		return res
+	}
+	if strings.HasPrefix(w.header.Get("Content-Type"), "application/json") {
+		if body, err := decodeJSONValue(w.body.Bytes()); err == nil {
+			res.Body = body
+			return res
+		}
+	}
+	res.Body = strings.TrimSpace(w.body.String())
+	return res
+}
+
+// decodeJSONValue dekodira JSON čuvajući brojeve kao json.Number (bez gubitka preciznosti ID-jeva).
+func decodeJSONValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// batchState čuva rezultate izvršenih operacija radi razrešavanja referenci.
+type batchState struct {
+	results []BatchResult
+	byID    map[string]int
+}
+
+// lookup vraća vrednost reference: operacija se bira po ID-ju ili rednom broju,
+// a segmenti putanje su ključevi objekta ili indeksi niza.
+func (b *batchState) lookup(ref string, path []string) (interface{}, error) {
+	idx, ok := b.byID[ref]
+	if !ok {
+		n, err := strconv.Atoi(ref)
+		if err != nil || n < 0 || n >= len(b.results) {
+			return nil, fmt.Errorf("operacija '%s' ne postoji ili još nije izvršena", ref)
+		}
+		idx = n
+	}
+	res := b.results[idx]
+	if res.Status >= 400 {
+		return nil, fmt.Errorf("operacija '%s' nije uspela (status %d)", ref, res.Status)
+	}
+
+	value := res.Body
+	for _, seg := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			field, ok := v[seg]
+			if !ok {
+				return nil, fmt.Errorf("polje '%s' ne postoji u rezultatu operacije '%s'", seg, ref)
+			}
+			value = field
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("nevažeći indeks '%s' u rezultatu operacije '%s'", seg, ref)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("putanja '%s' ne postoji u rezultatu operacije '%s'", strings.Join(path, "."), ref)
+		}
+	}
+	return value, nil
+}
+
+// resolveRef razrešava jednu referencu u obliku "${...}".
+func (b *batchState) resolveRef(match string) (interface{}, error) {
+	parts := batchRefPattern.FindStringSubmatch(match)
+	var path []string
+	if parts[2] != "" {
+		path = strings.Split(parts[2][1:], ".")
+	}
+	return b.lookup(parts[1], path)
+}
+
+// batchText vraća tekstualni oblik vrednosti za umetanje u string ili putanju.
+func batchText(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// resolveString zamenjuje reference u stringu. Ako je ceo string jedna referenca,
+// vraća se referencirana vrednost sa izvornim tipom (broj ostaje broj).
+func (b *batchState) resolveString(s string, escape func(string) string) (interface{}, error) {
+	if loc := batchRefPattern.FindStringIndex(s); loc != nil && loc[0] == 0 && loc[1] == len(s) && escape == nil {
+		return b.resolveRef(s)
+	}
+	var resolveErr error
+	out := batchRefPattern.ReplaceAllStringFunc(s, func(match string) string {
+		v, err := b.resolveRef(match)
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = err
+			}
+			return match
+		}
+		text := batchText(v)
+		if escape != nil {
+			text = escape(text)
+		}
+		return text
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	return out, nil
+}
+
+// resolveValue rekurzivno zamenjuje reference u dekodiranom JSON telu.
+func (b *batchState) resolveValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return b.resolveString(val, nil)
+	case map[string]interface{}:
+		for k, item := range val {
+			resolved, err := b.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = resolved
+		}
+		return val, nil
+	case []interface{}:
+		for i, item := range val {
+			resolved, err := b.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = resolved
+		}
+		return val, nil
+	default:
+		return v, nil
+	}
+}
+
+// validateBatchOperation proverava da li je operacija dozvoljena u batch-u.
+// Dozvoljene su samo operacije nad modulima, bez streaming i upload ruta.
+// Poziva se pre izvršavanja i ponovo nad putanjom sa razrešenim referencama,
+// jer referenca može da izabere rutu (npr. "${op1.name}" -> "events").
+func validateBatchOperation(op BatchOperation) error {
+	switch op.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("nepodržana metoda '%s'", op.Method)
+	}
+	u, err := url.Parse(op.Path)
+	if err != nil {
+		return fmt.Errorf("nevažeća putanja '%s': %v", op.Path, err)
+	}
+	if u.IsAbs() || u.Host != "" || !(u.Path == "/api/modules" || strings.HasPrefix(u.Path, "/api/modules/")) {
+		return fmt.Errorf("putanja '%s' nije dozvoljena u batch-u (samo /api/modules/...)", op.Path)
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("putanja '%s' nije dozvoljena u batch-u", op.Path)
+		}
+	}
+	for _, suffix := range []string{"/events", "/export", "/import"} {
+		if strings.HasSuffix(strings.TrimRight(u.Path, "/"), suffix) {
+			return fmt.Errorf("putanja '%s' nije dozvoljena u batch-u", op.Path)
+		}
+	}
+	return nil
+}
+
+// runBatchOperation izvršava jednu operaciju kroz router, sa identitetom spoljnog zahteva.
+func (s *APIServer) runBatchOperation(ctx context.Context, state *batchState, op BatchOperation) BatchResult {
+	fail := func(status int, format string, args ...interface{}) BatchResult {
+		body := newErrorBody(status, "", fmt.Sprintf(format, args...))
+		body.RequestID = requestIDFromContext(ctx)
+		localizeErrorBody(body, languageFromContext(ctx))
+		return BatchResult{ID: op.ID, Status: status, Body: ErrorResponse{Error: body}}
+	}
+
+	path, err := state.resolveString(op.Path, url.PathEscape)
+	if err != nil {
+		return fail(http.StatusBadRequest, "Greška pri razrešavanju reference u putanji: %v", err)
+	}
+	resolved := op
+	resolved.Path = path.(string)
+	if err := validateBatchOperation(resolved); err != nil {
+		return fail(http.StatusBadRequest, "%v", err)
+	}
+
+	var body []byte
+	if len(op.Body) > 0 {
+		value, err := decodeJSONValue(op.Body)
+		if err != nil {
+			return fail(http.StatusBadRequest, "Nevažeće telo operacije: %v", err)
+		}
+		if value, err = state.resolveValue(value); err != nil {
+			return fail(http.StatusBadRequest, "Greška pri razrešavanju reference u telu: %v", err)
+		}
+		if body, err = json.Marshal(value); err != nil {
+			return fail(http.StatusBadRequest, "Nevažeće telo operacije: %v", err)
+		}
+	}
+
+	subReq, err := http.NewRequestWithContext(ctx, op.Method, resolved.Path, bytes.NewReader(body))
+	if err != nil {
+		return fail(http.StatusBadRequest, "Nevažeća operacija: %v", err)
+	}
+	for name, value := range op.Headers {
+		// Identitet se uvek preuzima iz spoljnog zahteva (kontekst), ne iz operacije;
+		// idempotentnost važi za ceo batch (Idempotency-Key spoljnog zahteva)
+		switch http.CanonicalHeaderKey(name) {
+		case userIDHeader, userRolesHeader, idempotencyKeyHeader:
+			continue
+		}
+		subReq.Header.Set(name, value)
+	}
+	if len(body) > 0 {
+		subReq.Header.Set("Content-Type", "application/json")
+	}
+	if id := requestIDFromContext(ctx); id != "" {
+		subReq.Header.Set(requestIDHeader, id) // Operacije dele ID spoljnog zahteva
+	}
+	if subReq.Header.Get("Accept-Language") == "" {
+		subReq.Header.Set("Accept-Language", languageFromContext(ctx)) // i jezik poruka
+	}
+
+	rec := newResponseRecorder()
+	s.router.ServeHTTP(rec, subReq)
+	return rec.result(op.ID)
+}
+
+// RunBatch handles POST /api/batch: executes an ordered list of module operations,
+// optionally in a single transaction, with references to earlier results.
+func (s *APIServer) RunBatch(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, batchMaxBodySize)
+	var batch BatchRequest
+	if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+		writeError(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(batch.Operations) == 0 {
+		writeError(w, "Batch ne sadrži nijednu operaciju.", http.StatusBadRequest)
+		return
+	}
+	if len(batch.Operations) > batchMaxOperations {
+		writeError(w, fmt.Sprintf("Previše operacija u batch-u (%d, najviše %d).", len(batch.Operations), batchMaxOperations), http.StatusRequestEntityTooLarge)
+		return
+	}
+	byID := make(map[string]int, len(batch.Operations))
+	for i, op := range batch.Operations {
+		batch.Operations[i].Method = strings.ToUpper(op.Method)
+		if err := validateBatchOperation(batch.Operations[i]); err != nil {
+			writeError(w, fmt.Sprintf("Operacija %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		if op.ID == "" {
+			continue
+		}
+		if _, dup := byID[op.ID]; dup {
+			writeError(w, fmt.Sprintf("ID operacije '%s' se ponavlja.", op.ID), http.StatusBadRequest)
+			return
+		}
+		byID[op.ID] = i
+	}
+
+	response := BatchResponse{Transaction: batch.Transaction}
+	run := func(ctx context.Context) bool {
+		state := &batchState{byID: byID}
+		for i, op := range batch.Operations {
+			res := s.runBatchOperation(ctx, state, op)
+			state.results = append(state.results, res)
+			if batch.Transaction && res.Status >= 400 {
+				// Ostale operacije se ne izvršavaju; sve izmene se poništavaju
+				for _, rest := range batch.Operations[i+1:] {
+					body := newErrorBody(http.StatusFailedDependency, "", fmt.Sprintf("Preskočeno jer operacija %d nije uspela.", i))
+					body.RequestID = requestIDFromContext(ctx)
+					localizeErrorBody(body, languageFromContext(ctx))
+					state.results = append(state.results, BatchResult{ID: rest.ID, Status: http.StatusFailedDependency, Body: ErrorResponse{Error: body}})
+				}
+				response.Results = state.results
+				return false
+			}
+		}
+		response.Results = state.results
+		return true
+	}
+
+	status := http.StatusOK
+	if batch.Transaction {
+		err := s.dataset.withTx(req.Context(), func(ctx context.Context, tx *sql.Tx) error {
+			if !run(ctx) {
+				return errBatchRolledBack
+			}
+			return nil
+		})
+		if err == errBatchRolledBack {
+			response.RolledBack = true
+			status = http.StatusUnprocessableEntity
+		} else if err != nil {
+			writeInternalError(w, "Greška pri izvršavanju batch-a", err)
+			return
+		}
+	} else {
+		run(req.Context())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora za RunBatch: %v", err)
+	}
+	log.Printf("INFO: Izvršen batch sa %d operacija (transakcija: %t, poništen: %t).", len(batch.Operations), batch.Transaction, response.RolledBack)
+}
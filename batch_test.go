@@ -0,0 +1,131 @@
+// batch_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// batchStateWith vraća stanje sa jednim uspešnim rezultatom dekodiranim iz JSON-a.
+func batchStateWith(t *testing.T, id, body string) *batchState {
+	t.Helper()
+	value, err := decodeJSONValue([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &batchState{
+		results: []BatchResult{{ID: id, Status: http.StatusCreated, Body: value}},
+		byID:    map[string]int{id: 0},
+	}
+}
+
+func TestBatchResolveReferences(t *testing.T) {
+	state := batchStateWith(t, "order", `{"id": 12345678901234567, "customer": {"name": "Ana/Marko"}, "items": [{"sku": "A-1"}]}`)
+
+	tests := []struct {
+		in     string
+		escape func(string) string
+		want   interface{}
+	}{
+		{"${order.id}", nil, json.Number("12345678901234567")},
+		{"${0.id}", nil, json.Number("12345678901234567")},
+		{"Porudžbina ${order.id}", nil, "Porudžbina 12345678901234567"},
+		{"${order.items.0.sku}", nil, "A-1"},
+		{"/api/modules/m/records/${order.customer.name}", url.PathEscape, "/api/modules/m/records/Ana%2FMarko"},
+	}
+	for _, tt := range tests {
+		got, err := state.resolveString(tt.in, tt.escape)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveString(%q) = %#v, %v; očekivano %#v", tt.in, got, err, tt.want)
+		}
+	}
+
+	body, err := decodeJSONValue([]byte(`{"order_id": "${order.id}", "note": ["${order.customer.name}"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := state.resolveValue(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(resolved)
+	if string(data) != `{"note":["Ana/Marko"],"order_id":12345678901234567}` {
+		t.Errorf("razrešeno telo = %s", data)
+	}
+
+	for _, in := range []string{"${missing.id}", "${order.nope}", "${order.items.5}", "${1}"} {
+		if _, err := state.resolveString(in, nil); err == nil {
+			t.Errorf("resolveString(%q): očekivana greška", in)
+		}
+	}
+
+	failed := &batchState{results: []BatchResult{{Status: http.StatusConflict, Body: map[string]interface{}{"id": 1}}}}
+	if _, err := failed.resolveString("${0.id}", nil); err == nil {
+		t.Error("referenca na neuspelu operaciju mora da vrati grešku")
+	}
+}
+
+func TestBatchRejectsRoutesSelectedByReferences(t *testing.T) {
+	state := batchStateWith(t, "op1", `{"segment": "events", "dots": ".."}`)
+	s := &APIServer{}
+
+	for _, path := range []string{
+		"/api/modules/module_orders/${op1.segment}",
+		"/api/modules/module_orders/${op1.dots}/${op1.dots}/batch",
+	} {
+		op := BatchOperation{ID: "op2", Method: http.MethodGet, Path: path}
+		if err := validateBatchOperation(op); err != nil {
+			t.Fatalf("putanja %q sa referencom mora da prođe proveru pre razrešavanja: %v", path, err)
+		}
+		res := s.runBatchOperation(context.Background(), state, op)
+		if res.Status != http.StatusBadRequest {
+			t.Errorf("putanja %q: status = %d, očekivano 400", path, res.Status)
+		}
+	}
+}
+
+func TestValidateBatchOperation(t *testing.T) {
+	tests := []struct {
+		method, path string
+		ok           bool
+	}{
+		{http.MethodGet, "/api/modules/module_orders/records", true},
+		{http.MethodPost, "/api/modules/module_orders/records", true},
+		{http.MethodGet, "/api/modules/module_orders/events", false},
+		{http.MethodGet, "/api/modules/module_orders/events/", false},
+		{http.MethodGet, "/api/modules/module_orders/export", false},
+		{http.MethodPost, "/api/modules/module_orders/import", false},
+		{http.MethodGet, "/api/modules/../batch", false},
+		{http.MethodGet, "/api/webhooks", false},
+		{http.MethodGet, "http://example.com/api/modules", false},
+		{"TRACE", "/api/modules", false},
+	}
+	for _, tt := range tests {
+		err := validateBatchOperation(BatchOperation{Method: tt.method, Path: tt.path})
+		if (err == nil) != tt.ok {
+			t.Errorf("validateBatchOperation(%s %s) = %v, očekivano dozvoljeno: %v", tt.method, tt.path, err, tt.ok)
+		}
+	}
+}
+
+func TestResponseRecorderLimit(t *testing.T) {
+	rec := newResponseRecorder()
+	chunk := make([]byte, 1<<20)
+	var err error
+	for i := 0; i <= recordedResponseMaxSize/len(chunk) && err == nil; i++ {
+		_, err = rec.Write(chunk)
+	}
+	if !errors.Is(err, errResponseTooLarge) {
+		t.Fatalf("greška = %v, očekivano %v", err, errResponseTooLarge)
+	}
+	if rec.body.Len() > recordedResponseMaxSize {
+		t.Errorf("zabeleženo %d bajtova, najviše %d", rec.body.Len(), recordedResponseMaxSize)
+	}
+	if res := rec.result("big"); res.Status != http.StatusInternalServerError {
+		t.Errorf("status rezultata = %d, očekivano 500", res.Status)
+	}
+}
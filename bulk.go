@@ -0,0 +1,817 @@
+// bulk.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// Režimi grupnih operacija: atomic = sve ili ništa, best_effort = svaka stavka posebno.
+const (
+	BulkModeAtomic     = "atomic"
+	BulkModeBestEffort = "best_effort"
+)
+
+// Statusi stavki grupne operacije.
+const (
+	BulkStatusOK      = "ok"
+	BulkStatusError   = "error"
+	BulkStatusSkipped = "skipped" // Nije primenjena jer je atomic operacija poništena
+)
+
+const (
+	bulkMaxItems     = 1000  // Najveći broj stavki u jednom zahtevu
+	bulkMaxInsertArg = 60000 // Postgres dozvoljava najviše 65535 parametara po upitu
+)
+
+// BulkUpdateItem is a single {id, changes} entry of a bulk update.
+type BulkUpdateItem struct {
+	ID      interface{}            `json:"id"`
+	Changes map[string]interface{} `json:"changes"`
+	IfMatch string                 `json:"if_match,omitempty"` // Opcioni ETag, kao If-Match zaglavlje
+}
+
+// BulkRequest is the body of the bulk endpoints. Koriste se polja koja odgovaraju operaciji:
+// records (create, upsert), items ili filter+changes (update), ids ili filter (delete).
+type BulkRequest struct {
+	Mode    string                   `json:"mode"`
+	Records []map[string]interface{} `json:"records,omitempty"`
+	Items   []BulkUpdateItem         `json:"items,omitempty"`
+	IDs     []interface{}            `json:"ids,omitempty"`
+	Filter  map[string]string        `json:"filter,omitempty"` // Isti ključevi kao query filteri liste (npr. "price__lt")
+	Changes map[string]interface{}   `json:"changes,omitempty"`
+	Key     []string                 `json:"key,omitempty"` // Unique ključ za upsert; prazno = prvi deklarisani
+}
+
+// BulkItemResult reports the outcome of one item.
+type BulkItemResult struct {
+	Index  int              `json:"index"`
+	ID     interface{}      `json:"id,omitempty"`
+	Status string           `json:"status"`
+	Action string           `json:"action,omitempty"` // Upsert: "inserted" ili "updated"
+	Field  string           `json:"field,omitempty"`
+	Code   string           `json:"code,omitempty"` // Kod greške baze (npr. "unique_violation")
+	Error  string           `json:"error,omitempty"`
+	Fields ValidationErrors `json:"fields,omitempty"` // Sve greške po poljima
+}
+
+// BulkResult reports the outcome of a bulk operation.
+type BulkResult struct {
+	Mode      string           `json:"mode"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
+
+// newBulkResult pravi rezultat sa po jednom stavkom za svaki ulaz.
+func newBulkResult(mode string, n int) *BulkResult {
+	result := &BulkResult{Mode: mode, Items: make([]BulkItemResult, n)}
+	for i := range result.Items {
+		result.Items[i] = BulkItemResult{Index: i, Status: BulkStatusOK}
+	}
+	return result
+}
+
+// fail označava stavku kao neuspešnu.
+func (r *BulkResult) fail(index int, err error) {
+	item := &r.Items[index]
+	item.Status = BulkStatusError
+	var vErrs ValidationErrors
+	var vErr *ValidationError
+	var notFound *RecordNotFoundError
+	var conflict *PreconditionFailedError
+	switch {
+	case errors.As(err, &vErrs):
+		item.Field = vErrs[0].Field
+		item.Error = vErrs.Error()
+		item.Fields = vErrs
+	case errors.As(err, &vErr):
+		item.Field = vErr.Field
+		item.Error = vErr.Message
+	case errors.As(err, &notFound):
+		item.Code = ErrCodeNotFound
+		item.Error = notFound.Error()
+	case errors.As(err, &conflict):
+		item.Code = ErrCodePreconditionFailed
+		item.Error = conflict.Error()
+	default:
+		// Prekršeno ograničenje baze (unique, FK...) ima kod i kolonu
+		if body := databaseErrorBody(err); body != nil {
+			item.Code = body.Code
+			item.Error = body.Message
+			if len(body.Fields) > 0 {
+				item.Field = body.Fields[0].Field
+				item.Fields = body.Fields
+			}
+			return
+		}
+		// Ostale greške (npr. tekst greške baze) idu samo u log, kao kod pojedinačnih zahteva
+		log.Printf("ERROR: Greška grupne operacije za stavku %d: %v", index, err)
+		item.Code = ErrCodeInternal
+		item.Error = internalErrorMessage
+	}
+}
+
+// failByID označava grešku stavke sa datim ID-em i poništava atomic operaciju.
+// Ako ID nije među stavkama, greška se vraća neizmenjena, umesto da se pripiše pogrešnoj stavci.
+func (r *BulkResult) failByID(ids []string, id string, err error) error {
+	i := indexOf(ids, id)
+	if i < 0 {
+		return err
+	}
+	r.fail(i, err)
+	return errBulkRolledBack
+}
+
+// hasErrors javlja da li neka stavka nije uspela.
+func (r *BulkResult) hasErrors() bool {
+	for _, item := range r.Items {
+		if item.Status == BulkStatusError {
+			return true
+		}
+	}
+	return false
+}
+
+// finish prebrojava ishode; u atomic režimu sa greškom ostale stavke postaju "skipped".
+func (r *BulkResult) finish() {
+	rolledBack := r.Mode == BulkModeAtomic && r.hasErrors()
+	r.Succeeded, r.Failed = 0, 0
+	for i := range r.Items {
+		switch {
+		case r.Items[i].Status == BulkStatusError:
+			r.Failed++
+		case rolledBack:
+			r.Items[i].Status = BulkStatusSkipped
+		default:
+			r.Succeeded++
+		}
+	}
+}
+
+// errBulkRolledBack signalizira withTx-u da poništi atomic operaciju; detalji su u BulkResult.
+var errBulkRolledBack = errors.New("grupna operacija je poništena")
+
+// buildFilterCondition pravi WHERE uslov od filtera u formatu query parametara liste.
+// Za razliku od liste, nepoznat ili nevažeći filter je greška, da se operacija
+// ne bi tiho primenila na više zapisa nego što je traženo. Filter sme da koristi
+// samo kolone modula koje korisnik sme da čita; putanje kroz lookup kolone nisu podržane.
+func (s *SQLDataset) buildFilterCondition(ctx context.Context, moduleDef *ModuleDefinition, filter map[string]string) (string, []interface{}, error) {
+	if len(filter) == 0 {
+		return "", nil, NewValidationError("filter", "filter ne sme biti prazan")
+	}
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Stabilan redosled parametara
+
+	whereClauses := []string{}
+	args := []interface{}{}
+	argCounter := 1
+	joins := newQueryJoins(UserFromContext(ctx), false)
+	for _, key := range keys {
+		before := len(whereClauses)
+		if key == "_search" {
+			s.addSearchCondition(moduleDef, joins, filter[key], "", &whereClauses, &args, &argCounter)
+		} else {
+			s.buildWhereClause(moduleDef, joins, key, filter[key], &whereClauses, &args, &argCounter)
+		}
+		if err := joins.deniedError(); err != nil {
+			return "", nil, err
+		}
+		if len(whereClauses) == before {
+			return "", nil, NewValidationError(key, fmt.Sprintf("nevažeći filter '%s=%s'", key, filter[key]))
+		}
+	}
+	return strings.Join(whereClauses, " AND "), args, nil
+}
+
+// lockRecords čita i zaključava aktivne zapise koji zadovoljavaju uslov, indeksirane po ID-u (kao tekst).
+func (s *SQLDataset) lockRecords(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, where string, args ...interface{}) (map[string]map[string]interface{}, []string, error) {
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(where, moduleDef, ScopeActive), moduleDef, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY %s FOR UPDATE",
+		moduleDef.DBTableName, where, pkCol.DBColumnName)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("greška pri zaključavanju zapisa modula '%s': %w", moduleDef.Name, err)
+	}
+	defer rows.Close()
+
+	records, err := scanRecords(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]map[string]interface{}, len(records))
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		id := bulkRecordID(record[pkCol.DBColumnName])
+		byID[id] = record
+		ids = append(ids, id)
+	}
+	return byID, ids, nil
+}
+
+// BulkCreate inserts many records. U atomic režimu zapisi se upisuju višerednim INSERT
+// upitima u jednoj transakciji; u best_effort režimu svaki zapis u svom savepoint-u.
+func (s *SQLDataset) BulkCreate(ctx context.Context, moduleDef *ModuleDefinition, records []map[string]interface{}, mode string) (*BulkResult, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
+	}
+	result := newBulkResult(mode, len(records))
+	for i, payload := range records {
+		if err := s.validateRecord(ctx, validationTarget{Module: moduleDef}, payload); err != nil {
+			result.fail(i, err)
+		}
+	}
+	if mode == BulkModeAtomic && result.hasErrors() {
+		result.finish()
+		return result, nil
+	}
+
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if mode == BulkModeBestEffort {
+			for i, payload := range records {
+				if result.Items[i].Status == BulkStatusError {
+					continue
+				}
+				err := withSavepoint(ctx, tx, func() error {
+					created, err := s.createRecordTx(ctx, tx, moduleDef, payload)
+					if err == nil {
+						result.Items[i].ID = created[pkCol.DBColumnName]
+					}
+					return err
+				})
+				if err != nil {
+					result.fail(i, err)
+				}
+			}
+			return nil
+		}
+
+		if err := s.insertMany(ctx, tx, moduleDef, pkCol, records, result); err != nil {
+			return err
+		}
+		if result.hasErrors() {
+			return errBulkRolledBack
+		}
+		return nil
+	})
+	if err == errBulkRolledBack {
+		for i := range result.Items {
+			result.Items[i].ID = nil // Upisani redovi su poništeni
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	result.finish()
+	return result, nil
+}
+
+// insertMany izvršava BeforeCreate handler-e, grupiše zapise po skupu kolona i upisuje
+// svaku grupu jednim (ili, za velike grupe, nekoliko) višerednim INSERT upitom.
+func (s *SQLDataset) insertMany(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, records []map[string]interface{}, result *BulkResult) error {
+	type insertGroup struct {
+		cols    []string
+		indexes []int
+		values  [][]interface{}
+	}
+	groups := make(map[string]*insertGroup)
+	order := make([]string, 0)
+	now := time.Now()
+
+	for i, payload := range records {
+		if err := s.beforeCreate(ctx, moduleDef, payload); err != nil {
+			result.fail(i, err)
+			return nil
+		}
+		cols, vals, err := insertValues(ctx, moduleDef, payload, now)
+		if err != nil {
+			result.fail(i, err)
+			return nil
+		}
+		key := strings.Join(cols, ",")
+		g, ok := groups[key]
+		if !ok {
+			g = &insertGroup{cols: cols}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.indexes = append(g.indexes, i)
+		g.values = append(g.values, vals)
+	}
+
+	for _, key := range order {
+		g := groups[key]
+		chunk := bulkMaxInsertArg / len(g.cols)
+		for start := 0; start < len(g.values); start += chunk {
+			end := min(start+chunk, len(g.values))
+
+			var created []map[string]interface{}
+			err := withSavepoint(ctx, tx, func() error {
+				var err error
+				created, err = insertRows(ctx, tx, moduleDef, g.cols, g.values[start:end])
+				return err
+			})
+			if err != nil {
+				// Greška višerednog upita ne kaže koji red je kriv: ponovi red po red, svaki u svom savepoint-u
+				log.Printf("WARNING: Višeredni INSERT za modul '%s' nije uspeo, ponavljam red po red: %v", moduleDef.ID, err)
+				created = make([]map[string]interface{}, 0, end-start)
+				for j, vals := range g.values[start:end] {
+					var row []map[string]interface{}
+					err := withSavepoint(ctx, tx, func() error {
+						var err error
+						row, err = insertRows(ctx, tx, moduleDef, g.cols, [][]interface{}{vals})
+						return err
+					})
+					if err != nil {
+						result.fail(g.indexes[start+j], err)
+						return nil
+					}
+					created = append(created, row[0])
+				}
+			}
+
+			// RETURNING vraća redove istim redosledom kao VALUES (broj redova proverava insertRows)
+			for j, record := range created {
+				idx := g.indexes[start+j]
+				result.Items[idx].ID = record[pkCol.DBColumnName]
+				if err := s.Hooks.runAfterCreate(ctx, moduleDef, record); err != nil {
+					result.fail(idx, err)
+					return nil
+				}
+				if err := s.recordChange(ctx, tx, moduleDef, record[pkCol.DBColumnName], AuditCreate, nil, record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// insertRows upisuje redove jednim višerednim INSERT upitom i vraća upisane redove.
+// Ako upit ne vrati tačno po jedan red za svaki ulaz (npr. BEFORE INSERT trigger vrati NULL),
+// vraća grešku, jer se upisani redovi više ne mogu pouzdano upariti sa stavkama.
+func insertRows(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, cols []string, values [][]interface{}) ([]map[string]interface{}, error) {
+	args := make([]interface{}, 0, len(values)*len(cols))
+	rowsSQL := make([]string, 0, len(values))
+	for _, vals := range values {
+		placeholders := make([]string, len(vals))
+		for j, val := range vals {
+			args = append(args, val)
+			placeholders[j] = fmt.Sprintf("$%d", len(args))
+		}
+		rowsSQL = append(rowsSQL, "("+strings.Join(placeholders, ", ")+")")
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s RETURNING *",
+		moduleDef.DBTableName, strings.Join(cols, ", "), strings.Join(rowsSQL, ", "))
+
+	log.Printf("DEBUG: Executing bulk INSERT for module '%s': %d rows", moduleDef.ID, len(values))
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri izvršavanju INSERT upita: %w", err)
+	}
+	created, err := scanRecords(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(created) != len(values) {
+		return nil, fmt.Errorf("INSERT upit za modul '%s' je vratio %d redova umesto %d", moduleDef.ID, len(created), len(values))
+	}
+	return created, nil
+}
+
+// BulkUpdate applies per-record changes; svaka stavka može imati svoj If-Match ETag.
+func (s *SQLDataset) BulkUpdate(ctx context.Context, moduleDef *ModuleDefinition, items []BulkUpdateItem, mode string) (*BulkResult, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
+	}
+	result := newBulkResult(mode, len(items))
+	for i, item := range items {
+		result.Items[i].ID = item.ID
+		switch {
+		case item.ID == nil:
+			result.fail(i, NewValidationError("id", "ID zapisa je obavezan"))
+		case len(item.Changes) == 0:
+			result.fail(i, NewValidationError("changes", "nema izmena"))
+		default:
+			target := validationTarget{Module: moduleDef, RecordID: bulkRecordID(item.ID), Partial: true}
+			if err := s.validateRecord(ctx, target, item.Changes); err != nil {
+				result.fail(i, err)
+			}
+		}
+	}
+	if mode == BulkModeAtomic && result.hasErrors() {
+		result.finish()
+		return result, nil
+	}
+
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for i, item := range items {
+			if result.Items[i].Status == BulkStatusError {
+				continue
+			}
+			update := func() error {
+				_, err := s.updateRecordTx(ctx, tx, moduleDef, pkCol, bulkRecordID(item.ID), item.Changes, item.IfMatch)
+				return err
+			}
+			if mode == BulkModeBestEffort {
+				if err := withSavepoint(ctx, tx, update); err != nil {
+					result.fail(i, err)
+				}
+				continue
+			}
+			if err := update(); err != nil {
+				result.fail(i, err)
+				return errBulkRolledBack
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errBulkRolledBack {
+		return nil, err
+	}
+	result.finish()
+	return result, nil
+}
+
+// BulkUpdateByFilter applies the same changes to every record matching the filter.
+// Bez BeforeUpdate handler-a (koji mogu da menjaju payload po zapisu) izmena se izvršava
+// jednim UPDATE upitom; inače zapis po zapis.
+func (s *SQLDataset) BulkUpdateByFilter(ctx context.Context, moduleDef *ModuleDefinition, filter map[string]string, changes map[string]interface{}, mode string) (*BulkResult, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
+	}
+	if len(changes) == 0 {
+		return nil, NewValidationError("changes", "nema izmena")
+	}
+	if err := s.validateRecord(ctx, validationTarget{Module: moduleDef, Partial: true}, changes); err != nil {
+		return nil, err
+	}
+	where, whereArgs, err := s.buildFilterCondition(ctx, moduleDef, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *BulkResult
+	err = s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		before, ids, err := s.lockRecords(ctx, tx, moduleDef, pkCol, where, whereArgs...)
+		if err != nil {
+			return err
+		}
+		result = newBulkResult(mode, len(ids))
+		for i, id := range ids {
+			result.Items[i].ID = before[id][pkCol.DBColumnName]
+		}
+
+		if mode == BulkModeBestEffort || s.Hooks.hasBeforeUpdate(moduleDef.ID) {
+			for i, id := range ids {
+				// Svaki zapis dobija svoju kopiju izmena, jer handler-i mogu da je menjaju
+				payload := make(map[string]interface{}, len(changes))
+				for k, v := range changes {
+					payload[k] = v
+				}
+				update := func() error {
+					_, err := s.updateRecordTx(ctx, tx, moduleDef, pkCol, id, payload, "")
+					return err
+				}
+				if mode == BulkModeBestEffort {
+					if err := withSavepoint(ctx, tx, update); err != nil {
+						result.fail(i, err)
+					}
+					continue
+				}
+				if err := update(); err != nil {
+					result.fail(i, err)
+					return errBulkRolledBack
+				}
+			}
+			return nil
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+		setClauses, args, err := buildUpdateSet(ctx, moduleDef, changes)
+		if err != nil {
+			return err
+		}
+		args = append(args, pq.Array(ids))
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ANY($%d) RETURNING *",
+			moduleDef.DBTableName, strings.Join(setClauses, ", "), pkCol.DBColumnName, len(args))
+
+		log.Printf("DEBUG: Executing bulk UPDATE for module '%s': %d rows", moduleDef.ID, len(ids))
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("greška pri izvršavanju UPDATE upita za modul '%s': %w", moduleDef.Name, err)
+		}
+		updated, err := scanRecords(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		for _, after := range updated {
+			id := bulkRecordID(after[pkCol.DBColumnName])
+			if err := s.Hooks.runAfterUpdate(ctx, moduleDef, before[id], after); err != nil {
+				return result.failByID(ids, id, err)
+			}
+			if err := s.recordChange(ctx, tx, moduleDef, id, AuditUpdate, before[id], after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errBulkRolledBack {
+		return nil, err
+	}
+	result.finish()
+	return result, nil
+}
+
+// BulkDelete deletes records given by ID or matched by a filter. U atomic režimu svi
+// zapisi se brišu (ili soft delete-uju) jednim upitom; nepostojeći ID poništava operaciju.
+func (s *SQLDataset) BulkDelete(ctx context.Context, moduleDef *ModuleDefinition, ids []interface{}, filter map[string]string, mode string) (*BulkResult, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
+	}
+
+	var where string
+	var whereArgs []interface{}
+	requested := make([]string, len(ids))
+	if len(ids) > 0 {
+		for i, id := range ids {
+			requested[i] = bulkRecordID(id)
+		}
+		where = fmt.Sprintf("%s = ANY($1)", pkCol.DBColumnName)
+		whereArgs = []interface{}{pq.Array(requested)}
+	} else {
+		var err error
+		if where, whereArgs, err = s.buildFilterCondition(ctx, moduleDef, filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *BulkResult
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		current, found, err := s.lockRecords(ctx, tx, moduleDef, pkCol, where, whereArgs...)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			requested = found // Brišu se svi zapisi koji zadovoljavaju filter
+		}
+		result = newBulkResult(mode, len(requested))
+		for i, id := range requested {
+			result.Items[i].ID = id
+			if _, ok := current[id]; !ok {
+				result.fail(i, errRecordNotFound("zapis sa ID '%s' nije pronađen ili obrisan u modulu '%s'", id, moduleDef.Name))
+			}
+		}
+
+		if mode == BulkModeBestEffort {
+			for i, id := range requested {
+				if result.Items[i].Status == BulkStatusError {
+					continue
+				}
+				if err := withSavepoint(ctx, tx, func() error {
+					return s.deleteRecordTx(ctx, tx, moduleDef, pkCol, id, "")
+				}); err != nil {
+					result.fail(i, err)
+				}
+			}
+			return nil
+		}
+		if result.hasErrors() {
+			return errBulkRolledBack
+		}
+		if len(found) == 0 {
+			return nil
+		}
+
+		for i, id := range requested {
+			if err := s.Hooks.runBeforeDelete(ctx, moduleDef, current[id]); err != nil {
+				result.fail(i, err)
+				return errBulkRolledBack
+			}
+		}
+
+		args := []interface{}{pq.Array(found)}
+		var query string
+		if moduleDef.HasSoftDelete() {
+			query = fmt.Sprintf("UPDATE %s SET %s WHERE %s = ANY($1) RETURNING *",
+				moduleDef.DBTableName, softDeleteSetClause(ctx, moduleDef, &args), pkCol.DBColumnName)
+		} else {
+			query = fmt.Sprintf("DELETE FROM %s WHERE %s = ANY($1) RETURNING *",
+				moduleDef.DBTableName, pkCol.DBColumnName)
+		}
+
+		log.Printf("DEBUG: Executing bulk DELETE for module '%s': %d rows", moduleDef.ID, len(found))
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("greška pri izvršavanju DELETE upita za modul '%s': %w", moduleDef.Name, err)
+		}
+		deleted, err := scanRecords(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		for _, record := range deleted {
+			id := bulkRecordID(record[pkCol.DBColumnName])
+			if err := s.Hooks.runAfterDelete(ctx, moduleDef, record); err != nil {
+				return result.failByID(requested, id, err)
+			}
+			// Kao kod pojedinačnog brisanja: soft delete beleži stanje pre i posle, a DELETE samo obrisan red
+			before, after := record, map[string]interface{}(nil)
+			if moduleDef.HasSoftDelete() {
+				before, after = current[id], record
+			}
+			if err := s.recordChange(ctx, tx, moduleDef, id, AuditDelete, before, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errBulkRolledBack {
+		return nil, err
+	}
+	result.finish()
+	return result, nil
+}
+
+// indexOf vraća indeks vrednosti u listi, ili -1 ako je nema.
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// bulkRecordID vraća tekstualni ID zapisa; JSON brojevi se pišu bez eksponenta
+// (fmt.Sprint bi za 1e21 dao "1e+21", koji se ne poklapa sa ID-em iz baze).
+func bulkRecordID(id interface{}) string {
+	if f, ok := id.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(id)
+}
+
+// decodeBulkRequest čita telo grupnog zahteva i proverava režim i broj stavki.
+func decodeBulkRequest(w http.ResponseWriter, req *http.Request) (*BulkRequest, bool) {
+	var body BulkRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	switch body.Mode {
+	case "":
+		body.Mode = BulkModeAtomic
+	case BulkModeAtomic, BulkModeBestEffort:
+	default:
+		writeError(w, fmt.Sprintf("Nepodržan režim '%s' (dozvoljeno: atomic, best_effort).", body.Mode), http.StatusBadRequest)
+		return nil, false
+	}
+	if n := max(len(body.Records), len(body.Items), len(body.IDs)); n > bulkMaxItems {
+		writeError(w, fmt.Sprintf("Previše stavki u zahtevu (%d, najviše %d).", n, bulkMaxItems), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	return &body, true
+}
+
+// writeBulkResult vraća rezultat grupne operacije; poništena atomic operacija vraća 422.
+func writeBulkResult(w http.ResponseWriter, moduleDef *ModuleDefinition, operation string, result *BulkResult) {
+	status := http.StatusOK
+	if result.Mode == BulkModeAtomic && result.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju rezultata grupne operacije: %v", err)
+	}
+	log.Printf("INFO: Grupna operacija '%s' nad modulom '%s': %d uspešno, %d neuspešno.", operation, moduleDef.ID, result.Succeeded, result.Failed)
+}
+
+// bulkModule pronalazi modul tipa "table" i proverava dozvolu za operaciju.
+func (s *APIServer) bulkModule(w http.ResponseWriter, req *http.Request, operation string) *ModuleDefinition {
+	moduleID := mux.Vars(req)["moduleID"]
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return nil
+	}
+	if moduleDef.Type != "table" {
+		writeError(w, fmt.Sprintf("Grupne operacije nisu podržane za modul tipa '%s'.", moduleDef.Type), http.StatusBadRequest)
+		return nil
+	}
+	if !s.authorize(w, req, moduleDef, operation) {
+		return nil
+	}
+	return moduleDef
+}
+
+// BulkCreateRecords handles POST /api/modules/{moduleID}/bulk with {"records": [...]}.
+func (s *APIServer) BulkCreateRecords(w http.ResponseWriter, req *http.Request) {
+	moduleDef := s.bulkModule(w, req, PermCreate)
+	if moduleDef == nil {
+		return
+	}
+	body, ok := decodeBulkRequest(w, req)
+	if !ok {
+		return
+	}
+	if len(body.Records) == 0 {
+		writeError(w, "Polje 'records' mora sadržati bar jedan zapis.", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.dataset.BulkCreate(req.Context(), moduleDef, body.Records, body.Mode)
+	if err != nil {
+		writeInternalError(w, fmt.Sprintf("Greška pri grupnom kreiranju zapisa za modul '%s'", moduleDef.ID), err)
+		return
+	}
+	writeBulkResult(w, moduleDef, "create", result)
+}
+
+// BulkUpdateRecords handles PATCH /api/modules/{moduleID}/bulk with {"items": [{id, changes}]}
+// or {"filter": {...}, "changes": {...}}.
+func (s *APIServer) BulkUpdateRecords(w http.ResponseWriter, req *http.Request) {
+	moduleDef := s.bulkModule(w, req, PermUpdate)
+	if moduleDef == nil {
+		return
+	}
+	body, ok := decodeBulkRequest(w, req)
+	if !ok {
+		return
+	}
+
+	var result *BulkResult
+	var err error
+	switch {
+	case len(body.Items) > 0:
+		result, err = s.dataset.BulkUpdate(req.Context(), moduleDef, body.Items, body.Mode)
+	case len(body.Filter) > 0:
+		result, err = s.dataset.BulkUpdateByFilter(req.Context(), moduleDef, body.Filter, body.Changes, body.Mode)
+	default:
+		writeError(w, "Potrebno je polje 'items' ili 'filter' sa 'changes'.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri grupnom ažuriranju zapisa za modul '%s'", moduleDef.ID), err)
+		return
+	}
+	writeBulkResult(w, moduleDef, "update", result)
+}
+
+// BulkDeleteRecords handles DELETE /api/modules/{moduleID}/bulk with {"ids": [...]} or {"filter": {...}}.
+func (s *APIServer) BulkDeleteRecords(w http.ResponseWriter, req *http.Request) {
+	moduleDef := s.bulkModule(w, req, PermDelete)
+	if moduleDef == nil {
+		return
+	}
+	body, ok := decodeBulkRequest(w, req)
+	if !ok {
+		return
+	}
+	if len(body.IDs) == 0 && len(body.Filter) == 0 {
+		writeError(w, "Potrebno je polje 'ids' ili 'filter'.", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.dataset.BulkDelete(req.Context(), moduleDef, body.IDs, body.Filter, body.Mode)
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri grupnom brisanju zapisa za modul '%s'", moduleDef.ID), err)
+		return
+	}
+	writeBulkResult(w, moduleDef, "delete", result)
+}
@@ -0,0 +1,68 @@
+// bulk_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBulkRecordID(t *testing.T) {
+	tests := []struct {
+		id   interface{}
+		want string
+	}{
+		{float64(42), "42"},
+		{float64(1e21), "1000000000000000000000"},
+		{int64(7), "7"},
+		{"abc", "abc"},
+	}
+	for _, tt := range tests {
+		if got := bulkRecordID(tt.id); got != tt.want {
+			t.Errorf("bulkRecordID(%#v) = %q, očekivano %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestBulkResultFailByID(t *testing.T) {
+	result := newBulkResult(BulkModeAtomic, 2)
+	if err := result.failByID([]string{"1", "2"}, "2", errors.New("odbijeno")); err != errBulkRolledBack {
+		t.Errorf("poznat ID: greška %v, očekivano errBulkRolledBack", err)
+	}
+	if result.Items[0].Status == BulkStatusError || result.Items[1].Status != BulkStatusError {
+		t.Errorf("greška pripisana pogrešnoj stavci: %+v", result.Items)
+	}
+
+	result = newBulkResult(BulkModeAtomic, 1)
+	cause := errors.New("odbijeno")
+	if err := result.failByID([]string{"1"}, "3", cause); err != cause || result.hasErrors() {
+		t.Errorf("nepoznat ID: greška %v, stavke %+v", err, result.Items)
+	}
+}
+
+func TestBulkCreateAtomicReportsFailingRow(t *testing.T) {
+	module := testEmployeesModule()
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, name TEXT NOT NULL UNIQUE, salary INT, version INT NOT NULL DEFAULT 1")
+	if _, err := ds.db.Exec("INSERT INTO test_employees (name) VALUES ('Ana')"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []map[string]interface{}{{"name": "Boris"}, {"name": "Ana"}, {"name": "Vesna"}}
+	result, err := ds.BulkCreate(context.Background(), module, records, BulkModeAtomic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, item := range result.Items {
+		if failed := item.Status == BulkStatusError; failed != (i == 1) {
+			t.Errorf("stavka %d: %+v", i, item)
+		}
+		if item.ID != nil {
+			t.Errorf("stavka %d ima ID posle poništavanja: %+v", i, item)
+		}
+	}
+	var count int
+	if err := ds.db.QueryRow("SELECT count(*) FROM test_employees").Scan(&count); err != nil || count != 1 {
+		t.Errorf("broj zapisa posle neuspelog atomic upisa: %d, %v", count, err)
+	}
+}
@@ -0,0 +1,415 @@
+// codegen.go
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// goInitialisms su delovi imena koji se u Go identifikatorima pišu velikim slovima.
+var goInitialisms = map[string]string{
+	"id": "ID", "url": "URL", "uri": "URI", "api": "API", "http": "HTTP", "json": "JSON",
+	"sql": "SQL", "uuid": "UUID", "ip": "IP", "sku": "SKU", "vat": "VAT", "pib": "PIB",
+}
+
+// goReservedTypes su imena tipova koje generisani kod već koristi.
+var goReservedTypes = map[string]bool{"Client": true, "APIError": true, "FieldError": true, "LookupRef": true}
+
+// goIdent pretvara ime kolone ili modula u izvezen Go identifikator ("customer_id" -> "CustomerID").
+func goIdent(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		if upper, ok := goInitialisms[strings.ToLower(part)]; ok {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	ident := b.String()
+	if ident == "" || ident[0] >= '0' && ident[0] <= '9' {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// goSingular pravi jedninu od imena u množini (za tip jednog zapisa): "Categories" -> "Category".
+func goSingular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// goTypeNames dodeljuje Go ime tipa svakom modulu ("module_order_items" -> "OrderItem").
+// Ako se dva modula preslikaju u isto ime, koristi se puno ime iz ID-a modula.
+func goTypeNames(modules []*ModuleDefinition) map[string]string {
+	names := make(map[string]string, len(modules))
+	used := make(map[string]int)
+	for _, moduleDef := range modules {
+		name := goSingular(goIdent(strings.TrimPrefix(moduleDef.ID, "module_")))
+		if goReservedTypes[name] {
+			name += "Record"
+		}
+		names[moduleDef.ID] = name
+		used[name]++
+	}
+	for _, moduleDef := range modules {
+		if used[names[moduleDef.ID]] > 1 {
+			names[moduleDef.ID] = goIdent(moduleDef.ID)
+		}
+	}
+	return names
+}
+
+// goColumnType vraća Go tip vrednosti kolone (bez pokazivača).
+func (s *APIServer) goColumnType(colDef *ColumnDefinition) string {
+	colType := colDef.Type
+	if colType == "lookup" {
+		colType = "integer"
+		if colDef.LookupModule != nil {
+			if pk := s.dataset.getPrimaryKeyColumn(colDef.LookupModule); pk != nil {
+				colType = pk.Type
+			}
+		}
+	}
+	switch colType {
+	case "integer":
+		return "int64"
+	case "float":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "date", "datetime":
+		return "time.Time"
+	default:
+		return "string"
+	}
+}
+
+// goCodeWriter gradi izvorni kod generisanog paketa.
+type goCodeWriter struct {
+	strings.Builder
+}
+
+func (w *goCodeWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(w, format, args...)
+	w.WriteByte('\n')
+}
+
+// GenerateGoCode emits a Go package with typed structs for every module and a REST client.
+// Izlaz je formatiran (gofmt); greška formatiranja znači grešku u generatoru.
+func (s *APIServer) GenerateGoCode(pkg string) ([]byte, error) {
+	modules := make([]*ModuleDefinition, 0, len(s.config.Modules))
+	for _, moduleDef := range s.config.Modules {
+		if moduleDef.Type != "root" && moduleDef.Type != "group" {
+			modules = append(modules, moduleDef)
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].ID < modules[j].ID })
+	names := goTypeNames(modules)
+
+	body := &goCodeWriter{}
+	body.WriteString(goClientRuntime)
+	for _, moduleDef := range modules {
+		s.writeModuleTypes(body, moduleDef, names)
+		s.writeModuleClient(body, moduleDef, names[moduleDef.ID])
+	}
+
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "strings"}
+	if strings.Contains(body.String(), "time.Time") {
+		imports = append(imports, "time")
+	}
+	w := &goCodeWriter{}
+	w.line("// Code generated by \"demo generate\"; DO NOT EDIT.")
+	w.line("")
+	w.line("package %s", pkg)
+	w.line("")
+	w.line("import (")
+	for _, imp := range imports {
+		w.line("%q", imp)
+	}
+	w.line(")")
+	w.line("")
+	w.WriteString(body.String())
+
+	src, err := format.Source([]byte(w.String()))
+	if err != nil {
+		return nil, fmt.Errorf("greška pri formatiranju generisanog koda: %w", err)
+	}
+	return src, nil
+}
+
+// writeModuleTypes piše strukture modula: zapis (odgovor), Input (POST/PUT) i Patch (PATCH).
+func (s *APIServer) writeModuleTypes(w *goCodeWriter, moduleDef *ModuleDefinition, names map[string]string) {
+	name := names[moduleDef.ID]
+
+	w.line("// %s is a record of module %q (%s).", name, moduleDef.ID, moduleDef.Name)
+	w.line("type %s struct {", name)
+	fields := make(map[string]bool)
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		field := goIdent(colDef.DBColumnName)
+		fields[field] = true
+		goType := s.goColumnType(colDef)
+		switch {
+		case colDef.Type == "lookup":
+			// Pri čitanju se lookup proširuje u {"id": ..., "name": ..., lookup_fields...}
+			goType = fmt.Sprintf("*LookupRef[%s]", goType)
+		case !colDef.IsPrimaryKey && !colDef.IsRequired():
+			goType = "*" + goType
+		}
+		w.line("%s %s `json:%q db:%q` // %s", field, goType, colDef.DBColumnName, colDef.DBColumnName, colDef.Name)
+	}
+	for _, sub := range moduleDef.SubModules {
+		target, ok := names[sub.TargetModuleID]
+		if !ok {
+			continue
+		}
+		field := goIdent(strings.TrimPrefix(sub.TargetModuleID, "module_"))
+		if fields[field] {
+			field += "Items"
+		}
+		fields[field] = true
+		w.line("%s []%s `json:\"%s,omitempty\"` // %s (samo pri čitanju jednog zapisa)", field, target, sub.TargetModuleID, sub.DisplayName)
+	}
+	w.line("}")
+	w.line("")
+
+	if moduleDef.Type != "table" {
+		return
+	}
+
+	w.line("// %sInput is the payload for creating or replacing a %q record.", name, moduleDef.ID)
+	w.line("type %sInput struct {", name)
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if !isInputColumn(colDef) {
+			continue
+		}
+		goType, tag := s.goColumnType(colDef), colDef.DBColumnName
+		if !colDef.IsRequired() {
+			goType, tag = "*"+goType, tag+",omitempty"
+		}
+		w.line("%s %s `json:%q db:%q`", goIdent(colDef.DBColumnName), goType, tag, colDef.DBColumnName)
+	}
+	w.line("}")
+	w.line("")
+
+	w.line("// %sPatch holds a partial update of a %q record; nil fields are left unchanged.", name, moduleDef.ID)
+	w.line("type %sPatch struct {", name)
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if !isInputColumn(colDef) {
+			continue
+		}
+		w.line("%s *%s `json:\"%s,omitempty\" db:%q`", goIdent(colDef.DBColumnName), s.goColumnType(colDef), colDef.DBColumnName, colDef.DBColumnName)
+	}
+	w.line("}")
+	w.line("")
+}
+
+// writeModuleClient piše metode klijenta za rute modula.
+func (s *APIServer) writeModuleClient(w *goCodeWriter, moduleDef *ModuleDefinition, name string) {
+	path := "/api/modules/" + moduleDef.ID
+	plural := goIdent(strings.TrimPrefix(moduleDef.ID, "module_"))
+	if plural == name {
+		plural += "List"
+	}
+
+	w.line("// List%s returns records of %q; query holds filters, _sort, _limit, _offset and _search.", plural, moduleDef.ID)
+	w.line("func (c *Client) List%s(ctx context.Context, query url.Values) ([]%s, error) {", plural, name)
+	w.line("var out []%s", name)
+	w.line("if err := c.do(ctx, http.MethodGet, %q, query, nil, &out); err != nil { return nil, err }", path)
+	w.line("return out, nil")
+	w.line("}")
+	w.line("")
+
+	pk := s.dataset.getPrimaryKeyColumn(moduleDef)
+	if pk == nil {
+		return
+	}
+	idType := s.goColumnType(pk)
+	recordPath := fmt.Sprintf("%q + url.PathEscape(fmt.Sprint(id))", path+"/")
+
+	w.line("// Get%s returns a single %q record with its submodules.", name, moduleDef.ID)
+	w.line("func (c *Client) Get%s(ctx context.Context, id %s) (*%s, error) {", name, idType, name)
+	w.line("out := new(%s)", name)
+	w.line("if err := c.do(ctx, http.MethodGet, %s, nil, nil, out); err != nil { return nil, err }", recordPath)
+	w.line("return out, nil")
+	w.line("}")
+	w.line("")
+
+	if moduleDef.Type != "table" {
+		return
+	}
+
+	w.line("// Create%s creates a %q record and returns it as stored.", name, moduleDef.ID)
+	w.line("func (c *Client) Create%s(ctx context.Context, in *%sInput) (*%s, error) {", name, name, name)
+	w.line("var out struct{ Record *%s `json:\"record\"` }", name)
+	w.line("if err := c.do(ctx, http.MethodPost, %q, nil, in, &out); err != nil { return nil, err }", path)
+	w.line("return out.Record, nil")
+	w.line("}")
+	w.line("")
+
+	w.line("// Replace%s replaces a %q record (PUT); omitted fields get their default values.", name, moduleDef.ID)
+	w.line("func (c *Client) Replace%s(ctx context.Context, id %s, in *%sInput) (*%s, error) {", name, idType, name, name)
+	w.line("var out struct{ Record *%s `json:\"record\"` }", name)
+	w.line("if err := c.do(ctx, http.MethodPut, %s, nil, in, &out); err != nil { return nil, err }", recordPath)
+	w.line("return out.Record, nil")
+	w.line("}")
+	w.line("")
+
+	w.line("// Patch%s applies a partial update (merge patch) to a %q record.", name, moduleDef.ID)
+	w.line("func (c *Client) Patch%s(ctx context.Context, id %s, patch *%sPatch) (*%s, error) {", name, idType, name, name)
+	w.line("var out struct{ Record *%s `json:\"record\"` }", name)
+	w.line("if err := c.do(ctx, http.MethodPatch, %s, nil, patch, &out); err != nil { return nil, err }", recordPath)
+	w.line("return out.Record, nil")
+	w.line("}")
+	w.line("")
+
+	w.line("// Delete%s deletes a %q record.", name, moduleDef.ID)
+	w.line("func (c *Client) Delete%s(ctx context.Context, id %s) error {", name, idType)
+	w.line("return c.do(ctx, http.MethodDelete, %s, nil, nil, nil)", recordPath)
+	w.line("}")
+	w.line("")
+}
+
+// goClientRuntime je deo generisanog paketa koji ne zavisi od modula.
+const goClientRuntime = `// LookupRef is an expanded lookup value as returned by the API. Fields holds the other
+// columns of the lookup module in the object (lookup_fields and nested _expand), by column name.
+type LookupRef[K any] struct {
+	ID     K                          ` + "`json:\"id\"`" + `
+	Name   string                     ` + "`json:\"name\"`" + `
+	Fields map[string]json.RawMessage ` + "`json:\"-\"`" + `
+}
+
+func (r *LookupRef[K]) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if id, ok := raw["id"]; ok {
+		if err := json.Unmarshal(id, &r.ID); err != nil {
+			return err
+		}
+	}
+	// Prikazna vrednost može biti i broj (kada je prikazna kolona ID) ili null
+	var name interface{}
+	if err := json.Unmarshal(raw["name"], &name); err == nil && name != nil {
+		r.Name = fmt.Sprint(name)
+	}
+	delete(raw, "id")
+	delete(raw, "name")
+	if len(raw) > 0 {
+		r.Fields = raw
+	}
+	return nil
+}
+
+// MarshalJSON vraća objekat u obliku koji API vraća, sa poljima iz Fields.
+func (r LookupRef[K]) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(r.Fields)+2)
+	for k, v := range r.Fields {
+		object[k] = v
+	}
+	object["id"] = r.ID
+	object["name"] = r.Name
+	return json.Marshal(object)
+}
+
+// FieldError is a validation or constraint error of a single field.
+type FieldError struct {
+	Field   string                 ` + "`json:\"field\"`" + `
+	Rule    string                 ` + "`json:\"rule\"`" + `
+	Params  map[string]interface{} ` + "`json:\"params\"`" + `
+	Message string                 ` + "`json:\"message\"`" + `
+}
+
+// APIError is a non-2xx response of the API.
+type APIError struct {
+	StatusCode int                    ` + "`json:\"status\"`" + `
+	Code       string                 ` + "`json:\"code\"`" + `
+	Message    string                 ` + "`json:\"message\"`" + `
+	Detail     string                 ` + "`json:\"detail\"`" + `
+	RequestID  string                 ` + "`json:\"request_id\"`" + `
+	Fields     []FieldError           ` + "`json:\"fields\"`" + `
+	Current    map[string]interface{} ` + "`json:\"current\"`" + `
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client calls the REST API with typed requests and responses.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	UserID     string   // Šalje se kao X-User-ID
+	Roles      []string // Šalje se kao X-User-Roles
+}
+
+// NewClient creates a client for the API at baseURL (e.g. "http://localhost:8080").
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.UserID != "" {
+		req.Header.Set("X-User-ID", c.UserID)
+	}
+	if len(c.Roles) > 0 {
+		req.Header.Set("X-User-Roles", strings.Join(c.Roles, ","))
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		var envelope struct {
+			Error *APIError ` + "`json:\"error\"`" + `
+		}
+		if json.Unmarshal(data, &envelope) == nil && envelope.Error != nil {
+			envelope.Error.StatusCode = resp.StatusCode
+			return envelope.Error
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+`
@@ -0,0 +1,100 @@
+// codegen_test.go
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+// generatedStructs parsira generisan kod i vraća tipove polja po strukturi ("Order" -> "CustomerID" -> "*LookupRef[string]").
+func generatedStructs(t *testing.T, src []byte) map[string]map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "models_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("generisan kod se ne parsira: %v\n%s", err, src)
+	}
+	structs := make(map[string]map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return false
+		}
+		fields := make(map[string]string)
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				fields[name.Name] = types.ExprString(field.Type)
+			}
+		}
+		structs[spec.Name.Name] = fields
+		return false
+	})
+	return structs
+}
+
+func TestGenerateGoCode(t *testing.T) {
+	customers := &ModuleDefinition{ID: "module_customers", Name: "Kupci", Type: "table", DBTableName: "customers",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "code", Name: "Šifra", Type: "string", IsPrimaryKey: true, IsEditable: true},
+			{DBColumnName: "name", Name: "Naziv", Type: "string", IsEditable: true, Validation: "required"},
+		}}
+	items := &ModuleDefinition{ID: "module_order_items", Name: "Stavke", Type: "table", DBTableName: "order_items",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsReadOnly: true},
+			{DBColumnName: "order_id", Name: "Porudžbina", Type: "integer", IsEditable: true, Validation: "required"},
+		}}
+	orders := &ModuleDefinition{ID: "module_orders", Name: "Porudžbine", Type: "table", DBTableName: "orders",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsReadOnly: true},
+			{DBColumnName: "customer_id", Name: "Kupac", Type: "lookup", IsEditable: true, Validation: "required",
+				LookupModuleID: customers.ID, LookupModule: customers, LookupDisplayField: "name"},
+			{DBColumnName: "total", Name: "Iznos", Type: "float", IsEditable: true, Validation: "required,min:0"},
+			{DBColumnName: "note", Name: "Napomena", Type: "string", IsEditable: true},
+			{DBColumnName: "shipped_at", Name: "Poslato", Type: "datetime", IsEditable: true},
+			{DBColumnName: "created_by", Name: "Kreirao", Type: "string", Auto: AutoCreatedBy},
+		},
+		SubModules: []SubModuleDefinition{{ID: "items", DisplayName: "Stavke", TargetModuleID: items.ID, ChildForeignKeyField: "order_id"}}}
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{customers.ID: customers, items.ID: items, orders.ID: orders}, Rules: NewRuleRegistry()}
+	s := NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+
+	src, err := s.GenerateGoCode("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs := generatedStructs(t, src)
+
+	tests := []struct {
+		typeName, field, want string
+	}{
+		{"Order", "ID", "int64"},
+		{"Order", "CustomerID", "*LookupRef[string]"},
+		{"Order", "Total", "float64"},
+		{"Order", "Note", "*string"},
+		{"Order", "ShippedAt", "*time.Time"},
+		{"Order", "OrderItems", "[]OrderItem"},
+		{"Customer", "Code", "string"},
+		{"OrderInput", "CustomerID", "string"},
+		{"OrderInput", "Note", "*string"},
+		{"OrderPatch", "Total", "*float64"},
+		{"OrderPatch", "CustomerID", "*string"},
+	}
+	for _, tt := range tests {
+		if got, ok := structs[tt.typeName][tt.field]; !ok || got != tt.want {
+			t.Errorf("%s.%s: tip %q, očekivano %q", tt.typeName, tt.field, got, tt.want)
+		}
+	}
+	for _, absent := range []string{"ID", "CreatedBy"} {
+		if got, ok := structs["OrderInput"][absent]; ok {
+			t.Errorf("OrderInput ne sme da ima polje %s (%s)", absent, got)
+		}
+	}
+	if _, ok := structs["LookupRef"]; !ok {
+		t.Error("generisan kod nema tip LookupRef")
+	}
+}
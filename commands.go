@@ -0,0 +1,117 @@
+// commands.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// runCommand izvršava komandu iz komandne linije umesto pokretanja servera.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "openapi":
+		return runOpenAPICommand(args)
+	case "generate":
+		return runGenerateCommand(args)
+	default:
+		return fmt.Errorf("nepoznata komanda '%s' (dostupne: openapi, generate)", name)
+	}
+}
+
+// loadOfflineServer učitava konfiguraciju i module i pravi APIServer bez veze sa bazom,
+// za komande kojima je potrebna samo definicija aplikacije.
+func loadOfflineServer(configPath string) (*APIServer, error) {
+	config, err := LoadConfigFromFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri učitavanju konfiguracije: %w", err)
+	}
+	appConfig, err := NewAppConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri inicijalizaciji AppConfig: %w", err)
+	}
+	dataset := &SQLDataset{config: appConfig, Hooks: NewHookRegistry(), Broker: NewChangeBroker()}
+	return NewAPIServer(appConfig, dataset), nil
+}
+
+// runOpenAPICommand upisuje OpenAPI dokument u datoteku ("-" za standardni izlaz).
+func runOpenAPICommand(args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "putanja do konfiguracije")
+	output := fs.String("o", "openapi.json", "izlazna datoteka (\"-\" za standardni izlaz)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	server, err := loadOfflineServer(*configPath)
+	if err != nil {
+		return err
+	}
+	doc, err := server.BuildOpenAPI()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("greška pri enkodiranju OpenAPI dokumenta: %w", err)
+	}
+	data = append(data, '\n')
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("greška pri upisu '%s': %w", *output, err)
+	}
+	log.Printf("INFO: OpenAPI dokument upisan u '%s' (%d putanja).", *output, len(doc.Paths))
+	return nil
+}
+
+// runGenerateCommand upisuje Go strukture modula i tipizovan klijent u datoteku ("-" za standardni izlaz).
+func runGenerateCommand(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "putanja do konfiguracije")
+	output := fs.String("o", "client/models_gen.go", "izlazna datoteka (\"-\" za standardni izlaz)")
+	pkg := fs.String("package", "", "ime Go paketa (podrazumevano ime direktorijuma izlazne datoteke)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pkg == "" {
+		*pkg = "client"
+		if *output != "-" {
+			if dir := filepath.Base(filepath.Dir(*output)); dir != "." && dir != string(filepath.Separator) {
+				*pkg = dir
+			}
+		}
+	}
+	if !token.IsIdentifier(*pkg) {
+		return fmt.Errorf("nevažeće ime paketa '%s'", *pkg)
+	}
+
+	server, err := loadOfflineServer(*configPath)
+	if err != nil {
+		return err
+	}
+	src, err := server.GenerateGoCode(*pkg)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(*output), 0755); err != nil {
+		return fmt.Errorf("greška pri kreiranju direktorijuma za '%s': %w", *output, err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		return fmt.Errorf("greška pri upisu '%s': %w", *output, err)
+	}
+	log.Printf("INFO: Go kod (paket '%s') upisan u '%s'.", *pkg, *output)
+	return nil
+}
@@ -0,0 +1,108 @@
+// concurrency.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PreconditionFailedError is returned when the If-Match version of a write
+// does not match the current version of the record.
+type PreconditionFailedError struct {
+	ModuleID    string
+	RecordID    string
+	CurrentETag string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("zapis sa ID '%s' u modulu '%s' je u međuvremenu izmenjen (trenutna verzija %s)", e.RecordID, e.ModuleID, e.CurrentETag)
+}
+
+// HasVersion reports whether the module uses optimistic concurrency control.
+func (m *ModuleDefinition) HasVersion() bool {
+	return m.VersionColumn != ""
+}
+
+// formatETag pretvara vrednost verzione kolone u ETag (jaki validator, pod navodnicima).
+func formatETag(version interface{}) string {
+	if version == nil {
+		return ""
+	}
+	switch v := version.(type) {
+	case time.Time:
+		return fmt.Sprintf(`"%s"`, v.UTC().Format(time.RFC3339Nano))
+	default:
+		return fmt.Sprintf(`"%v"`, v)
+	}
+}
+
+// recordETag vraća ETag zapisa, ili prazan string ako modul nema verzionu kolonu.
+func recordETag(moduleDef *ModuleDefinition, record map[string]interface{}) string {
+	if !moduleDef.HasVersion() || record == nil {
+		return ""
+	}
+	return formatETag(record[moduleDef.VersionColumn])
+}
+
+// etagMatches proverava da li If-Match (ili If-None-Match) zaglavlje odgovara trenutnom ETag-u.
+// Podržava "*" i listu ETag-ova odvojenih zarezom. Sa weak=false poređenje je strogo (RFC 9110, If-Match): slab ETag (W/...) nikad ne odgovara;
+// slabo poređenje (weak=true) važi samo za If-None-Match.
+func etagMatches(header, current string, weak bool) bool {
+	if current == "" || (!weak && strings.HasPrefix(current, "W/")) {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(current, "W/") {
+				return true
+			}
+		} else if candidate == current {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVersion upoređuje If-Match sa verzijom zaključanog zapisa.
+// Prazan ifMatch znači da klijent ne traži proveru verzije.
+func checkVersion(moduleDef *ModuleDefinition, recordID string, current map[string]interface{}, ifMatch string) error {
+	if ifMatch == "" || !moduleDef.HasVersion() {
+		return nil
+	}
+	currentETag := recordETag(moduleDef, current)
+	if !etagMatches(ifMatch, currentETag, false) {
+		return &PreconditionFailedError{ModuleID: moduleDef.ID, RecordID: recordID, CurrentETag: currentETag}
+	}
+	return nil
+}
+
+// versionSetClause vraća SET izraz koji pomera verziju zapisa: celobrojna kolona
+// se uvećava, a vremenska (npr. updated_at) dobija trenutno vreme.
+func versionSetClause(moduleDef *ModuleDefinition) string {
+	if !moduleDef.HasVersion() {
+		return ""
+	}
+	if colDef := getColumnByDBName(moduleDef.Columns, moduleDef.VersionColumn); colDef != nil && colDef.Type == "integer" {
+		return fmt.Sprintf("%s = %s + 1", moduleDef.VersionColumn, moduleDef.VersionColumn)
+	}
+	return fmt.Sprintf("%s = now()", moduleDef.VersionColumn)
+}
+
+// versionCondition vraća WHERE uslov koji vezuje izmenu za pročitanu verziju zapisa
+// i dodaje vrednost verzije u argumente upita.
+func versionCondition(moduleDef *ModuleDefinition, current map[string]interface{}, args *[]interface{}) string {
+	if !moduleDef.HasVersion() || current == nil {
+		return ""
+	}
+	version := current[moduleDef.VersionColumn]
+	if version == nil {
+		return fmt.Sprintf(" AND %s IS NULL", moduleDef.VersionColumn)
+	}
+	*args = append(*args, version)
+	return fmt.Sprintf(" AND %s = $%d", moduleDef.VersionColumn, len(*args))
+}
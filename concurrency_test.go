@@ -0,0 +1,24 @@
+// concurrency_test.go
+package main
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	cases := []struct {
+		header, current string
+		weak, want      bool
+	}{
+		{`"3"`, `"3"`, false, true},
+		{`W/"3"`, `"3"`, false, false}, // If-Match: slab ETag ne sme da odgovara
+		{`W/"3"`, `"3"`, true, true},   // If-None-Match: slabo poređenje
+		{`"1", "3"`, `"3"`, false, true},
+		{`*`, `"3"`, false, true},
+		{`*`, ``, false, false},
+		{`"2"`, `"3"`, true, false},
+	}
+	for _, c := range cases {
+		if got := etagMatches(c.header, c.current, c.weak); got != c.want {
+			t.Errorf("etagMatches(%q, %q, %v) = %v, očekivano %v", c.header, c.current, c.weak, got, c.want)
+		}
+	}
+}
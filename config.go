@@ -19,8 +19,23 @@ type DatabaseConfig struct {
 
 // Config struct for overall application configuration.
 type Config struct {
-	Database    DatabaseConfig `json:"database"`
-	ModulesPath string         `json:"modules_path"`
+	Database    DatabaseConfig    `json:"database"`
+	ModulesPath string            `json:"modules_path"`
+	AuditTable  string            `json:"audit_table"` // Tabela za audit log, podrazumevano "audit_log"
+	Webhooks    WebhookConfig     `json:"webhooks"`
+	Idempotency IdempotencyConfig `json:"idempotency"`
+	I18n        I18nConfig        `json:"i18n"`
+}
+
+// WebhookConfig struct for outgoing webhook delivery settings.
+type WebhookConfig struct {
+	PollIntervalSeconds int      `json:"poll_interval_seconds"` // Koliko često worker proverava outbox
+	TimeoutSeconds      int      `json:"timeout_seconds"`       // Timeout jednog HTTP poziva
+	MaxAttempts         int      `json:"max_attempts"`          // Posle ovoliko neuspeha isporuka ide u dead letter
+	BackoffBaseSeconds  int      `json:"backoff_base_seconds"`  // Početno kašnjenje; udvostručuje se posle svakog neuspeha
+	BackoffMaxSeconds   int      `json:"backoff_max_seconds"`   // Gornja granica kašnjenja
+	AdminRoles          []string `json:"admin_roles"`           // Uloge koje smeju da upravljaju pretplatama; prazno = niko
+	AllowPrivateTargets bool     `json:"allow_private_targets"` // Dozvoljava isporuku na loopback i privatne adrese (samo za razvoj)
 }
 
 // LoadConfigFromFile reads configuration from a JSON file.
@@ -35,5 +50,12 @@ func LoadConfigFromFile(filePath string) (*Config, error) {
 		return nil, fmt.Errorf("greška pri parsiranju konfiguracionog fajla '%s': %w", filePath, err)
 	}
 
+	if config.AuditTable == "" {
+		config.AuditTable = defaultAuditTable
+	}
+	config.Webhooks.applyDefaults()
+	config.Idempotency.applyDefaults()
+	config.I18n.applyDefaults()
+
 	return &config, nil
 }
@@ -2,20 +2,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL drajver
 )
 
 // SQLDataset handles database operations.
 type SQLDataset struct {
-	db     *sql.DB
-	config *AppConfig // Dodato za pristup AppConfig i GetModuleByID
+	db        *sql.DB
+	connStr   string
+	config    *AppConfig    // Dodato za pristup AppConfig i GetModuleByID
+	Hooks     *HookRegistry // Serverski event handler-i po modulima
+	Broker    *ChangeBroker // Pretplatnici na izmene (SSE)
+	listening atomic.Bool   // Da li je aktivan LISTEN na kanalu izmena
 }
 
 // NewSQLDataset creates a new SQLDataset instance.
@@ -39,7 +47,38 @@ func NewSQLDataset(config *AppConfig) (*SQLDataset, error) {
 	}
 
 	log.Println("INFO: Uspešno povezano sa bazom podataka.")
-	return &SQLDataset{db: db, config: config}, nil
+
+	dataset := &SQLDataset{db: db, connStr: connStr, config: config, Hooks: NewHookRegistry(), Broker: NewChangeBroker()}
+	if err := dataset.ensureAuditTable(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := dataset.ensureWebhookTables(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := dataset.ensureIdempotencyTable(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// queryer je zajednički interfejs za *sql.DB i *sql.Tx, da bi pomoćne funkcije
+// radile i van i unutar transakcije.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn vraća transakciju iz konteksta (npr. zajednička transakcija batch zahteva),
+// a van transakcije konekcioni pool, da bi čitanja videla nepotvrđene izmene iste transakcije.
+func (s *SQLDataset) conn(ctx context.Context) queryer {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx
+	}
+	return s.db
 }
 
 // Close closes the database connection.
@@ -51,19 +90,61 @@ func (s *SQLDataset) Close() {
 }
 
 // GetRecords fetches records for a given module, applying filters, sorting, and pagination.
-func (s *SQLDataset) GetRecords(moduleDef *ModuleDefinition, queryParams url.Values) ([]map[string]interface{}, error) {
-	if moduleDef.DBTableName == "" && moduleDef.SelectQuery == "" {
-		return nil, fmt.Errorf("modul '%s' nema definisanu tabelu ili select query", moduleDef.ID)
+func (s *SQLDataset) GetRecords(ctx context.Context, moduleDef *ModuleDefinition, queryParams url.Values) ([]map[string]interface{}, error) {
+	finalQuery, args, err := s.buildSelectQuery(ctx, moduleDef, queryParams)
+	if err != nil {
+		return nil, err
 	}
 
-	var baseQuery string
-	if moduleDef.SelectQuery != "" {
-		baseQuery = moduleDef.SelectQuery
-	} else {
-		// Konstruiši SELECT * FROM table_name ako SelectQuery nije definisan
-		baseQuery = fmt.Sprintf("SELECT * FROM %s", moduleDef.DBTableName)
+	rows, err := s.conn(ctx).QueryContext(ctx, finalQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri izvršavanju SELECT upita za modul '%s': %w", moduleDef.ID, err)
+	}
+	defer rows.Close()
+
+	records, err := scanRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Proširenje lookup i submodule polja
+	if err := s.performLookupExpansion(ctx, records, moduleDef); err != nil {
+		log.Printf("WARNING: Greška pri proširenju lookup-a za modul '%s': %v", moduleDef.ID, err)
+		// Opcionalno: vrati grešku ili samo nastavi bez proširenja
 	}
 
+	if len(moduleDef.SubModules) > 0 {
+		if pkCol := s.getPrimaryKeyColumn(moduleDef); pkCol != nil {
+			for _, record := range records {
+				if pkVal, ok := record[pkCol.DBColumnName]; ok {
+					if err := s.performSubmoduleExpansion(ctx, record, moduleDef, pkVal, UserFromContext(ctx)); err != nil {
+						log.Printf("WARNING: Greška pri proširenju submodula za modul '%s', PK '%v': %v", moduleDef.ID, pkVal, err)
+						// Opcionalno: vrati grešku ili samo nastavi
+					}
+				}
+			}
+		} else {
+			log.Printf("WARNING: Modul '%s' ima submodule ali nema definisan primarni ključ za proširenje.", moduleDef.ID)
+		}
+	}
+
+	if err := s.Hooks.runOnSelect(ctx, moduleDef, records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// buildSelectQuery gradi SELECT upit modula iz filter, sort, search i paginacionih parametara.
+func (s *SQLDataset) buildSelectQuery(ctx context.Context, moduleDef *ModuleDefinition, queryParams url.Values) (string, []interface{}, error) {
+	if moduleDef.DBTableName == "" && moduleDef.SelectQuery == "" {
+		return "", nil, fmt.Errorf("modul '%s' nema definisanu tabelu ili select query", moduleDef.ID)
+	}
+
+	// Putanje kroz lookup kolone (npr. customer_id.username) spajaju se JOIN-om;
+	// za module sa select_query struktura upita nije poznata, pa nisu podržane
+	joins := newQueryJoins(UserFromContext(ctx), moduleDef.SelectQuery == "")
+
 	// Liste za SQL WHERE klauzulu i argumente za prepared statement
 	whereClauses := []string{}
 	args := []interface{}{}
@@ -76,6 +157,11 @@ func (s *SQLDataset) GetRecords(moduleDef *ModuleDefinition, queryParams url.Val
 	// Sortiranje
 	orderByClauses := []string{}
 
+	// Soft delete: podrazumevano se prikazuju samo neobrisani zapisi
+	if cond := softDeleteCondition(moduleDef, deletedScopeFromQuery(queryParams)); cond != "" {
+		whereClauses = append(whereClauses, cond)
+	}
+
 	// Prođi kroz query parametre
 	for key, values := range queryParams {
 		if len(values) == 0 {
@@ -111,23 +197,59 @@ func (s *SQLDataset) GetRecords(moduleDef *ModuleDefinition, queryParams url.Val
 					columnName = strings.TrimPrefix(field, "-")
 				}
 				// Proveri da li je kolona validna (da sprečimo SQL injection)
-				if colDef := getColumnByDBName(moduleDef.Columns, columnName); colDef != nil {
-					orderByClauses = append(orderByClauses, fmt.Sprintf("%s %s", colDef.DBColumnName, order))
+				if colDef, columnExpr := s.resolveColumn(moduleDef, joins, columnName); colDef != nil {
+					orderByClauses = append(orderByClauses, fmt.Sprintf("%s %s", columnExpr, order))
 				} else {
 					log.Printf("WARNING: Pokušaj sortiranja po nepostojećoj koloni: '%s'", columnName)
 				}
 			}
 		case "_search":
 			// Pozovi pomoćnu funkciju za pretragu
-			s.addSearchCondition(moduleDef, value, &whereClauses, &args, &argCounter)
+			s.addSearchCondition(moduleDef, joins, value, queryParams.Get("_search_fields"), &whereClauses, &args, &argCounter)
+		case "_search_fields":
+			// Obrađuje se uz _search
+		case "_with_deleted", "_only_deleted":
+			// Već obrađeno iznad (soft delete opseg)
+		case "_expand":
+			// Proširenje lookup-a se obrađuje pri čitanju (performLookupExpansion)
 		default:
 			// Standardno filtriranje po kolonama (npr. 'column=value' ili 'column__gt=value')
-			s.buildWhereClause(moduleDef, key, value, &whereClauses, &args, &argCounter)
+			s.buildWhereClause(moduleDef, joins, key, value, &whereClauses, &args, &argCounter)
 		}
 	}
 
+	if err := joins.deniedError(); err != nil {
+		return "", nil, err
+	}
+
+	// Row-level filter (RowFilter handler-i) ograničava redove dostupne korisniku
+	rowFilter, rowFilterArgs, err := s.Hooks.rowFilterCondition(ctx, moduleDef, argCounter-1)
+	if err != nil {
+		return "", nil, fmt.Errorf("greška u row-level filteru modula '%s': %w", moduleDef.ID, err)
+	}
+	if rowFilter != "" {
+		whereClauses = append(whereClauses, rowFilter)
+		args = append(args, rowFilterArgs...)
+		argCounter += len(rowFilterArgs)
+	}
+
 	// Izgradnja finalnog SQL upita
-	finalQuery := baseQuery
+	var finalQuery string
+	switch {
+	case moduleDef.SelectQuery != "":
+		finalQuery = moduleDef.SelectQuery
+	case len(joins.joins) > 0:
+		joinSQL, joinArgs, err := s.joinClauses(ctx, joins, args)
+		if err != nil {
+			return "", nil, err
+		}
+		args = joinArgs
+		argCounter = len(args) + 1
+		finalQuery = fmt.Sprintf("SELECT %s.* FROM %s %s", moduleDef.DBTableName, moduleDef.DBTableName, joinSQL)
+	default:
+		// Konstruiši SELECT * FROM table_name ako SelectQuery nije definisan
+		finalQuery = fmt.Sprintf("SELECT * FROM %s", moduleDef.DBTableName)
+	}
 
 	if len(whereClauses) > 0 {
 		finalQuery += " WHERE " + strings.Join(whereClauses, " AND ")
@@ -148,75 +270,7 @@ func (s *SQLDataset) GetRecords(moduleDef *ModuleDefinition, queryParams url.Val
 
 	log.Printf("INFO: Izvršavanje SQL upita: %s sa parametrima: %v", finalQuery, args)
 
-	rows, err := s.db.Query(finalQuery, args...)
-	if err != nil {
-		return nil, fmt.Errorf("greška pri izvršavanju SELECT upita za modul '%s': %w", moduleDef.ID, err)
-	}
-	defer rows.Close()
-
-	records := make([]map[string]interface{}, 0)
-	columnNames, err := rows.Columns()
-	if err != nil {
-		return nil, fmt.Errorf("greška pri dohvatanju imena kolona: %w", err)
-	}
-
-	for rows.Next() {
-		columns := make([]interface{}, len(columnNames))
-		columnPointers := make([]interface{}, len(columnNames))
-		for i := range columns {
-			columnPointers[i] = &columns[i]
-		}
-
-		if err := rows.Scan(columnPointers...); err != nil {
-			return nil, fmt.Errorf("greška pri skeniranju reda: %w", err)
-		}
-
-		record := make(map[string]interface{})
-		for i, colName := range columnNames {
-			val := columns[i]
-			// dbColDef := getColumnByDBName(moduleDef.Columns, colName) // Možda ti treba za tip
-			if val == nil {
-				record[colName] = nil
-			} else {
-				// PostgreSQL vraća neke tipove kao []byte, konvertujemo ih u string ako je to očekivano
-				switch v := val.(type) {
-				case []byte:
-					record[colName] = string(v)
-				default:
-					record[colName] = v
-				}
-			}
-		}
-
-		records = append(records, record)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("greška nakon iteracije kroz redove: %w", err)
-	}
-
-	// Proširenje lookup i submodule polja
-	if err := s.performLookupExpansion(records, moduleDef); err != nil {
-		log.Printf("WARNING: Greška pri proširenju lookup-a za modul '%s': %v", moduleDef.ID, err)
-		// Opcionalno: vrati grešku ili samo nastavi bez proširenja
-	}
-
-	if len(moduleDef.SubModules) > 0 {
-		if pkCol := s.getPrimaryKeyColumn(moduleDef); pkCol != nil {
-			for _, record := range records {
-				if pkVal, ok := record[pkCol.DBColumnName]; ok {
-					if err := s.performSubmoduleExpansion(record, moduleDef, pkVal); err != nil {
-						log.Printf("WARNING: Greška pri proširenju submodula za modul '%s', PK '%v': %v", moduleDef.ID, pkVal, err)
-						// Opcionalno: vrati grešku ili samo nastavi
-					}
-				}
-			}
-		} else {
-			log.Printf("WARNING: Modul '%s' ima submodule ali nema definisan primarni ključ za proširenje.", moduleDef.ID)
-		}
-	}
-
-	return records, nil
+	return finalQuery, args, nil
 }
 
 // GetReportData executes a select_query for report or custom type modules.
@@ -284,6 +338,72 @@ func (s *SQLDataset) GetReportData(moduleDef *ModuleDefinition, queryParams url.
 	return results, nil
 }
 
+// normalizeDBValue konvertuje []byte (koje PostgreSQL vraća za neke tipove) u string.
+func normalizeDBValue(val interface{}) interface{} {
+	if b, ok := val.([]byte); ok {
+		return string(b)
+	}
+	return val
+}
+
+// scanRecords čita sve redove u mape indeksirane imenom kolone.
+func scanRecords(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("greška pri dohvatanju imena kolona: %w", err)
+	}
+
+	records := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		record, err := scanRow(rows, columnNames)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("greška nakon iteracije kroz redove: %w", err)
+	}
+	return records, nil
+}
+
+// scanRow čita trenutni red u mapu indeksiranu imenom kolone.
+func scanRow(rows *sql.Rows, columnNames []string) (map[string]interface{}, error) {
+	columnValues := make([]interface{}, len(columnNames))
+	columnPointers := make([]interface{}, len(columnNames))
+	for i := range columnValues {
+		columnPointers[i] = &columnValues[i]
+	}
+	if err := rows.Scan(columnPointers...); err != nil {
+		return nil, fmt.Errorf("greška pri skeniranju reda: %w", err)
+	}
+
+	record := make(map[string]interface{}, len(columnNames))
+	for i, colName := range columnNames {
+		record[colName] = normalizeDBValue(columnValues[i])
+	}
+	return record, nil
+}
+
+// queryRecord izvršava upit koji vraća najviše jedan red i vraća ga kao mapu.
+// Ako upit ne vrati nijedan red, vraća sql.ErrNoRows.
+func queryRecord(ctx context.Context, q queryer, query string, args ...interface{}) (map[string]interface{}, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records, err := scanRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return records[0], nil
+}
+
 // getColumnByDBName je pomoćna funkcija za pronalaženje definicije kolone po DBColumnName
 func getColumnByDBName(columns []ColumnDefinition, dbColumnName string) *ColumnDefinition {
 	for i := range columns {
@@ -294,8 +414,9 @@ func getColumnByDBName(columns []ColumnDefinition, dbColumnName string) *ColumnD
 	return nil
 }
 
-// buildWhereClause parsira filter parametre i dodaje ih u WHERE klauzulu.
-func (s *SQLDataset) buildWhereClause(moduleDef *ModuleDefinition, key, value string, whereClauses *[]string, args *[]interface{}, argCounter *int) {
+// buildWhereClause parsira filter parametre i dodaje ih u WHERE klauzulu. Kolona može biti
+// i putanja kroz lookup kolone (customer_id.username__ilike=ana) kada je joins zadat.
+func (s *SQLDataset) buildWhereClause(moduleDef *ModuleDefinition, joins *queryJoins, key, value string, whereClauses *[]string, args *[]interface{}, argCounter *int) {
 	parts := strings.Split(key, "__")
 	columnName := parts[0]
 	operator := ""
@@ -303,7 +424,7 @@ func (s *SQLDataset) buildWhereClause(moduleDef *ModuleDefinition, key, value st
 		operator = parts[1]
 	}
 
-	colDef := getColumnByDBName(moduleDef.Columns, columnName)
+	colDef, columnExpr := s.resolveColumn(moduleDef, joins, columnName)
 	if colDef == nil {
 		log.Printf("WARNING: Pokušaj filtriranja po nepostojećoj koloni: '%s'", columnName)
 		return
@@ -344,7 +465,7 @@ func (s *SQLDataset) buildWhereClause(moduleDef *ModuleDefinition, key, value st
 			*args = append(*args, convertedVal)
 			*argCounter++
 		}
-		*whereClauses = append(*whereClauses, fmt.Sprintf("%s %s (%s)", colDef.DBColumnName, sqlOperator, strings.Join(placeholders, ", ")))
+		*whereClauses = append(*whereClauses, fmt.Sprintf("%s %s (%s)", columnExpr, sqlOperator, strings.Join(placeholders, ", ")))
 		needsValueConversion = false // Vrednosti su već konvertovane
 	default:
 		// Ako operator nije eksplicitno naveden, pretpostavljamo '='
@@ -360,20 +481,36 @@ func (s *SQLDataset) buildWhereClause(moduleDef *ModuleDefinition, key, value st
 		if needsLikeEscape {
 			convertedVal = fmt.Sprintf("%%%s%%", convertedVal) // Dodaj % za LIKE/ILIKE pretragu po podstringu
 		}
-		*whereClauses = append(*whereClauses, fmt.Sprintf("%s %s $%d", colDef.DBColumnName, sqlOperator, *argCounter))
+		*whereClauses = append(*whereClauses, fmt.Sprintf("%s %s $%d", columnExpr, sqlOperator, *argCounter))
 		*args = append(*args, convertedVal)
 		*argCounter++
 	}
 }
 
-// addSearchCondition dodaje uslov pretrage za "_search" parametar.
-func (s *SQLDataset) addSearchCondition(moduleDef *ModuleDefinition, searchValue string, whereClauses *[]string, args *[]interface{}, argCounter *int) {
+// addSearchCondition dodaje uslov pretrage za "_search" parametar. Polja iz _search_fields
+// (odvojena zarezom, i putanje kao customer_id.username) zamenjuju podrazumevane kolone.
+func (s *SQLDataset) addSearchCondition(moduleDef *ModuleDefinition, joins *queryJoins, searchValue, searchFields string, whereClauses *[]string, args *[]interface{}, argCounter *int) {
 	searchableColumns := []string{}
-	for _, colDef := range moduleDef.Columns {
-		// Pretpostavljamo da su sve string kolone (koje su visible i editable) pretražive
-		// Možeš dodati i novo polje "IsSearchable" u ColumnDefinition ako želiš veću kontrolu
-		if colDef.Type == "string" && colDef.IsVisible { // i colDef.IsSearchable ako dodas
-			searchableColumns = append(searchableColumns, colDef.DBColumnName)
+	for _, field := range strings.Split(searchFields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		// Pretražive su samo vidljive string kolone, kao i bez _search_fields
+		colDef, columnExpr := s.resolveColumn(moduleDef, joins, field)
+		if colDef == nil || !isSearchableColumn(colDef) {
+			log.Printf("WARNING: Pokušaj pretrage po nepostojećoj ili nepretraživoj koloni: '%s'", field)
+			continue
+		}
+		searchableColumns = append(searchableColumns, columnExpr)
+	}
+	if searchFields == "" {
+		for _, colDef := range moduleDef.Columns {
+			// Pretpostavljamo da su sve string kolone (koje su visible i editable) pretražive
+			// Možeš dodati i novo polje "IsSearchable" u ColumnDefinition ako želiš veću kontrolu
+			if isSearchableColumn(&colDef) && colDef.UserCanRead(joins.reader()) {
+				searchableColumns = append(searchableColumns, colDef.DBColumnName)
+			}
 		}
 	}
 
@@ -392,6 +529,11 @@ func (s *SQLDataset) addSearchCondition(moduleDef *ModuleDefinition, searchValue
 	*argCounter++
 }
 
+// isSearchableColumn reports whether _search may match the column: only visible string columns.
+func isSearchableColumn(colDef *ColumnDefinition) bool {
+	return colDef.Type == "string" && colDef.IsVisible
+}
+
 // convertValueToColumnType pokušava da konvertuje string vrednost u odgovarajući tip kolone.
 func convertValueToColumnType(value string, colType string) (interface{}, error) {
 	switch colType {
@@ -422,17 +564,104 @@ func (s *SQLDataset) getPrimaryKeyColumn(moduleDef *ModuleDefinition) *ColumnDef
 
 // CreateRecord inserts a new record into the database.
 // Vraća (interface{}, error) jer vraća ID novog zapisa.
-func (s *SQLDataset) CreateRecord(moduleDef *ModuleDefinition, payload map[string]interface{}) (interface{}, error) {
+func (s *SQLDataset) CreateRecord(ctx context.Context, moduleDef *ModuleDefinition, payload map[string]interface{}) (interface{}, error) {
 	if moduleDef.Type != "table" {
 		return nil, fmt.Errorf("kreiranje zapisa nije podržano za modul tipa '%s'", moduleDef.Type)
 	}
 
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		// ISPRAVLJENO: Vraća (nil, error)
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ za povratak ID-a", moduleDef.Name)
+	}
+
+	var newID interface{}
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		created, err := s.createRecordTx(ctx, tx, moduleDef, payload)
+		if err != nil {
+			return err
+		}
+		newID = created[pkCol.DBColumnName]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newID, nil
+}
+
+// createRecordTx upisuje novi zapis u postojećoj transakciji (hook-ovi, INSERT, audit)
+// i vraća ceo novi red.
+func (s *SQLDataset) createRecordTx(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, payload map[string]interface{}) (map[string]interface{}, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ za povratak ID-a", moduleDef.Name)
+	}
+
+	// Handler-i mogu da izmene payload pre nego što se od njega napravi INSERT
+	if err := s.beforeCreate(ctx, moduleDef, payload); err != nil {
+		return nil, err
+	}
+
+	query, vals, err := buildInsertQuery(ctx, moduleDef, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("DEBUG: Executing INSERT query: %s with values: %v", query, vals)
+
+	created, err := queryRecord(ctx, tx, query, vals...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri izvršavanju INSERT upita za modul '%s': %w", moduleDef.Name, err)
+	}
+
+	if err := s.Hooks.runAfterCreate(ctx, moduleDef, created); err != nil {
+		return nil, err
+	}
+	if err := s.recordChange(ctx, tx, moduleDef, created[pkCol.DBColumnName], AuditCreate, nil, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// buildInsertQuery pravi INSERT upit od payload-a, default vrednosti i automatskih kolona.
+func buildInsertQuery(ctx context.Context, moduleDef *ModuleDefinition, payload map[string]interface{}) (string, []interface{}, error) {
+	cols, vals, err := insertValues(ctx, moduleDef, payload, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+
+	placeholders := make([]string, len(vals))
+	for i := range vals {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	// RETURNING * vraća ceo novi red (uključujući vrednosti koje je postavila baza) za audit log
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+		moduleDef.DBTableName,
+		strings.Join(cols, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	return query, vals, nil
+}
+
+// insertValues vraća kolone i vrednosti za INSERT: polja iz payload-a, default vrednosti
+// i automatske kolone.
+func insertValues(ctx context.Context, moduleDef *ModuleDefinition, payload map[string]interface{}, now time.Time) ([]string, []interface{}, error) {
 	cols := []string{}
 	vals := []interface{}{}
-	placeholders := []string{}
+	fieldCount := 0 // Broj polja iz payload-a ili default vrednosti (bez automatskih kolona)
 
-	i := 1
 	for _, colDef := range moduleDef.Columns {
+		// Automatske kolone popunjava server; vrednost iz payload-a se ignoriše
+		if colDef.IsAuto() {
+			if val, ok := autoColumnValue(ctx, &colDef, now, true); ok {
+				cols = append(cols, colDef.DBColumnName)
+				vals = append(vals, val)
+			}
+			continue
+		}
 		// Preskoči kolone koje nisu editable, primarne ključeve i read-only
 		if !colDef.IsEditable || colDef.IsPrimaryKey || colDef.IsReadOnly {
 			continue
@@ -440,108 +669,157 @@ func (s *SQLDataset) CreateRecord(moduleDef *ModuleDefinition, payload map[strin
 		if val, ok := payload[colDef.DBColumnName]; ok {
 			cols = append(cols, colDef.DBColumnName)
 			vals = append(vals, val)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-			i++
+			fieldCount++
 		} else if colDef.DefaultValue != nil {
 			cols = append(cols, colDef.DBColumnName)
 			vals = append(vals, colDef.DefaultValue)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-			i++
+			fieldCount++
 		}
 	}
 
-	if len(cols) == 0 {
-		// ISPRAVLJENO: Vraća (nil, error) da se poklopi sa definicijom funkcije
-		return nil, fmt.Errorf("nema validnih polja za kreiranje zapisa u modulu '%s'", moduleDef.Name)
+	if fieldCount == 0 {
+		return nil, nil, fmt.Errorf("nema validnih polja za kreiranje zapisa u modulu '%s'", moduleDef.Name)
+	}
+	return cols, vals, nil
+}
+
+// UpdateRecord updates an existing record in the database.
+// Vraća ažurirani red; ifMatch (ETag iz If-Match zaglavlja) je opcion.
+func (s *SQLDataset) UpdateRecord(ctx context.Context, moduleDef *ModuleDefinition, recordID string, payload map[string]interface{}, ifMatch string) (map[string]interface{}, error) {
+	if moduleDef.Type != "table" {
+		return nil, fmt.Errorf("ažuriranje zapisa nije podržano za modul tipa '%s'", moduleDef.Type)
 	}
 
 	pkCol := s.getPrimaryKeyColumn(moduleDef)
 	if pkCol == nil {
-		// ISPRAVLJENO: Vraća (nil, error)
-		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ za povratak ID-a", moduleDef.Name)
+		// ISPRAVLJENO: Vraća samo error
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ za ažuriranje", moduleDef.Name)
+	}
+
+	var after map[string]interface{}
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		after, err = s.updateRecordTx(ctx, tx, moduleDef, pkCol, recordID, payload, ifMatch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return after, nil
+}
+
+// updateRecordTx menja postojeći zapis u postojećoj transakciji (zaključavanje, provera
+// verzije, hook-ovi, UPDATE, audit) i vraća ažurirani red.
+func (s *SQLDataset) updateRecordTx(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, recordID string, payload map[string]interface{}, ifMatch string) (map[string]interface{}, error) {
+	// Zaključaj i pročitaj trenutno stanje zapisa da bi audit imao vrednosti pre izmene
+	before, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeActive)
+	if err == sql.ErrNoRows {
+		return nil, errRecordNotFound("zapis sa ID '%s' nije pronađen ili ažuriran u modulu '%s'", recordID, moduleDef.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(moduleDef, recordID, before, ifMatch); err != nil {
+		return nil, err
+	}
+	// DEFAULT vrednosti (PUT) se ne prosleđuju handler-ima; vraćaju se za kolone koje handler-i nisu postavili
+	defaults := takeSQLDefaults(payload)
+	if err := s.beforeUpdate(ctx, moduleDef, recordID, before, payload); err != nil {
+		return nil, err
+	}
+	for _, col := range defaults {
+		if _, set := payload[col]; !set {
+			payload[col] = sqlDefault{}
+		}
+	}
+
+	setClauses, args, err := buildUpdateSet(ctx, moduleDef, payload)
+	if err != nil {
+		return nil, err
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
+	// Dodaj recordID kao argument za WHERE klauzulu, pa verziju ako je modul ima
+	args = append(args, recordID)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d%s RETURNING *",
 		moduleDef.DBTableName,
-		strings.Join(cols, ", "),
-		strings.Join(placeholders, ", "),
-		pkCol.DBColumnName,
+		strings.Join(setClauses, ", "),
+		pkCol.DBColumnName, len(args),
+		versionCondition(moduleDef, before, &args),
 	)
 
-	log.Printf("DEBUG: Executing INSERT query: %s with values: %v", query, vals)
+	log.Printf("DEBUG: Executing UPDATE query: %s with values: %v", query, args)
 
-	var newID interface{}
-	err := s.db.QueryRow(query, vals...).Scan(&newID)
+	after, err := queryRecord(ctx, tx, query, args...)
+	if err == sql.ErrNoRows {
+		return nil, errRecordNotFound("zapis sa ID '%s' nije pronađen ili ažuriran u modulu '%s'", recordID, moduleDef.Name)
+	}
 	if err != nil {
-		// ISPRAVLJENO: Vraća (nil, error)
-		return nil, fmt.Errorf("greška pri izvršavanju INSERT upita za modul '%s': %w", moduleDef.Name, err)
+		return nil, fmt.Errorf("greška pri izvršavanju UPDATE upita za modul '%s', ID '%s': %w", moduleDef.Name, recordID, err)
 	}
 
-	return newID, nil
-}
-
-// UpdateRecord updates an existing record in the database.
-// Vraća samo error.
-func (s *SQLDataset) UpdateRecord(moduleDef *ModuleDefinition, recordID string, payload map[string]interface{}) error {
-	if moduleDef.Type != "table" {
-		return fmt.Errorf("ažuriranje zapisa nije podržano za modul tipa '%s'", moduleDef.Type)
+	if err := s.Hooks.runAfterUpdate(ctx, moduleDef, before, after); err != nil {
+		return nil, err
+	}
+	if err := s.recordChange(ctx, tx, moduleDef, recordID, AuditUpdate, before, after); err != nil {
+		return nil, err
 	}
+	return after, nil
+}
 
+// buildUpdateSet pravi SET izraze (sa placeholder-ima od $1) za polja iz payload-a,
+// automatske kolone i verziju.
+func buildUpdateSet(ctx context.Context, moduleDef *ModuleDefinition, payload map[string]interface{}) ([]string, []interface{}, error) {
 	setClauses := []string{}
+	autoClauses := []string{}
 	vals := []interface{}{}
-	i := 1
-
-	pkCol := s.getPrimaryKeyColumn(moduleDef)
-	if pkCol == nil {
-		// ISPRAVLJENO: Vraća samo error
-		return fmt.Errorf("modul '%s' nema definisan primarni ključ za ažuriranje", moduleDef.Name)
-	}
+	fieldCount := 0 // Broj polja iz payload-a (bez automatskih kolona i verzije)
 
+	now := time.Now()
+	i := 1
 	for _, colDef := range moduleDef.Columns {
+		// Verziju menja isključivo server
+		if colDef.DBColumnName == moduleDef.VersionColumn {
+			continue
+		}
+		if colDef.IsAuto() {
+			if val, ok := autoColumnValue(ctx, &colDef, now, false); ok {
+				autoClauses = append(autoClauses, fmt.Sprintf("%s = $%d", colDef.DBColumnName, i))
+				vals = append(vals, val)
+				i++
+			}
+			continue
+		}
 		if !colDef.IsEditable || colDef.IsPrimaryKey || colDef.IsReadOnly {
 			continue
 		}
 		if val, ok := payload[colDef.DBColumnName]; ok {
+			if _, isDefault := val.(sqlDefault); isDefault {
+				setClauses = append(setClauses, fmt.Sprintf("%s = DEFAULT", colDef.DBColumnName))
+				fieldCount++
+				continue
+			}
 			setClauses = append(setClauses, fmt.Sprintf("%s = $%d", colDef.DBColumnName, i))
 			vals = append(vals, val)
+			fieldCount++
 			i++
 		}
 	}
 
-	if len(setClauses) == 0 {
-		// ISPRAVLJENO: Vraća samo error
-		return fmt.Errorf("nema validnih polja za ažuriranje zapisa u modulu '%s'", moduleDef.Name)
+	if fieldCount == 0 {
+		return nil, nil, fmt.Errorf("nema validnih polja za ažuriranje zapisa u modulu '%s'", moduleDef.Name)
 	}
 
-	// Dodaj recordID kao poslednji argument za WHERE klauzulu
-	vals = append(vals, recordID)
-
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d",
-		moduleDef.DBTableName,
-		strings.Join(setClauses, ", "),
-		pkCol.DBColumnName, i, // i je sada indeks poslednjeg placeholder-a ($d)
-	)
-
-	log.Printf("DEBUG: Executing UPDATE query: %s with values: %v", query, vals)
-
-	res, err := s.db.Exec(query, vals...)
-	if err != nil {
-		// ISPRAVLJENO: Vraća samo error
-		return fmt.Errorf("greška pri izvršavanju UPDATE upita za modul '%s', ID '%s': %w", moduleDef.Name, recordID, err)
-	}
-
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
-		// ISPRAVLJENO: Vraća samo error
-		return fmt.Errorf("zapis sa ID '%s' nije pronađen ili ažuriran u modulu '%s'", recordID, moduleDef.Name)
+	setClauses = append(setClauses, autoClauses...)
+	if clause := versionSetClause(moduleDef); clause != "" {
+		setClauses = append(setClauses, clause)
 	}
-
-	return nil
+	return setClauses, vals, nil
 }
 
 // DeleteRecord deletes a record from the database.
-// Vraća samo error.
-func (s *SQLDataset) DeleteRecord(moduleDef *ModuleDefinition, recordID string) error {
+// ifMatch (ETag iz If-Match zaglavlja) je opcion.
+func (s *SQLDataset) DeleteRecord(ctx context.Context, moduleDef *ModuleDefinition, recordID string, ifMatch string) error {
 	if moduleDef.Type != "table" {
 		return fmt.Errorf("brisanje zapisa nije podržano za modul tipa '%s'", moduleDef.Type)
 	}
@@ -552,31 +830,132 @@ func (s *SQLDataset) DeleteRecord(moduleDef *ModuleDefinition, recordID string)
 		return fmt.Errorf("modul '%s' nema definisan primarni ključ za brisanje", moduleDef.Name)
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1",
+	return s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return s.deleteRecordTx(ctx, tx, moduleDef, pkCol, recordID, ifMatch)
+	})
+}
+
+// deleteRecordTx briše zapis u postojećoj transakciji (zaključavanje, provera verzije,
+// hook-ovi, DELETE ili soft delete, audit).
+func (s *SQLDataset) deleteRecordTx(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, recordID string, ifMatch string) error {
+	if moduleDef.HasSoftDelete() {
+		return s.softDeleteRecordTx(ctx, tx, moduleDef, pkCol, recordID, ifMatch)
+	}
+
+	current, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeActive)
+	if err == sql.ErrNoRows {
+		return errRecordNotFound("zapis sa ID '%s' nije pronađen ili obrisan u modulu '%s'", recordID, moduleDef.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(moduleDef, recordID, current, ifMatch); err != nil {
+		return err
+	}
+	if err := s.Hooks.runBeforeDelete(ctx, moduleDef, current); err != nil {
+		return err
+	}
+
+	args := []interface{}{recordID}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1%s RETURNING *",
 		moduleDef.DBTableName,
 		pkCol.DBColumnName,
+		versionCondition(moduleDef, current, &args),
 	)
 
-	log.Printf("DEBUG: Executing DELETE query: %s with ID: %s", query, recordID)
+	log.Printf("DEBUG: Executing DELETE query: %s with values: %v", query, args)
 
-	res, err := s.db.Exec(query, recordID)
+	deleted, err := queryRecord(ctx, tx, query, args...)
+	if err == sql.ErrNoRows {
+		return errRecordNotFound("zapis sa ID '%s' nije pronađen ili obrisan u modulu '%s'", recordID, moduleDef.Name)
+	}
 	if err != nil {
-		// ISPRAVLJENO: Vraća samo error
 		return fmt.Errorf("greška pri izvršavanju DELETE upita za modul '%s', ID '%s': %w", moduleDef.Name, recordID, err)
 	}
 
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
-		// ISPRAVLJENO: Vraća samo error
-		return fmt.Errorf("zapis sa ID '%s' nije pronađen ili obrisan u modulu '%s'", recordID, moduleDef.Name)
+	if err := s.Hooks.runAfterDelete(ctx, moduleDef, deleted); err != nil {
+		return err
 	}
+	return s.recordChange(ctx, tx, moduleDef, recordID, AuditDelete, deleted, nil)
+}
 
+// recordChange beleži potvrđenu izmenu zapisa u okviru iste transakcije:
+// audit log, obaveštenje za SSE i webhook outbox. Ako bilo šta ne uspe, poništava se i sama izmena.
+func (s *SQLDataset) recordChange(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, recordID interface{}, operation string, before, after map[string]interface{}) error {
+	entry, err := s.writeAudit(ctx, tx, moduleDef, recordID, operation, before, after)
+	if err != nil {
+		return err
+	}
+	if err := s.publishChange(ctx, tx, entry); err != nil {
+		return err
+	}
+	return s.enqueueWebhooks(ctx, tx, moduleDef, recordID, operation, before, after)
+}
+
+// withTx izvršava fn u transakciji; transakcija se potvrđuje samo ako fn ne vrati grešku.
+// Kontekst prosleđen fn-u nosi transakciju (vidi TxFromContext) za serverske handler-e.
+// Ako kontekst već nosi transakciju (npr. /api/batch), fn se izvršava u njoj, a potvrda je na spoljnom pozivaocu.
+func (s *SQLDataset) withTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if tx := TxFromContext(ctx); tx != nil {
+		return fn(ctx, tx)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("greška pri započinjanju transakcije: %w", err)
+	}
+	defer tx.Rollback() // Nema efekta nakon uspešnog Commit-a
+
+	ctx, callbacks := withCommitCallbacks(withTxContext(ctx, tx))
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("greška pri potvrđivanju transakcije: %w", err)
+	}
+	callbacks.run()
+	return nil
+}
+
+// withSavepoint izvršava fn unutar SAVEPOINT-a postojeće transakcije. Ako fn vrati grešku,
+// poništavaju se samo njene izmene (i odložene afterCommit funkcije), a transakcija ostaje upotrebljiva.
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT row_change"); err != nil {
+		return fmt.Errorf("greška pri kreiranju savepoint-a: %w", err)
+	}
+	mark := commitCallbacksMark(ctx)
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT row_change"); rbErr != nil {
+			return fmt.Errorf("greška pri poništavanju savepoint-a: %v (izvorna greška: %w)", rbErr, err)
+		}
+		discardCommitCallbacks(ctx, mark)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT row_change"); err != nil {
+		return fmt.Errorf("greška pri oslobađanju savepoint-a: %w", err)
+	}
 	return nil
 }
 
+// lockRecord čita postojeći zapis i zaključava ga do kraja transakcije (SELECT ... FOR UPDATE).
+// Vraća sql.ErrNoRows ako zapis ne postoji (ili nije u traženom soft delete opsegu).
+func (s *SQLDataset) lockRecord(ctx context.Context, q queryer, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, recordID interface{}, scope DeletedScope) (map[string]interface{}, error) {
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(fmt.Sprintf("%s = $1", pkCol.DBColumnName), moduleDef, scope), moduleDef, []interface{}{recordID})
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s FOR UPDATE", moduleDef.DBTableName, where)
+	record, err := queryRecord(ctx, q, query, args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("greška pri čitanju zapisa sa ID '%v' u modulu '%s': %w", recordID, moduleDef.Name, err)
+	}
+	return record, err
+}
+
 // GetRecordByID fetches a single record by its ID.
 // This is used by GetSingleRecord in app.go
-func (s *SQLDataset) GetRecordByID(moduleDef *ModuleDefinition, id interface{}) (map[string]interface{}, error) {
+// Zapis koji ne postoji, nije u traženom soft delete opsegu ili je van row-level filtera
+// vraća RecordNotFoundError.
+func (s *SQLDataset) GetRecordByID(ctx context.Context, moduleDef *ModuleDefinition, id interface{}, scope DeletedScope) (map[string]interface{}, error) {
 	pkCol := s.getPrimaryKeyColumn(moduleDef)
 	if pkCol == nil {
 		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
@@ -586,6 +965,10 @@ func (s *SQLDataset) GetRecordByID(moduleDef *ModuleDefinition, id interface{})
 	if len(columns) == 0 {
 		return nil, fmt.Errorf("modul '%s' nema definisanih vidljivih kolona za dohvatanje zapisa po ID-u", moduleDef.Name)
 	}
+	// Verzija je potrebna za ETag, čak i ako kolona nije vidljiva
+	if moduleDef.HasVersion() && !slices.Contains(columns, moduleDef.VersionColumn) {
+		columns = append(columns, moduleDef.VersionColumn)
+	}
 
 	// Kreiramo SELECT klauzulu sa aliasingom za svaku kolonu
 	selectColumns := make([]string, len(columns))
@@ -593,15 +976,19 @@ func (s *SQLDataset) GetRecordByID(moduleDef *ModuleDefinition, id interface{})
 		selectColumns[i] = fmt.Sprintf("%s AS %s", colName, colName)
 	}
 
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1",
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(fmt.Sprintf("%s = $1", pkCol.DBColumnName), moduleDef, scope), moduleDef, []interface{}{id})
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
 		strings.Join(selectColumns, ", "),
 		moduleDef.DBTableName,
-		pkCol.DBColumnName,
+		where,
 	)
 
 	log.Printf("DEBUG: Executing GetRecordByID query: %s with ID: %v", query, id)
 
-	row := s.db.QueryRow(query, id)
+	row := s.conn(ctx).QueryRowContext(ctx, query, args...)
 
 	record := make(map[string]interface{})
 
@@ -615,7 +1002,7 @@ func (s *SQLDataset) GetRecordByID(moduleDef *ModuleDefinition, id interface{})
 
 	if err := row.Scan(columnPointers...); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("zapis sa ID '%v' nije pronađen u modulu '%s'", id, moduleDef.Name)
+			return nil, errRecordNotFound("zapis sa ID '%v' nije pronađen u modulu '%s'", id, moduleDef.Name)
 		}
 		return nil, fmt.Errorf("greška pri skeniranju pojedinačnog reda: %w", err)
 	}
@@ -636,18 +1023,22 @@ func (s *SQLDataset) GetRecordByID(moduleDef *ModuleDefinition, id interface{})
 	}
 
 	// Perform lookup expansion for this single record
-	if err := s.performLookupExpansion([]map[string]interface{}{record}, moduleDef); err != nil {
+	if err := s.performLookupExpansion(ctx, []map[string]interface{}{record}, moduleDef); err != nil {
 		log.Printf("WARNING: Greška pri proširenju lookup-a za pojedinačni zapis u modulu '%s': %v", moduleDef.ID, err)
 	}
 
 	// Perform submodule expansion
 	if len(moduleDef.SubModules) > 0 {
 		// PK je već poznat kao id
-		if err := s.performSubmoduleExpansion(record, moduleDef, id); err != nil {
+		if err := s.performSubmoduleExpansion(ctx, record, moduleDef, id, UserFromContext(ctx)); err != nil {
 			log.Printf("WARNING: Greška pri proširenju submodula za pojedinačni zapis '%v': %v", id, err)
 		}
 	}
 
+	if err := s.Hooks.runOnSelect(ctx, moduleDef, []map[string]interface{}{record}); err != nil {
+		return nil, err
+	}
+
 	return record, nil
 }
 
@@ -663,151 +1054,45 @@ func getVisibleDBColumnNames(cols []ColumnDefinition) []string {
 	return visibleCols
 }
 
-// performLookupExpansion is now internal and part of GetRecords/GetRecordByID flow
-func (s *SQLDataset) performLookupExpansion(records []map[string]interface{}, currentModule *ModuleDefinition) error {
-	for _, colDef := range currentModule.Columns {
-		// Proveri da li je kolona lookup tipa i da li ima definisan modul za lookup
-		if colDef.Type == "lookup" && colDef.LookupModule != nil && colDef.LookupModuleID != "" {
-			lookupModule := colDef.LookupModule
-			lookupPKCol := s.getPrimaryKeyColumn(lookupModule)
-			if lookupPKCol == nil {
-				log.Printf("WARNING: Lookup modul '%s' za kolonu '%s' nema definisan primarni ključ, preskačem proširenje", lookupModule.ID, colDef.Name)
-				continue
-			}
-
-			// Sakupi sve jedinstvene lookup ID-eve iz trenutnih zapisa
-			lookupIDs := make(map[interface{}]struct{})
-			for _, record := range records {
-				if id, ok := record[colDef.DBColumnName]; ok && id != nil {
-					lookupIDs[id] = struct{}{}
-				}
-			}
-
-			if len(lookupIDs) == 0 {
-				continue // Nema lookup ID-eva za obradu u ovoj koloni
-			}
-
-			// Pripremi WHERE klauzulu za batch dohvatanje lookup podataka
-			placeholders := make([]string, 0, len(lookupIDs))
-			args := make([]interface{}, 0, len(lookupIDs))
-			paramCounter := 1
-			for id := range lookupIDs {
-				placeholders = append(placeholders, fmt.Sprintf("$%d", paramCounter))
-				args = append(args, id)
-				paramCounter++
-			}
-
-			// Odaberi kolone za lookup. Koristi LookupDisplayField ako je definisan
-			lookupColsToSelect := []string{lookupPKCol.DBColumnName}
-			lookupDisplayCol := ""
-
-			if colDef.LookupDisplayField != "" { // Koristi LookupDisplayField
-				lookupDisplayCol = colDef.LookupDisplayField
-			} else {
-				// Fallback na prvu string kolonu (ili "name" ako postoji)
-				for _, lc := range lookupModule.Columns {
-					if lc.DBColumnName == "name" && lc.Type == "string" {
-						lookupDisplayCol = "name"
-						break
-					}
-				}
-				if lookupDisplayCol == "" {
-					for _, lc := range lookupModule.Columns {
-						if lc.DBColumnName != lookupPKCol.DBColumnName && lc.Type == "string" {
-							lookupDisplayCol = lc.DBColumnName
-							break
-						}
-					}
-				}
-				if lookupDisplayCol == "" {
-					lookupDisplayCol = lookupPKCol.DBColumnName // Fallback na ID ako nema string kolone
-				}
-			}
-
-			// Dodaj prikaznu kolonu u SELECT listu, ako već nije primarni ključ
-			if lookupDisplayCol != lookupPKCol.DBColumnName {
-				lookupColsToSelect = append(lookupColsToSelect, lookupDisplayCol)
-			}
-
-			lookupQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s)",
-				strings.Join(lookupColsToSelect, ", "),
-				lookupModule.DBTableName,
-				lookupPKCol.DBColumnName,
-				strings.Join(placeholders, ", "),
-			)
-
-			lookupRows, err := s.db.Query(lookupQuery, args...)
-			if err != nil {
-				return fmt.Errorf("greška pri dohvatanju lookup podataka za kolonu '%s': %w", colDef.Name, err)
-			}
-			defer lookupRows.Close()
-
-			// Mapiraj lookup ID-eve na dohvataene objekte
-			lookupMap := make(map[interface{}]map[string]interface{})
-			for lookupRows.Next() {
-				lookupCols, err := lookupRows.Columns()
-				if err != nil {
-					return fmt.Errorf("greška pri čitanju naziva kolona lookup-a: %w", err)
-				}
-				values := make([]interface{}, len(lookupCols))
-				pointers := make([]interface{}, len(lookupCols))
-				for i := range values {
-					pointers[i] = &values[i]
-				}
-
-				err = lookupRows.Scan(pointers...)
-				if err != nil {
-					return fmt.Errorf("greška pri skeniranju lookup reda: %w", err)
-				}
-
-				lookupRecord := make(map[string]interface{})
-				for i, colName := range lookupCols {
-					val := values[i]
-					if b, ok := val.([]byte); ok {
-						val = string(b)
-					}
-					lookupRecord[colName] = val
-				}
-				if id, ok := lookupRecord[lookupPKCol.DBColumnName]; ok {
-					lookupMap[id] = lookupRecord
-				}
-			}
-			if err = lookupRows.Err(); err != nil {
-				return fmt.Errorf("greška nakon iteracije lookup redova: %w", err)
-			}
+// performLookupExpansion is now internal and part of GetRecords/GetRecordByID flow.
+// Lookup kolone se proširuju u {"id": ..., "name": ...} (sa poljima iz LookupFields), a
+// lookup-i lookup modula po _expand putanjama iz konteksta (vidi withLookupExpand).
+func (s *SQLDataset) performLookupExpansion(ctx context.Context, records []map[string]interface{}, currentModule *ModuleDefinition) error {
+	return s.expandLookups(ctx, records, currentModule, lookupExpandFromContext(ctx, currentModule), true)
+}
 
-			// Ažuriraj originalne zapise sa proširenim lookup podacima
-			for idx := range records {
-				record := records[idx]
-				if id, ok := record[colDef.DBColumnName]; ok && id != nil {
-					if expandedVal, found := lookupMap[id]; found {
-						lookupObject := map[string]interface{}{
-							"id": id,
-						}
-						if val, ok := expandedVal[lookupDisplayCol]; ok {
-							lookupObject["name"] = val
-						} else {
-							lookupObject["name"] = fmt.Sprintf("ID: %v", id)
-						}
-						records[idx][colDef.DBColumnName] = lookupObject
-					} else {
-						records[idx][colDef.DBColumnName] = nil
-					}
-				}
-			}
+// lookupDisplayColumn vraća kolonu lookup modula koja se prikazuje umesto ID-a:
+// LookupDisplayField, pa "name", pa prva string kolona, pa primarni ključ.
+func lookupDisplayColumn(colDef *ColumnDefinition, lookupPKCol *ColumnDefinition) string {
+	if colDef.LookupDisplayField != "" {
+		return colDef.LookupDisplayField
+	}
+	for _, lc := range colDef.LookupModule.Columns {
+		if lc.DBColumnName == "name" && lc.Type == "string" {
+			return "name"
 		}
 	}
-	return nil
+	for _, lc := range colDef.LookupModule.Columns {
+		if lc.DBColumnName != lookupPKCol.DBColumnName && lc.Type == "string" {
+			return lc.DBColumnName
+		}
+	}
+	return lookupPKCol.DBColumnName // Fallback na ID ako nema string kolone
 }
 
 // performSubmoduleExpansion fetches and attaches submodule data to a parent record.
-func (s *SQLDataset) performSubmoduleExpansion(parentRecord map[string]interface{}, parentModuleDef *ModuleDefinition, parentPKVal interface{}) error {
+// Submoduli koje korisnik ne sme da čita se izostavljaju, a iz redova se uklanjaju
+// kolone koje ne sme da vidi, na svakom nivou.
+func (s *SQLDataset) performSubmoduleExpansion(ctx context.Context, parentRecord map[string]interface{}, parentModuleDef *ModuleDefinition, parentPKVal interface{}, user *User) error {
 	for _, subModDef := range parentModuleDef.SubModules {
 		targetModule := s.config.GetModuleByID(subModDef.TargetModuleID)
 		if targetModule == nil {
 			log.Printf("WARNING: Target modul '%s' za submodul '%s' nije pronađen.", subModDef.TargetModuleID, subModDef.DisplayName)
 			continue
 		}
+		if !targetModule.UserCan(user, PermRead) {
+			continue
+		}
 
 		columns := getVisibleDBColumnNames(targetModule.Columns) // Koristi pomoćnu funkciju i ovde
 		if len(columns) == 0 {
@@ -821,72 +1106,46 @@ func (s *SQLDataset) performSubmoduleExpansion(parentRecord map[string]interface
 			selectColumns[i] = fmt.Sprintf("%s AS %s", colName, colName)
 		}
 
-		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1",
+		where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(fmt.Sprintf("%s = $1", subModDef.ChildForeignKeyField), targetModule, ScopeActive), targetModule, []interface{}{parentPKVal})
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
 			strings.Join(selectColumns, ", "),
 			targetModule.DBTableName,
-			subModDef.ChildForeignKeyField,
+			where,
 		)
 
 		log.Printf("DEBUG: Executing submodule query for '%s': %s with parent PK: %v", subModDef.DisplayName, query, parentPKVal)
 
-		rows, err := s.db.Query(query, parentPKVal)
+		// Svi redovi se pročitaju pre proširenja, jer u transakciji (batch) veza ne može
+		// da izvršava novi upit dok su redovi prethodnog otvoreni
+		rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 		if err != nil {
 			return fmt.Errorf("greška pri dohvatanju podataka za submodul '%s': %w", subModDef.DisplayName, err)
 		}
-		defer rows.Close()
-
-		var subRecords []map[string]interface{}
-		for rows.Next() {
-			subRecord := make(map[string]interface{})
-
-			// Dohvati stvarne nazive kolona iz baze za submodul
-			dbColumnNames, err := rows.Columns()
-			if err != nil {
-				return fmt.Errorf("greška pri dohvatanju naziva kolona submodula iz baze: %w", err)
-			}
-
-			columnValues := make([]interface{}, len(dbColumnNames))
-			columnPointers := make([]interface{}, len(dbColumnNames))
-			for i := range dbColumnNames {
-				columnPointers[i] = &columnValues[i]
-			}
-
-			if err := rows.Scan(columnPointers...); err != nil {
-				return fmt.Errorf("greška pri skeniranju reda submodula '%s': %w", subModDef.DisplayName, err)
-			}
+		subRecords, err := scanRecords(rows)
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("greška pri čitanju redova submodula '%s': %w", subModDef.DisplayName, err)
+		}
 
-			for i, dbColName := range dbColumnNames {
-				val := columnValues[i]
-				if val == nil {
-					subRecord[dbColName] = nil
-				} else {
-					switch v := val.(type) {
-					case []byte:
-						subRecord[dbColName] = string(v)
-					default:
-						subRecord[dbColName] = v
-					}
-				}
-			}
-			if err := s.performLookupExpansion([]map[string]interface{}{subRecord}, targetModule); err != nil {
+		for _, subRecord := range subRecords {
+			if err := s.performLookupExpansion(ctx, []map[string]interface{}{subRecord}, targetModule); err != nil {
 				log.Printf("WARNING: Greška pri proširenju lookup-a u submodulu '%s': %v", subModDef.DisplayName, err)
 			}
 			if len(targetModule.SubModules) > 0 {
 				if subPKCol := s.getPrimaryKeyColumn(targetModule); subPKCol != nil {
 					if subPKVal, ok := subRecord[subPKCol.DBColumnName]; ok {
-						if err := s.performSubmoduleExpansion(subRecord, targetModule, subPKVal); err != nil {
+						if err := s.performSubmoduleExpansion(ctx, subRecord, targetModule, subPKVal, user); err != nil {
 							log.Printf("WARNING: Greška pri rekurzivnom proširenju submodula '%s' unutar '%s': %v", subModDef.DisplayName, parentModuleDef.ID, err)
 						}
 					}
 				}
 			}
-			subRecords = append(subRecords, subRecord)
-		}
-
-		if err = rows.Err(); err != nil {
-			return fmt.Errorf("greška nakon iteracije kroz redove submodula '%s': %w", subModDef.DisplayName, err)
 		}
 
+		hideUnreadableColumns(targetModule, user, subRecords...)
 		parentRecord[subModDef.TargetModuleID] = subRecords
 	}
 	return nil
@@ -0,0 +1,188 @@
+// dataset_test.go
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testDatabaseEnv je promenljiva okruženja sa DSN-om PostgreSQL baze za testove
+// (npr. "host=localhost dbname=demo_test sslmode=disable"); bez nje se testovi sa bazom preskaču.
+const testDatabaseEnv = "TEST_DATABASE_DSN"
+
+// newTestDataset povezuje se na test bazu, kreira sistemske tabele i registruje date module.
+func newTestDataset(t *testing.T, modules ...*ModuleDefinition) *SQLDataset {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s nije postavljen, preskačem test sa bazom", testDatabaseEnv)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Fatalf("greška pri povezivanju sa test bazom: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &AppConfig{Config: Config{AuditTable: defaultAuditTable}, Modules: make(map[string]*ModuleDefinition), Rules: NewRuleRegistry()}
+	cfg.Config.Webhooks.applyDefaults()
+	cfg.Config.Idempotency.applyDefaults()
+	for _, module := range modules {
+		cfg.Modules[module.ID] = module
+	}
+	s := &SQLDataset{db: db, connStr: dsn, config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()}
+	for _, ensure := range []func() error{s.ensureAuditTable, s.ensureWebhookTables, s.ensureIdempotencyTable} {
+		if err := ensure(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// createTestTable (ponovo) kreira tabelu za test i briše je po završetku testa.
+func createTestTable(t *testing.T, s *SQLDataset, table, columns string) {
+	t.Helper()
+	if _, err := s.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s; CREATE TABLE %s (%s)", table, table, columns)); err != nil {
+		t.Fatalf("greška pri kreiranju test tabele '%s': %v", table, err)
+	}
+	t.Cleanup(func() { s.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)) })
+}
+
+// testEmployeesModule je modul sa platom koju vidi samo uloga "hr".
+func testEmployeesModule() *ModuleDefinition {
+	return &ModuleDefinition{ID: "test_employees", Name: "Zaposleni", Type: "table", DBTableName: "test_employees", VersionColumn: "version",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true, IsReadOnly: true},
+			{DBColumnName: "name", Name: "Ime", Type: "string", IsVisible: true, IsEditable: true},
+			{DBColumnName: "salary", Name: "Plata", Type: "integer", IsVisible: true, IsEditable: true, ReadRoles: []string{"hr"}},
+			{DBColumnName: "version", Name: "Verzija", Type: "integer", IsReadOnly: true},
+		}}
+}
+
+// serveTest izvršava zahtev kroz router servera u ime korisnika sa datim ulogama.
+func serveTest(s *APIServer, method, path, body string, roles string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(userIDHeader, "tester")
+	req.Header.Set(userRolesHeader, roles)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRecordResponsesHideUnreadableColumns(t *testing.T) {
+	module := testEmployeesModule()
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, name TEXT NOT NULL, salary INT, version INT NOT NULL DEFAULT 1")
+	s := NewAPIServer(ds.config, ds)
+
+	rec := serveTest(s, http.MethodPost, "/api/modules/test_employees", `{"name": "Ana", "salary": 1000}`, "staff", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("kreiranje: status %d, telo %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID     json.Number            `json:"id"`
+		Record map[string]interface{} `json:"record"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := created.Record["salary"]; ok || created.Record["name"] != "Ana" {
+		t.Errorf("odgovor na kreiranje: %v", created.Record)
+	}
+	if rec.Header().Get("ETag") != `"1"` {
+		t.Errorf("ETag = %q, očekivano \"1\"", rec.Header().Get("ETag"))
+	}
+	recordPath := "/api/modules/test_employees/" + created.ID.String()
+
+	rec = serveTest(s, http.MethodPatch, recordPath, `{"name": "Ana M."}`, "staff", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "salary") {
+		t.Errorf("ažuriranje: status %d, telo %s", rec.Code, rec.Body)
+	}
+
+	rec = serveTest(s, http.MethodPatch, recordPath, `{"name": "Ana"}`, "staff", map[string]string{"If-Match": `"1"`})
+	if rec.Code != http.StatusPreconditionFailed || !strings.Contains(rec.Body.String(), `"current"`) || strings.Contains(rec.Body.String(), "salary") {
+		t.Errorf("412: status %d, telo %s", rec.Code, rec.Body)
+	}
+
+	rec = serveTest(s, http.MethodGet, recordPath+"/history", "", "staff", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "salary") {
+		t.Errorf("istorija: status %d, telo %s", rec.Code, rec.Body)
+	}
+	rec = serveTest(s, http.MethodGet, recordPath+"/history", "", "hr", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "salary") {
+		t.Errorf("istorija za hr: status %d, telo %s", rec.Code, rec.Body)
+	}
+
+	for _, query := range []string{"salary=1000", "salary__gt=10", "_sort=-salary", "_search=1&_search_fields=salary"} {
+		rec = serveTest(s, http.MethodGet, "/api/modules/test_employees?"+query, "", "staff", nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("?%s: status %d, očekivano 400", query, rec.Code)
+		}
+	}
+	rec = serveTest(s, http.MethodGet, "/api/modules/test_employees?salary=1000", "", "hr", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"salary":1000`) {
+		t.Errorf("filter za hr: status %d, telo %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSubmodulesHideUnreadableData(t *testing.T) {
+	items := &ModuleDefinition{ID: "test_sub_items", Name: "Stavke", Type: "table", DBTableName: "test_sub_items",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "order_id", Name: "Porudžbina", Type: "integer", IsVisible: true},
+			{DBColumnName: "price", Name: "Cena", Type: "integer", IsVisible: true, ReadRoles: []string{"hr"}},
+		}}
+	notes := &ModuleDefinition{ID: "test_sub_notes", Name: "Beleške", Type: "table", DBTableName: "test_sub_notes",
+		Permissions: map[string][]string{PermRead: {"manager"}},
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "order_id", Name: "Porudžbina", Type: "integer", IsVisible: true},
+			{DBColumnName: "text", Name: "Tekst", Type: "string", IsVisible: true},
+		}}
+	orders := &ModuleDefinition{ID: "test_sub_orders", Name: "Porudžbine", Type: "table", DBTableName: "test_sub_orders",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "title", Name: "Naziv", Type: "string", IsVisible: true},
+		},
+		SubModules: []SubModuleDefinition{
+			{ID: "items", DisplayName: "Stavke", TargetModuleID: items.ID, ChildForeignKeyField: "order_id"},
+			{ID: "notes", DisplayName: "Beleške", TargetModuleID: notes.ID, ChildForeignKeyField: "order_id"},
+		}}
+	ds := newTestDataset(t, orders, items, notes)
+	createTestTable(t, ds, orders.DBTableName, "id SERIAL PRIMARY KEY, title TEXT")
+	createTestTable(t, ds, items.DBTableName, "id SERIAL PRIMARY KEY, order_id INT, price INT")
+	createTestTable(t, ds, notes.DBTableName, "id SERIAL PRIMARY KEY, order_id INT, text TEXT")
+	if _, err := ds.db.Exec(`INSERT INTO test_sub_orders (id, title) VALUES (1, 'prva');
+		INSERT INTO test_sub_items (order_id, price) VALUES (1, 4242);
+		INSERT INTO test_sub_notes (order_id, text) VALUES (1, 'tajna beleška')`); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAPIServer(ds.config, ds)
+
+	for _, path := range []string{"/api/modules/test_sub_orders/1", "/api/modules/test_sub_orders"} {
+		rec := serveTest(s, http.MethodGet, path, "", "staff", nil)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `"test_sub_items"`) || strings.Contains(body, "4242") || strings.Contains(body, "tajna beleška") {
+			t.Errorf("%s za staff: status %d, telo %s", path, rec.Code, body)
+		}
+		rec = serveTest(s, http.MethodGet, path, "", "hr,manager", nil)
+		body = rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, "4242") || !strings.Contains(body, "tajna beleška") {
+			t.Errorf("%s za hr,manager: status %d, telo %s", path, rec.Code, body)
+		}
+	}
+}
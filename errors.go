@@ -0,0 +1,282 @@
+// errors.go
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// requestIDHeader nosi ID zahteva; klijent ga može poslati, a inače ga server generiše.
+const requestIDHeader = "X-Request-ID"
+
+// Kodovi grešaka u JSON odgovorima (polje "code"), stabilni za klijente.
+const (
+	ErrCodeBadRequest           = "bad_request"
+	ErrCodeValidation           = "validation_failed"
+	ErrCodeForbidden            = "forbidden"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeConflict             = "conflict"
+	ErrCodeUniqueViolation      = "unique_violation"
+	ErrCodeForeignKeyViolation  = "foreign_key_violation"
+	ErrCodeRecordReferenced     = "record_referenced"
+	ErrCodeNotNullViolation     = "not_null_violation"
+	ErrCodeCheckViolation       = "check_violation"
+	ErrCodeInvalidValue         = "invalid_value"
+	ErrCodePreconditionFailed   = "precondition_failed"
+	ErrCodePayloadTooLarge      = "payload_too_large"
+	ErrCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrCodeUnprocessable        = "unprocessable_entity"
+	ErrCodeFailedDependency     = "failed_dependency"
+	ErrCodePatchTestFailed      = "patch_test_failed"
+	ErrCodeIdempotencyKeyReused = "idempotency_key_reused"
+	ErrCodeRequestInProgress    = "request_in_progress"
+	ErrCodeInternal             = "internal_error"
+)
+
+// RecordNotFoundError is returned when a write targets a record that does not exist,
+// is outside the requested soft delete scope or is hidden by the row filter.
+type RecordNotFoundError struct {
+	message string
+}
+
+func (e *RecordNotFoundError) Error() string {
+	return e.message
+}
+
+// errRecordNotFound pravi RecordNotFoundError sa porukom po formatu.
+func errRecordNotFound(format string, args ...interface{}) error {
+	return &RecordNotFoundError{message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorBody is the machine-readable description of a failed request.
+type ErrorBody struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Detail    string                 `json:"detail,omitempty"` // Detaljna poruka na izvornom jeziku kada je message preveden
+	Status    int                    `json:"status"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Fields    ValidationErrors       `json:"fields,omitempty"`  // Greške po poljima (validacija, ograničenja baze)
+	Current   map[string]interface{} `json:"current,omitempty"` // 412: trenutno stanje zapisa
+}
+
+// ErrorResponse is the JSON envelope of every error response: {"error": {...}}.
+type ErrorResponse struct {
+	Error *ErrorBody `json:"error"`
+}
+
+// errorCodeForStatus vraća podrazumevani kod greške za HTTP status.
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusPreconditionFailed:
+		return ErrCodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrCodeUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return ErrCodeUnprocessable
+	case http.StatusFailedDependency:
+		return ErrCodeFailedDependency
+	}
+	if status >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeBadRequest
+}
+
+// newErrorBody pravi opis greške; prazan code se određuje iz statusa.
+func newErrorBody(status int, code, message string) *ErrorBody {
+	if code == "" {
+		code = errorCodeForStatus(status)
+	}
+	return &ErrorBody{Code: code, Message: message, Status: status}
+}
+
+// writeError je zamena za http.Error koja vraća JSON envelope sa kodom određenim iz statusa.
+func writeError(w http.ResponseWriter, message string, status int) {
+	writeErrorBody(w, newErrorBody(status, "", message))
+}
+
+// writeErrorCode vraća JSON grešku sa eksplicitnim kodom.
+func writeErrorCode(w http.ResponseWriter, code, message string, status int) {
+	writeErrorBody(w, newErrorBody(status, code, message))
+}
+
+// internalErrorMessage je poruka koju klijent dobija za serversku grešku; detalji idu samo u log.
+const internalErrorMessage = "Interna serverska greška"
+
+// writeInternalError beleži grešku sa ID-em zahteva u log i vraća 500 sa generičkom porukom,
+// da tekst greške baze (upiti, imena tabela i ograničenja) ne bi stigao do klijenta.
+func writeInternalError(w http.ResponseWriter, message string, err error) {
+	log.Printf("ERROR: %s: %v (request_id %s)", message, err, w.Header().Get(requestIDHeader))
+	writeError(w, internalErrorMessage, http.StatusInternalServerError)
+}
+
+// writeErrorBody upisuje JSON grešku; ID zahteva i jezik se preuzimaju iz zaglavlja odgovora
+// (requestIDMiddleware, languageMiddleware).
+func writeErrorBody(w http.ResponseWriter, body *ErrorBody) {
+	body.RequestID = w.Header().Get(requestIDHeader)
+	localizeErrorBody(body, w.Header().Get("Content-Language"))
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(body.Status)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: body}); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora o grešci: %v", err)
+	}
+}
+
+// writeClientError vraća grešku za koju je kriv klijent: validaciju (400, sa svim poljima)
+// ili prekršeno ograničenje baze (409/422). Vraća false za ostale greške.
+func writeClientError(w http.ResponseWriter, err error) bool {
+	body := clientErrorBody(err)
+	if body == nil {
+		return false
+	}
+	writeErrorBody(w, body)
+	return true
+}
+
+// clientErrorBody prevodi grešku validacije, nepostojećeg zapisa ili baze u opis greške, ili vraća nil.
+func clientErrorBody(err error) *ErrorBody {
+	var notFound *RecordNotFoundError
+	if errors.As(err, &notFound) {
+		return newErrorBody(http.StatusNotFound, ErrCodeNotFound, notFound.Error())
+	}
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		messages := make([]string, len(validationErrs))
+		for i, e := range validationErrs {
+			messages[i] = e.Message
+		}
+		body := newErrorBody(http.StatusBadRequest, ErrCodeValidation, fmt.Sprintf("Greška validacije payload-a: %s", strings.Join(messages, "; ")))
+		body.Fields = validationErrs
+		return body
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		body := newErrorBody(http.StatusBadRequest, ErrCodeValidation, fmt.Sprintf("Greška validacije payload-a: %v", validationErr))
+		body.Fields = ValidationErrors{validationErr}
+		return body
+	}
+	return databaseErrorBody(err)
+}
+
+// pqKeyColumns izdvaja kolone iz pq detalja, npr. "Key (code, year)=(A1, 2024) already exists."
+var pqKeyColumns = regexp.MustCompile(`Key \(([^)]+)\)=`)
+
+// databaseErrorBody prevodi prekršena ograničenja PostgreSQL-a u 409/422 sa kolonom na koju se odnose.
+func databaseErrorBody(err error) *ErrorBody {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+	field := string(pqErr.Column)
+	if m := pqKeyColumns.FindStringSubmatch(pqErr.Detail); m != nil {
+		field = strings.ReplaceAll(m[1], " ", "")
+	}
+
+	var body *ErrorBody
+	var fieldErr *ValidationError
+	switch {
+	case pqErr.Code == "23505": // unique_violation
+		fieldErr = newValidationError(field, "unique", "validation.unique", nil)
+		body = newErrorBody(http.StatusConflict, ErrCodeUniqueViolation, fmt.Sprintf("Zapis sa istom vrednošću polja '%s' već postoji.", field))
+	case pqErr.Code == "23503" && strings.Contains(pqErr.Detail, "still referenced"):
+		// Brisanje (ili izmena ključa) zapisa na koji upućuju drugi zapisi
+		body = newErrorBody(http.StatusConflict, ErrCodeRecordReferenced, fmt.Sprintf("Zapis se koristi u tabeli '%s' i ne može se obrisati.", pqErr.Table))
+	case pqErr.Code == "23503": // foreign_key_violation
+		fieldErr = newValidationError(field, "exists", "validation.foreign_key", nil)
+		body = newErrorBody(http.StatusUnprocessableEntity, ErrCodeForeignKeyViolation, fmt.Sprintf("Vrednost polja '%s' upućuje na nepostojeći zapis.", field))
+	case pqErr.Code == "23502": // not_null_violation
+		fieldErr = newValidationError(field, "required", "validation.required", nil)
+		body = newErrorBody(http.StatusUnprocessableEntity, ErrCodeNotNullViolation, fmt.Sprintf("Polje '%s' je obavezno.", field))
+	case pqErr.Code == "23514": // check_violation
+		fieldErr = newValidationError(field, "check", "validation.check", map[string]interface{}{"constraint": pqErr.Constraint})
+		body = newErrorBody(http.StatusUnprocessableEntity, ErrCodeCheckViolation, fmt.Sprintf("Vrednost ne ispunjava ograničenje '%s'.", pqErr.Constraint))
+	case pqErr.Code.Class() == "22": // data_exception (nevažeći format, prekoračenje opsega...)
+		if field != "" {
+			fieldErr = &ValidationError{Field: field, Rule: "type", Message: pqErr.Message}
+		}
+		body = newErrorBody(http.StatusUnprocessableEntity, ErrCodeInvalidValue, fmt.Sprintf("Nevažeća vrednost: %s", pqErr.Message))
+	default:
+		return nil
+	}
+	if fieldErr != nil && fieldErr.Field != "" {
+		body.Fields = ValidationErrors{fieldErr}
+	}
+	return body
+}
+
+type requestIDContextKey struct{}
+
+// requestIDFromContext vraća ID zahteva iz konteksta ili prazan string.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// newRequestID generiše nasumičan ID zahteva.
+func newRequestID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID prihvata ID iz zaglavlja klijenta samo ako je kratak i bez kontrolnih znakova.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware dodeljuje ID svakom zahtevu (ili preuzima ID klijenta) i vraća ga u zaglavlju odgovora.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDContextKey{}, id)))
+	})
+}
+
+// notFoundHandler vraća JSON grešku za nepostojeće rute.
+func notFoundHandler(w http.ResponseWriter, req *http.Request) {
+	writeError(w, fmt.Sprintf("Ruta '%s' ne postoji.", req.URL.Path), http.StatusNotFound)
+}
+
+// methodNotAllowedHandler vraća JSON grešku za nepodržan metod na postojećoj ruti.
+func methodNotAllowedHandler(w http.ResponseWriter, req *http.Request) {
+	writeError(w, fmt.Sprintf("Metod %s nije dozvoljen za '%s'.", req.Method, req.URL.Path), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,48 @@
+// errors_test.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInternalErrorsHideDetails(t *testing.T) {
+	cause := errors.New(`pq: relation "secret_table" does not exist`)
+
+	rec := httptest.NewRecorder()
+	writeInternalError(rec, "Greška pri dohvatanju zapisa", cause)
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusInternalServerError || resp.Error.Code != ErrCodeInternal || strings.Contains(rec.Body.String(), "secret_table") {
+		t.Errorf("writeInternalError: status %d, telo %s", rec.Code, rec.Body)
+	}
+
+	result := newBulkResult(BulkModeBestEffort, 1)
+	result.fail(0, cause)
+	if item := result.Items[0]; item.Code != ErrCodeInternal || strings.Contains(item.Error, "secret_table") {
+		t.Errorf("bulk stavka: %+v", item)
+	}
+	if rowErrs := importRowErrors(2, cause); strings.Contains(rowErrs[0].Message, "secret_table") {
+		t.Errorf("greška reda uvoza: %+v", rowErrs[0])
+	}
+}
+
+func TestRecordNotFoundIsClientError(t *testing.T) {
+	err := errRecordNotFound("zapis sa ID '%s' nije pronađen ili obrisan u modulu '%s'", "7", "Zaposleni")
+	body := clientErrorBody(err)
+	if body == nil || body.Status != http.StatusNotFound || body.Message != err.Error() {
+		t.Errorf("clientErrorBody(%v) = %+v", err, body)
+	}
+
+	result := newBulkResult(BulkModeAtomic, 1)
+	result.fail(0, err)
+	if item := result.Items[0]; item.Code != ErrCodeNotFound || item.Error != err.Error() {
+		t.Errorf("bulk stavka: %+v", item)
+	}
+}
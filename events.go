@@ -0,0 +1,387 @@
+// events.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// changeNotifyChannel je Postgres kanal preko kog se šalju obaveštenja o izmenama.
+const changeNotifyChannel = "module_changes"
+
+// Koliko propuštenih događaja se najviše šalje pri nastavku (Last-Event-ID); kada ih ima
+// više, šalje se događaj "reset" i klijent ponovo učitava podatke.
+const sseReplayLimit = 1000
+
+// sseResetEvent javlja klijentu da propuštene izmene nisu poslate i da treba ponovo da učita podatke.
+const sseResetEvent = "reset"
+
+// ChangeEvent is a created/updated/deleted notification pushed to SSE clients.
+// ID je ID audit zapisa i služi klijentu za prepoznavanje ponovljenih događaja. SSE id
+// (Last-Event-ID) nije ID događaja nego kursor: transakcija od koje nastavak ponovo šalje
+// izmene. Događaji se zato pri nastavku mogu ponoviti, ali se ne gube.
+type ChangeEvent struct {
+	ID         int64                  `json:"id"`
+	Event      string                 `json:"event"`
+	ModuleID   string                 `json:"module_id"`
+	RecordID   string                 `json:"record_id"`
+	Fields     map[string]interface{} `json:"fields"` // Nove vrednosti izmenjenih kolona
+	UserID     string                 `json:"user_id,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	TxID       int64                  `json:"-"` // Transakcija izmene
+	Horizon    int64                  `json:"-"` // Sve transakcije pre ove su potvrđene pre izmene (vidi AuditEntry.Horizon)
+}
+
+// changeEventFromAudit pravi događaj od audit zapisa.
+func changeEventFromAudit(entry *AuditEntry) ChangeEvent {
+	event := ChangeEvent{
+		ID:         entry.ID,
+		Event:      webhookEvents[entry.Operation],
+		ModuleID:   entry.ModuleID,
+		RecordID:   entry.RecordID,
+		Fields:     make(map[string]interface{}, len(entry.Changes)),
+		OccurredAt: entry.ChangedAt,
+		TxID:       entry.TxID,
+		Horizon:    entry.Horizon,
+	}
+	if entry.UserID != nil {
+		event.UserID = *entry.UserID
+	}
+	for col, change := range entry.Changes {
+		event.Fields[col] = change.New
+	}
+	return event
+}
+
+// pgSnapshot je snimak transakcija (pg_current_snapshot): završene pre xmin, aktivne u xip,
+// i još nezapočete od xmax.
+type pgSnapshot struct {
+	xmin, xmax int64
+	xip        map[int64]bool
+}
+
+// parsePGSnapshot parsira tekstualni oblik snimka "xmin:xmax:xip1,xip2".
+func parsePGSnapshot(text string) (*pgSnapshot, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("nevažeći snimak transakcija '%s'", text)
+	}
+	snapshot := &pgSnapshot{xip: make(map[int64]bool)}
+	var err error
+	if snapshot.xmin, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("nevažeći snimak transakcija '%s': %w", text, err)
+	}
+	if snapshot.xmax, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("nevažeći snimak transakcija '%s': %w", text, err)
+	}
+	if parts[2] != "" {
+		for _, xid := range strings.Split(parts[2], ",") {
+			id, err := strconv.ParseInt(xid, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("nevažeći snimak transakcija '%s': %w", text, err)
+			}
+			snapshot.xip[id] = true
+		}
+	}
+	return snapshot, nil
+}
+
+// visible proverava da li su izmene transakcije bile vidljive u snimku (kao pg_visible_in_snapshot).
+func (p *pgSnapshot) visible(xid int64) bool {
+	return xid < p.xmin || (xid < p.xmax && !p.xip[xid])
+}
+
+// ChangeBroker fans out change events to in-process subscribers.
+type ChangeBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan ChangeEvent]struct{} // moduleID -> kanali
+}
+
+// NewChangeBroker creates an empty broker.
+func NewChangeBroker() *ChangeBroker {
+	return &ChangeBroker{subscribers: make(map[string]map[chan ChangeEvent]struct{})}
+}
+
+// Subscribe registers a subscriber for a module's events. Pozivalac mora da pozove
+// vraćenu funkciju kada završi; kanal se zatvara i kada pretplatnik ne stiže da čita.
+func (b *ChangeBroker) Subscribe(moduleID string) (<-chan ChangeEvent, func()) {
+	ch := make(chan ChangeEvent, 64)
+	b.mu.Lock()
+	if b.subscribers[moduleID] == nil {
+		b.subscribers[moduleID] = make(map[chan ChangeEvent]struct{})
+	}
+	b.subscribers[moduleID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() { b.remove(moduleID, ch) }
+}
+
+// remove uklanja i zatvara kanal pretplatnika (bezbedno za višestruki poziv).
+func (b *ChangeBroker) remove(moduleID string, ch chan ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[moduleID][ch]; ok {
+		delete(b.subscribers[moduleID], ch)
+		close(ch)
+	}
+}
+
+// Reset closes all subscriptions; klijenti se ponovo povezuju i nastavljaju od kursora.
+// Koristi se kada su obaveštenja mogla biti izgubljena (prekid veze listener-a).
+func (b *ChangeBroker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for moduleID, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(b.subscribers, moduleID)
+	}
+}
+
+// Publish delivers the event to all subscribers of its module. Spor pretplatnik
+// se odjavljuje (kanal se zatvara), a klijent nastavlja preko Last-Event-ID.
+func (b *ChangeBroker) Publish(event ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[event.ModuleID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("WARNING: SSE pretplatnik modula '%s' ne stiže da čita, odjavljujem ga.", event.ModuleID)
+			delete(b.subscribers[event.ModuleID], ch)
+			close(ch)
+		}
+	}
+}
+
+// publishChange objavljuje izmenu: preko NOTIFY ako je listener aktivan (da bi je
+// videle sve instance servera), a inače direktno u broker posle potvrde transakcije.
+func (s *SQLDataset) publishChange(ctx context.Context, tx *sql.Tx, entry *AuditEntry) error {
+	if s.listening.Load() {
+		payload := fmt.Sprintf(`{"id":%d,"module_id":%q,"horizon":%d}`, entry.ID, entry.ModuleID, entry.Horizon)
+		if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", changeNotifyChannel, payload); err != nil {
+			return fmt.Errorf("greška pri slanju NOTIFY obaveštenja: %w", err)
+		}
+		return nil
+	}
+	event := changeEventFromAudit(entry)
+	afterCommit(ctx, func() { s.Broker.Publish(event) })
+	return nil
+}
+
+// StartChangeListener subscribes to Postgres NOTIFY and feeds the broker until ctx is cancelled.
+// Ako LISTEN ne uspe, izmene se i dalje objavljuju direktno u broker (samo u ovoj instanci).
+func (s *SQLDataset) StartChangeListener(ctx context.Context) error {
+	listener := pq.NewListener(s.connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("WARNING: Postgres listener događaj %d: %v", ev, err)
+		}
+	})
+	if err := listener.Listen(changeNotifyChannel); err != nil {
+		listener.Close()
+		return fmt.Errorf("greška pri LISTEN na kanal '%s': %w", changeNotifyChannel, err)
+	}
+	s.listening.Store(true)
+	log.Printf("INFO: Osluškujem izmene na Postgres kanalu '%s'.", changeNotifyChannel)
+
+	go func() {
+		defer func() {
+			s.listening.Store(false)
+			listener.Close()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				if n == nil {
+					// Veza je ponovo uspostavljena i obaveštenja su možda izgubljena; klijenti se
+					// odjavljuju i nadoknađuju izmene preko Last-Event-ID
+					s.Broker.Reset()
+					continue
+				}
+				s.handleNotification(ctx, n.Extra)
+			case <-time.After(90 * time.Second):
+				go listener.Ping()
+			}
+		}
+	}()
+	return nil
+}
+
+// handleNotification učitava audit zapis iz NOTIFY obaveštenja i objavljuje ga u broker.
+func (s *SQLDataset) handleNotification(ctx context.Context, payload string) {
+	var note struct {
+		ID      int64 `json:"id"`
+		Horizon int64 `json:"horizon"`
+	}
+	if err := json.Unmarshal([]byte(payload), &note); err != nil {
+		log.Printf("WARNING: Nevažeće NOTIFY obaveštenje '%s': %v", payload, err)
+		return
+	}
+	entry, err := s.getAuditEntry(ctx, note.ID)
+	if err != nil {
+		log.Printf("WARNING: Greška pri učitavanju izmene '%d' iz NOTIFY obaveštenja: %v", note.ID, err)
+		return
+	}
+	entry.Horizon = note.Horizon
+	s.Broker.Publish(changeEventFromAudit(entry))
+}
+
+// changeVisible proverava da li zapis iz događaja prolazi row-level filter korisnika.
+// Greška pri proveri se beleži i događaj se ne šalje.
+func (s *APIServer) changeVisible(ctx context.Context, moduleDef *ModuleDefinition, event ChangeEvent) bool {
+	visible, err := s.dataset.recordVisible(ctx, moduleDef, event.RecordID)
+	if err != nil {
+		log.Printf("ERROR: Greška pri proveri vidljivosti SSE događaja %d za modul '%s': %v", event.ID, moduleDef.ID, err)
+		return false
+	}
+	return visible
+}
+
+// visibleChangeEvent ostavlja samo kolone koje korisnik sme da vidi (is_visible i primarni ključ, uz read_roles).
+func visibleChangeEvent(moduleDef *ModuleDefinition, user *User, event ChangeEvent) ChangeEvent {
+	fields := make(map[string]interface{}, len(event.Fields))
+	for col, val := range event.Fields {
+		if colDef := getColumnByDBName(moduleDef.Columns, col); colDef != nil && (colDef.IsVisible || colDef.IsPrimaryKey) && colDef.UserCanRead(user) {
+			fields[col] = val
+		}
+	}
+	event.Fields = fields
+	return event
+}
+
+// writeSSEEvent upisuje jedan događaj u SSE formatu, sa kursorom za nastavak kao SSE id.
+func writeSSEEvent(w http.ResponseWriter, event ChangeEvent, cursor int64) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", cursor, event.Event, data)
+	return err
+}
+
+// StreamModuleEvents handles Server-Sent Events subscriptions to a module's changes.
+func (s *APIServer) StreamModuleEvents(w http.ResponseWriter, req *http.Request) {
+	moduleID := mux.Vars(req)["moduleID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermRead) {
+		return
+	}
+	user := UserFromContext(req.Context())
+
+	rc := http.NewResponseController(w)
+	// Stream traje duže od WriteTimeout-a servera
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("WARNING: Nije moguće ukloniti write deadline za SSE: %v", err)
+	}
+
+	cursor := int64(0)
+	if v := req.Header.Get("Last-Event-ID"); v != "" {
+		cursor, _ = strconv.ParseInt(v, 10, 64)
+	} else if v := req.URL.Query().Get("last_event_id"); v != "" {
+		cursor, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	// Pretplata pre nadoknade, da se ne izgubi događaj nastao u međuvremenu
+	events, unsubscribe := s.dataset.Broker.Subscribe(moduleID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Transakcije čije su izmene poslate tokom nadoknade; njihovi događaji iz pretplate se preskaču
+	var replayed *pgSnapshot
+	if cursor > 0 {
+		missed, snapshot, err := s.dataset.GetAuditEntriesSince(req.Context(), moduleID, cursor, sseReplayLimit+1)
+		if err != nil {
+			log.Printf("ERROR: Greška pri nadoknadi SSE događaja za modul '%s': %v", moduleID, err)
+			missed = nil
+		}
+		if snapshot != nil {
+			replayed = snapshot
+			if len(missed) > sseReplayLimit {
+				// Previše propuštenih izmena: klijent ponovo učitava podatke i nastavlja od ovog trenutka
+				missed = nil
+				cursor = max(cursor, snapshot.xmin)
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: {\"module_id\":%q}\n\n", cursor, sseResetEvent, moduleID); err != nil {
+					return
+				}
+			}
+		}
+		// Redosled po ID-u nije redosled potvrde, pa kursor napreduje tek posle cele nadoknade
+		for i := range missed {
+			event := changeEventFromAudit(&missed[i])
+			if !s.changeVisible(req.Context(), moduleDef, event) {
+				continue
+			}
+			if err := writeSSEEvent(w, visibleChangeEvent(moduleDef, user, event), cursor); err != nil {
+				return
+			}
+		}
+		if len(missed) > 0 {
+			// Blok samo sa id poljem menja Last-Event-ID klijenta bez novog događaja
+			cursor = max(cursor, replayed.xmin)
+			if _, err := fmt.Fprintf(w, "id: %d\n\n", cursor); err != nil {
+				return
+			}
+		}
+	}
+	// Bez podrške za flush (npr. odgovor koji se beleži u memoriji) stream ne može da radi
+	if err := rc.Flush(); err != nil {
+		log.Printf("WARNING: SSE stream za modul '%s' nije moguć: %v", moduleID, err)
+		return
+	}
+	log.Printf("INFO: SSE klijent povezan na modul '%s' (od transakcije %d).", moduleID, cursor)
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			log.Printf("INFO: SSE klijent odjavljen sa modula '%s'.", moduleID)
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			rc.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return // Broker je odjavio sporog klijenta; on se ponovo povezuje sa Last-Event-ID
+			}
+			if replayed != nil && replayed.visible(event.TxID) {
+				continue // Već poslato tokom nadoknade
+			}
+			// Obaveštenja stižu redom potvrde, pa su sve transakcije pre Horizon-a već obrađene
+			cursor = max(cursor, event.Horizon)
+			if !s.changeVisible(req.Context(), moduleDef, event) {
+				continue
+			}
+			if err := writeSSEEvent(w, visibleChangeEvent(moduleDef, user, event), cursor); err != nil {
+				return
+			}
+			rc.Flush()
+		}
+	}
+}
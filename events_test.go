@@ -0,0 +1,100 @@
+// events_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPGSnapshotVisible(t *testing.T) {
+	snapshot, err := parsePGSnapshot("100:105:101,103")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for xid, want := range map[int64]bool{99: true, 100: true, 101: false, 102: true, 103: false, 104: true, 105: false, 200: false} {
+		if got := snapshot.visible(xid); got != want {
+			t.Errorf("visible(%d) = %v, očekivano %v", xid, got, want)
+		}
+	}
+	if empty, err := parsePGSnapshot("7:7:"); err != nil || !empty.visible(6) || empty.visible(7) {
+		t.Errorf("snimak bez aktivnih transakcija: %+v, %v", empty, err)
+	}
+	for _, text := range []string{"", "1:2", "a:2:", "1:2:x"} {
+		if _, err := parsePGSnapshot(text); err == nil {
+			t.Errorf("parsePGSnapshot(%q): očekivana greška", text)
+		}
+	}
+}
+
+func TestWriteSSEEventUsesCursorAsID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	event := ChangeEvent{ID: 42, Event: "created", ModuleID: "m", RecordID: "1", TxID: 900, Horizon: 880}
+	if err := writeSSEEvent(rec, event, 880); err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 880\nevent: created\ndata: {\"id\":42,\"event\":\"created\",\"module_id\":\"m\",\"record_id\":\"1\",\"fields\":null,\"occurred_at\":\"0001-01-01T00:00:00Z\"}\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("SSE blok = %q, očekivano %q", rec.Body.String(), want)
+	}
+}
+
+func TestAuditReplayDoesNotSkipLateCommits(t *testing.T) {
+	module := &ModuleDefinition{ID: fmt.Sprintf("test_sse_%d", time.Now().UnixNano()), Name: "SSE"}
+	ds := newTestDataset(t, module)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		ds.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE module_id = $1", ds.config.Config.AuditTable), module.ID)
+	})
+
+	// A dobija manji ID, ali se potvrđuje posle B
+	txA, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txA.Rollback()
+	entryA, err := ds.writeAudit(ctx, txA, module, 1, AuditCreate, nil, map[string]interface{}{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entryA.Horizon > entryA.TxID {
+		t.Errorf("horizon %d je posle sopstvene transakcije %d", entryA.Horizon, entryA.TxID)
+	}
+	txB, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryB, err := ds.writeAudit(ctx, txB, module, 2, AuditCreate, nil, map[string]interface{}{"n": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txB.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, snapshot, err := ds.GetAuditEntriesSince(ctx, module.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != entryB.ID || !snapshot.visible(entryB.TxID) || snapshot.visible(entryA.TxID) {
+		t.Fatalf("pre potvrde A: %+v, snimak %+v", entries, snapshot)
+	}
+	cursor := max(1, snapshot.xmin)
+
+	if err := txA.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err = ds.GetAuditEntriesSince(ctx, module.ID, cursor, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, entry := range entries {
+		found = found || entry.ID == entryA.ID
+	}
+	if !found {
+		t.Errorf("nastavak od kursora %d ne sadrži izmenu potvrđenu kasnije: %+v", cursor, entries)
+	}
+}
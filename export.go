@@ -0,0 +1,386 @@
+// export.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// Broj redova koji se čitaju, proširuju (lookup) i upisuju odjednom pri izvozu.
+const exportBatchSize = 500
+
+// Query parametri izvoza; ostali parametri se tumače kao kod GetRecords (filteri, _sort, _search...).
+const (
+	exportFormatParam    = "_format"
+	exportDelimiterParam = "_delimiter"
+	exportEncodingParam  = "_encoding"
+)
+
+// recordExporter upisuje izvezene redove u određenom formatu.
+type recordExporter interface {
+	WriteHeader(headers []string) error
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// StreamRecords runs the module's select query with the given parameters and passes
+// the rows to fn in batches, so large result sets are never held in memory at once.
+// Lookup kolone su proširene i OnSelect handler-i pozvani za svaku grupu redova.
+func (s *SQLDataset) StreamRecords(ctx context.Context, moduleDef *ModuleDefinition, queryParams url.Values, fn func(batch []map[string]interface{}) error) error {
+	query, args, err := s.buildSelectQuery(ctx, moduleDef, queryParams)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("greška pri izvršavanju SELECT upita za modul '%s': %w", moduleDef.ID, err)
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("greška pri dohvatanju imena kolona: %w", err)
+	}
+
+	flush := func(batch []map[string]interface{}) error {
+		if err := s.performLookupExpansion(ctx, batch, moduleDef); err != nil {
+			log.Printf("WARNING: Greška pri proširenju lookup-a za modul '%s': %v", moduleDef.ID, err)
+		}
+		if err := s.Hooks.runOnSelect(ctx, moduleDef, batch); err != nil {
+			return err
+		}
+		return fn(batch)
+	}
+
+	batch := make([]map[string]interface{}, 0, exportBatchSize)
+	for rows.Next() {
+		record, err := scanRow(rows, columnNames)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, record)
+		if len(batch) == exportBatchSize {
+			if err := flush(batch); err != nil {
+				return err
+			}
+			batch = make([]map[string]interface{}, 0, exportBatchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("greška nakon iteracije kroz redove: %w", err)
+	}
+	if len(batch) > 0 {
+		return flush(batch)
+	}
+	return nil
+}
+
+// exportColumns vraća kolone koje se izvoze: vidljive kolone koje korisnik sme da vidi.
+func exportColumns(moduleDef *ModuleDefinition, user *User) []ColumnDefinition {
+	columns := make([]ColumnDefinition, 0, len(moduleDef.Columns))
+	for _, colDef := range moduleDef.Columns {
+		if colDef.IsVisible && colDef.UserCanRead(user) {
+			columns = append(columns, colDef)
+		}
+	}
+	return columns
+}
+
+// exportCellValue pretvara vrednost iz baze u vrednost ćelije: lookup kolone se prikazuju
+// prikaznom vrednošću, datumi kao tekst, a numeričke kolone kao brojevi.
+func exportCellValue(colDef *ColumnDefinition, val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}: // Proširen lookup: {"id": ..., "name": ...}
+		return v["name"]
+	case time.Time:
+		if colDef.Type == "date" {
+			return v.Format(time.DateOnly)
+		}
+		return v.Format(time.DateTime)
+	case string:
+		if colDef.Type == "integer" || colDef.Type == "float" {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f // NUMERIC kolone stižu kao tekst
+			}
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// formatCSVValue pretvara vrednost ćelije u tekst za CSV.
+func formatCSVValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvExporter upisuje redove kao CSV.
+type csvExporter struct {
+	cw  *csv.Writer
+	out *bufio.Writer
+}
+
+// newCSVExporter kreira CSV izvoz sa zadatim separatorom i kodnom stranom
+// ("utf-8", "utf-8-bom" za Excel ili "windows-1250").
+func newCSVExporter(w io.Writer, delimiter rune, encoding string) (*csvExporter, error) {
+	var dst io.Writer = w
+	switch encoding {
+	case "", "utf-8":
+	case "utf-8-bom":
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return nil, err
+		}
+	case "windows-1250", "cp1250":
+		dst = &windows1250Writer{w: w}
+	default:
+		return nil, fmt.Errorf("nepodržana kodna strana '%s'", encoding)
+	}
+
+	out := bufio.NewWriter(dst)
+	cw := csv.NewWriter(out)
+	cw.Comma = delimiter
+	cw.UseCRLF = true // Excel očekuje CRLF
+	return &csvExporter{cw: cw, out: out}, nil
+}
+
+func (c *csvExporter) WriteHeader(headers []string) error {
+	return c.cw.Write(headers)
+}
+
+func (c *csvExporter) WriteRow(cells []interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i] = formatCSVValue(cell)
+	}
+	return c.cw.Write(record)
+}
+
+func (c *csvExporter) Close() error {
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		return err
+	}
+	return c.out.Flush()
+}
+
+// parseCSVDelimiter tumači _delimiter parametar ("," podrazumevano, "tab" za tabulator).
+func parseCSVDelimiter(value string) (rune, error) {
+	switch value {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("nevažeći separator '%s'", value)
+	}
+	return r, nil
+}
+
+// ExportModuleRecords handles GET /api/modules/{moduleID}/export?_format=csv|xlsx.
+// Filteri, sortiranje i pretraga su isti kao kod liste zapisa.
+func (s *APIServer) ExportModuleRecords(w http.ResponseWriter, req *http.Request) {
+	moduleID := mux.Vars(req)["moduleID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermRead) {
+		return
+	}
+
+	columns := exportColumns(moduleDef, UserFromContext(req.Context()))
+	if len(columns) == 0 {
+		writeError(w, fmt.Sprintf("Modul '%s' nema vidljivih kolona za izvoz.", moduleID), http.StatusBadRequest)
+		return
+	}
+
+	queryParams := req.URL.Query()
+	format := strings.ToLower(queryParams.Get(exportFormatParam))
+	if format == "" {
+		format = "csv"
+	}
+	delimiter, err := parseCSVDelimiter(queryParams.Get(exportDelimiterParam))
+	if err != nil {
+		writeError(w, fmt.Sprintf("Greška u parametru %s: %v", exportDelimiterParam, err), http.StatusBadRequest)
+		return
+	}
+	encoding := strings.ToLower(queryParams.Get(exportEncodingParam))
+
+	var contentType string
+	switch format {
+	case "csv":
+		switch encoding {
+		case "", "utf-8", "utf-8-bom":
+			contentType = "text/csv; charset=utf-8"
+		case "windows-1250", "cp1250":
+			contentType = "text/csv; charset=windows-1250"
+		default:
+			writeError(w, fmt.Sprintf("Nepodržana kodna strana '%s' (dozvoljeno: utf-8, utf-8-bom, windows-1250).", encoding), http.StatusBadRequest)
+			return
+		}
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		writeError(w, fmt.Sprintf("Nepodržan format izvoza '%s' (dozvoljeno: csv, xlsx).", format), http.StatusBadRequest)
+		return
+	}
+
+	// Parametri izvoza nisu filteri
+	for _, param := range []string{exportFormatParam, exportDelimiterParam, exportEncodingParam} {
+		queryParams.Del(param)
+	}
+
+	headers := make([]string, len(columns))
+	for i, colDef := range columns {
+		headers[i] = colDef.Name
+	}
+
+	// Izvoz velikih tabela traje duže od WriteTimeout-a servera
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("WARNING: Nije moguće ukloniti write deadline za izvoz: %v", err)
+	}
+
+	// Izlaz se otvara tek uz prvu grupu redova, da bi greška u upitu mogla da se vrati kao 500
+	var exporter recordExporter
+	start := func() error {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, moduleID, format))
+		var err error
+		if format == "xlsx" {
+			exporter, err = newXLSXWriter(w, moduleDef.Name)
+		} else {
+			exporter, err = newCSVExporter(w, delimiter, encoding)
+		}
+		if err != nil {
+			return err
+		}
+		return exporter.WriteHeader(headers)
+	}
+
+	rowCount := 0
+	err = s.dataset.StreamRecords(req.Context(), moduleDef, queryParams, func(batch []map[string]interface{}) error {
+		if exporter == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		for _, record := range batch {
+			cells := make([]interface{}, len(columns))
+			for i := range columns {
+				cells[i] = exportCellValue(&columns[i], record[columns[i].DBColumnName])
+			}
+			if err := exporter.WriteRow(cells); err != nil {
+				return err
+			}
+		}
+		rowCount += len(batch)
+		return nil
+	})
+	if err != nil {
+		if exporter == nil {
+			if writeClientError(w, err) {
+				return
+			}
+			writeInternalError(w, fmt.Sprintf("Greška pri izvozu zapisa za modul '%s'", moduleID), err)
+		} else {
+			// Zaglavlje odgovora je već poslato; klijent dobija nepotpunu datoteku
+			log.Printf("ERROR: Izvoz modula '%s' prekinut posle %d redova: %v", moduleID, rowCount, err)
+		}
+		return
+	}
+
+	if exporter == nil { // Nijedan red: datoteka sadrži samo zaglavlje
+		if err := start(); err != nil {
+			writeInternalError(w, fmt.Sprintf("Greška pri izvozu zapisa za modul '%s'", moduleID), err)
+			return
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		log.Printf("ERROR: Greška pri završavanju izvoza modula '%s': %v", moduleID, err)
+		return
+	}
+	log.Printf("INFO: Izvezeno %d zapisa modula '%s' u formatu %s.", rowCount, moduleID, format)
+}
+
+// windows1250High mapira bajtove 0x80-0xFF kodne strane Windows-1250 na Unicode (0 = nedefinisano).
+var windows1250High = [128]rune{
+	0x20AC, 0, 0x201A, 0, 0x201E, 0x2026, 0x2020, 0x2021, 0, 0x2030, 0x0160, 0x2039, 0x015A, 0x0164, 0x017D, 0x0179,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0, 0x2122, 0x0161, 0x203A, 0x015B, 0x0165, 0x017E, 0x017A,
+	0x00A0, 0x02C7, 0x02D8, 0x0141, 0x00A4, 0x0104, 0x00A6, 0x00A7, 0x00A8, 0x00A9, 0x015E, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x017B,
+	0x00B0, 0x00B1, 0x02DB, 0x0142, 0x00B4, 0x00B5, 0x00B6, 0x00B7, 0x00B8, 0x0105, 0x015F, 0x00BB, 0x013D, 0x02DD, 0x013E, 0x017C,
+	0x0154, 0x00C1, 0x00C2, 0x0102, 0x00C4, 0x0139, 0x0106, 0x00C7, 0x010C, 0x00C9, 0x0118, 0x00CB, 0x011A, 0x00CD, 0x00CE, 0x010E,
+	0x0110, 0x0143, 0x0147, 0x00D3, 0x00D4, 0x0150, 0x00D6, 0x00D7, 0x0158, 0x016E, 0x00DA, 0x0170, 0x00DC, 0x00DD, 0x0162, 0x00DF,
+	0x0155, 0x00E1, 0x00E2, 0x0103, 0x00E4, 0x013A, 0x0107, 0x00E7, 0x010D, 0x00E9, 0x0119, 0x00EB, 0x011B, 0x00ED, 0x00EE, 0x010F,
+	0x0111, 0x0144, 0x0148, 0x00F3, 0x00F4, 0x0151, 0x00F6, 0x00F7, 0x0159, 0x016F, 0x00FA, 0x0171, 0x00FC, 0x00FD, 0x0163, 0x02D9,
+}
+
+// windows1250Encode je obrnuta mapa: Unicode -> bajt Windows-1250.
+var windows1250Encode = func() map[rune]byte {
+	m := make(map[rune]byte, len(windows1250High))
+	for i, r := range windows1250High {
+		if r != 0 {
+			m[r] = byte(0x80 + i)
+		}
+	}
+	return m
+}()
+
+// windows1250Writer prekodira UTF-8 tekst u Windows-1250; znakovi koji ne postoje
+// u kodnoj strani upisuju se kao '?'. Nepotpun UTF-8 niz na kraju upisa čeka sledeći upis.
+type windows1250Writer struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (e *windows1250Writer) Write(p []byte) (int, error) {
+	data := append(e.pending, p...)
+	out := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		switch b, ok := windows1250Encode[r]; {
+		case r < utf8.RuneSelf:
+			out = append(out, byte(r))
+		case ok:
+			out = append(out, b)
+		default:
+			out = append(out, '?')
+		}
+	}
+	e.pending = append(e.pending[:0:0], data...)
+	if _, err := e.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
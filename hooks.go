@@ -0,0 +1,345 @@
+// hooks.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Potpisi serverskih event handler-a (po uzoru na Jam.py server module).
+// Handler koji vrati grešku prekida operaciju i poništava transakciju; za poruku
+// namenjenu korisniku treba vratiti *ValidationError.
+type (
+	// BeforeCreateHook može da izmeni payload pre INSERT-a; izmenjen payload se ponovo validira.
+	BeforeCreateHook func(ctx context.Context, module *ModuleDefinition, payload map[string]interface{}) error
+	// AfterCreateHook dobija novi red onako kako ga je vratila baza.
+	AfterCreateHook func(ctx context.Context, module *ModuleDefinition, record map[string]interface{}) error
+	// BeforeUpdateHook dobija trenutno stanje zapisa i može da izmeni payload (koji se tada ponovo validira).
+	BeforeUpdateHook func(ctx context.Context, module *ModuleDefinition, old, payload map[string]interface{}) error
+	// AfterUpdateHook dobija stanje zapisa pre i posle izmene.
+	AfterUpdateHook func(ctx context.Context, module *ModuleDefinition, old, new map[string]interface{}) error
+	// BeforeDeleteHook i AfterDeleteHook dobijaju zapis koji se briše.
+	BeforeDeleteHook func(ctx context.Context, module *ModuleDefinition, record map[string]interface{}) error
+	AfterDeleteHook  func(ctx context.Context, module *ModuleDefinition, record map[string]interface{}) error
+	// SelectHook dobija pročitane zapise (posle proširenja lookup-a) i može da ih izmeni.
+	SelectHook func(ctx context.Context, module *ModuleDefinition, records []map[string]interface{}) error
+	// RowFilterHook vraća SQL uslov koji ograničava redove modula dostupne korisniku iz ctx
+	// (npr. "company_id = $1" sa args); parametri se numerišu od $1, a prazan uslov ne ograničava ništa.
+	RowFilterHook func(ctx context.Context, module *ModuleDefinition) (condition string, args []interface{}, err error)
+)
+
+// moduleHooks holds the handlers registered for a single module.
+type moduleHooks struct {
+	beforeCreate []BeforeCreateHook
+	afterCreate  []AfterCreateHook
+	beforeUpdate []BeforeUpdateHook
+	afterUpdate  []AfterUpdateHook
+	beforeDelete []BeforeDeleteHook
+	afterDelete  []AfterDeleteHook
+	onSelect     []SelectHook
+	rowFilter    []RowFilterHook
+}
+
+// HookRegistry stores server event handlers keyed by module ID.
+type HookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string]*moduleHooks
+}
+
+// NewHookRegistry creates an empty hook registry.
+func NewHookRegistry() *HookRegistry {
+	return &HookRegistry{hooks: make(map[string]*moduleHooks)}
+}
+
+// register vraća (i po potrebi kreira) handler-e modula pod zaključanim mutex-om.
+func (r *HookRegistry) register(moduleID string, fn func(h *moduleHooks)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hooks[moduleID]
+	if !ok {
+		h = &moduleHooks{}
+		r.hooks[moduleID] = h
+	}
+	fn(h)
+}
+
+// get vraća kopiju handler-a modula, da bi se mogli pozivati bez držanja mutex-a.
+func (r *HookRegistry) get(moduleID string) moduleHooks {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if h, ok := r.hooks[moduleID]; ok {
+		return *h
+	}
+	return moduleHooks{}
+}
+
+// hasBeforeUpdate javlja da li modul ima BeforeUpdate handler-e (koji mogu da menjaju payload po zapisu).
+func (r *HookRegistry) hasBeforeUpdate(moduleID string) bool {
+	return len(r.get(moduleID).beforeUpdate) > 0
+}
+
+// BeforeCreate registers a handler invoked before a record of the module is inserted.
+func (r *HookRegistry) BeforeCreate(moduleID string, fn BeforeCreateHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.beforeCreate = append(h.beforeCreate, fn) })
+}
+
+// AfterCreate registers a handler invoked after a record of the module is inserted.
+func (r *HookRegistry) AfterCreate(moduleID string, fn AfterCreateHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.afterCreate = append(h.afterCreate, fn) })
+}
+
+// BeforeUpdate registers a handler invoked before a record of the module is updated.
+func (r *HookRegistry) BeforeUpdate(moduleID string, fn BeforeUpdateHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.beforeUpdate = append(h.beforeUpdate, fn) })
+}
+
+// AfterUpdate registers a handler invoked after a record of the module is updated.
+func (r *HookRegistry) AfterUpdate(moduleID string, fn AfterUpdateHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.afterUpdate = append(h.afterUpdate, fn) })
+}
+
+// BeforeDelete registers a handler invoked before a record of the module is deleted.
+func (r *HookRegistry) BeforeDelete(moduleID string, fn BeforeDeleteHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.beforeDelete = append(h.beforeDelete, fn) })
+}
+
+// AfterDelete registers a handler invoked after a record of the module is deleted.
+func (r *HookRegistry) AfterDelete(moduleID string, fn AfterDeleteHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.afterDelete = append(h.afterDelete, fn) })
+}
+
+// OnSelect registers a handler invoked with the records read from the module.
+func (r *HookRegistry) OnSelect(moduleID string, fn SelectHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.onSelect = append(h.onSelect, fn) })
+}
+
+// RowFilter registers a row-level filter: čitanja, izmene i lookup reference vide samo
+// redove modula koji ispunjavaju uslov.
+func (r *HookRegistry) RowFilter(moduleID string, fn RowFilterHook) {
+	r.register(moduleID, func(h *moduleHooks) { h.rowFilter = append(h.rowFilter, fn) })
+}
+
+func (r *HookRegistry) runBeforeCreate(ctx context.Context, module *ModuleDefinition, payload map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).beforeCreate {
+		if err := fn(ctx, module, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// beforeCreate izvršava BeforeCreate handler-e. Payload se validira pre handler-a, pa se
+// ponovo validira ako su ga handler-i izmenili.
+func (s *SQLDataset) beforeCreate(ctx context.Context, module *ModuleDefinition, payload map[string]interface{}) error {
+	snapshot := maps.Clone(payload)
+	if err := s.Hooks.runBeforeCreate(ctx, module, payload); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(snapshot, payload) {
+		return nil
+	}
+	return s.validateRecord(ctx, validationTarget{Module: module}, payload)
+}
+
+// beforeUpdate izvršava BeforeUpdate handler-e i ponovo validira payload koji su izmenili.
+func (s *SQLDataset) beforeUpdate(ctx context.Context, module *ModuleDefinition, recordID string, old, payload map[string]interface{}) error {
+	snapshot := maps.Clone(payload)
+	if err := s.Hooks.runBeforeUpdate(ctx, module, old, payload); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(snapshot, payload) {
+		return nil
+	}
+	return s.validateRecord(ctx, validationTarget{Module: module, RecordID: recordID, Partial: true}, payload)
+}
+
+func (r *HookRegistry) runAfterCreate(ctx context.Context, module *ModuleDefinition, record map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).afterCreate {
+		if err := fn(ctx, module, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HookRegistry) runBeforeUpdate(ctx context.Context, module *ModuleDefinition, old, payload map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).beforeUpdate {
+		if err := fn(ctx, module, old, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HookRegistry) runAfterUpdate(ctx context.Context, module *ModuleDefinition, old, new map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).afterUpdate {
+		if err := fn(ctx, module, old, new); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HookRegistry) runBeforeDelete(ctx context.Context, module *ModuleDefinition, record map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).beforeDelete {
+		if err := fn(ctx, module, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HookRegistry) runAfterDelete(ctx context.Context, module *ModuleDefinition, record map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).afterDelete {
+		if err := fn(ctx, module, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HookRegistry) runOnSelect(ctx context.Context, module *ModuleDefinition, records []map[string]interface{}) error {
+	for _, fn := range r.get(module.ID).onSelect {
+		if err := fn(ctx, module, records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeholderPattern prepoznaje parametre upita ($1, $2...).
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// rowFilterCondition spaja uslove RowFilter handler-a sa AND; parametri se prenumerišu tako
+// da se nastavljaju na argOffset postojećih argumenata upita.
+func (r *HookRegistry) rowFilterCondition(ctx context.Context, module *ModuleDefinition, argOffset int) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+	for _, fn := range r.get(module.ID).rowFilter {
+		cond, condArgs, err := fn(ctx, module)
+		if err != nil {
+			return "", nil, err
+		}
+		if cond == "" {
+			continue
+		}
+		offset := argOffset + len(args)
+		cond = placeholderPattern.ReplaceAllStringFunc(cond, func(p string) string {
+			n, _ := strconv.Atoi(p[1:])
+			return fmt.Sprintf("$%d", n+offset)
+		})
+		conditions = append(conditions, "("+cond+")")
+		args = append(args, condArgs...)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// andRowFilter dodaje uslov row-level filtera modula na where, a njegove parametre na args.
+func (s *SQLDataset) andRowFilter(ctx context.Context, where string, moduleDef *ModuleDefinition, args []interface{}) (string, []interface{}, error) {
+	cond, condArgs, err := s.Hooks.rowFilterCondition(ctx, moduleDef, len(args))
+	if err != nil {
+		return "", nil, fmt.Errorf("greška u row-level filteru modula '%s': %w", moduleDef.ID, err)
+	}
+	if cond == "" {
+		return where, args, nil
+	}
+	if where == "" {
+		return cond, append(args, condArgs...), nil
+	}
+	return where + " AND " + cond, append(args, condArgs...), nil
+}
+
+// recordVisible proverava da li zapis (i soft-obrisan) ispunjava row-level filter modula.
+// Bez filtera vraća true bez upita; trajno obrisan zapis sa filterom nije vidljiv.
+func (s *SQLDataset) recordVisible(ctx context.Context, moduleDef *ModuleDefinition, recordID string) (bool, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil || moduleDef.DBTableName == "" {
+		return true, nil
+	}
+	base := fmt.Sprintf("%s = $1", pkCol.DBColumnName)
+	id, convErr := convertValueToColumnType(recordID, pkCol.Type)
+	where, args, err := s.andRowFilter(ctx, base, moduleDef, []interface{}{id})
+	if err != nil {
+		return false, err
+	}
+	if where == base {
+		return true, nil
+	}
+	if convErr != nil {
+		return false, nil
+	}
+	var one int
+	err = s.conn(ctx).QueryRowContext(ctx, fmt.Sprintf("SELECT 1 FROM %s WHERE %s", moduleDef.DBTableName, where), args...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("greška pri proveri vidljivosti zapisa '%s' u modulu '%s': %w", recordID, moduleDef.ID, err)
+	}
+	return true, nil
+}
+
+type txContextKey struct{}
+
+// TxFromContext returns the write transaction a hook is running in, or nil
+// outside of a transaction. Hooks use it to make their own changes atomic
+// with the record change.
+func TxFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txContextKey{}).(*sql.Tx)
+	return tx
+}
+
+// withTxContext vraća kontekst koji nosi transakciju za handler-e.
+func withTxContext(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+type commitCallbacksKey struct{}
+
+// commitCallbacks čuva funkcije koje se izvršavaju tek posle uspešnog Commit-a.
+type commitCallbacks struct {
+	fns []func()
+}
+
+// withCommitCallbacks vraća kontekst u koji afterCommit može da dodaje funkcije.
+func withCommitCallbacks(ctx context.Context) (context.Context, *commitCallbacks) {
+	callbacks := &commitCallbacks{}
+	return context.WithValue(ctx, commitCallbacksKey{}, callbacks), callbacks
+}
+
+func (c *commitCallbacks) run() {
+	for _, fn := range c.fns {
+		fn()
+	}
+}
+
+// afterCommit odlaže fn do potvrde transakcije iz konteksta; van transakcije se odmah izvršava.
+func afterCommit(ctx context.Context, fn func()) {
+	if callbacks, ok := ctx.Value(commitCallbacksKey{}).(*commitCallbacks); ok {
+		callbacks.fns = append(callbacks.fns, fn)
+		return
+	}
+	fn()
+}
+
+// commitCallbacksMark vraća broj do sada odloženih funkcija, za kasnije odbacivanje.
+func commitCallbacksMark(ctx context.Context) int {
+	if callbacks, ok := ctx.Value(commitCallbacksKey{}).(*commitCallbacks); ok {
+		return len(callbacks.fns)
+	}
+	return 0
+}
+
+// discardCommitCallbacks odbacuje funkcije odložene posle oznake (poništen savepoint).
+func discardCommitCallbacks(ctx context.Context, mark int) {
+	if callbacks, ok := ctx.Value(commitCallbacksKey{}).(*commitCallbacks); ok && mark <= len(callbacks.fns) {
+		callbacks.fns = callbacks.fns[:mark]
+	}
+}
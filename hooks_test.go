@@ -0,0 +1,83 @@
+// hooks_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBeforeCreateRevalidatesHookChanges(t *testing.T) {
+	module := &ModuleDefinition{ID: "notes", Columns: []ColumnDefinition{
+		{DBColumnName: "title", Type: "string", IsEditable: true, Validation: "required,max:5"},
+	}}
+	s := &SQLDataset{config: &AppConfig{Rules: NewRuleRegistry()}, Hooks: NewHookRegistry()}
+	s.Hooks.BeforeCreate("notes", func(ctx context.Context, m *ModuleDefinition, payload map[string]interface{}) error {
+		if payload["title"] == "long" {
+			payload["title"] = "predugačak naslov"
+		}
+		return nil
+	})
+
+	if err := s.beforeCreate(context.Background(), module, map[string]interface{}{"title": "ok"}); err != nil {
+		t.Errorf("nepromenjen payload: neočekivana greška %v", err)
+	}
+	err := s.beforeCreate(context.Background(), module, map[string]interface{}{"title": "long"})
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || validationErrs[0].Field != "title" {
+		t.Errorf("payload koji je izmenio handler mora ponovo da prođe validaciju, greška = %v", err)
+	}
+}
+
+func TestRowFilterAppliesToUpsertHistoryAndLookups(t *testing.T) {
+	accounts := &ModuleDefinition{ID: "test_accounts", Name: "Nalozi", Type: "table", DBTableName: "test_accounts", UniqueKeys: [][]string{{"code"}},
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true, IsReadOnly: true},
+			{DBColumnName: "code", Name: "Šifra", Type: "string", IsVisible: true, IsEditable: true},
+			{DBColumnName: "owner", Name: "Vlasnik", Type: "string", IsVisible: true, IsEditable: true},
+		}}
+	orders := &ModuleDefinition{ID: "test_account_orders", Name: "Porudžbine", Type: "table", DBTableName: "test_account_orders",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true, IsReadOnly: true},
+			{DBColumnName: "account_id", Name: "Nalog", Type: "lookup", IsVisible: true, IsEditable: true,
+				LookupModuleID: "test_accounts", LookupDisplayField: "code", LookupModule: accounts},
+		}}
+	ds := newTestDataset(t, accounts, orders)
+	createTestTable(t, ds, accounts.DBTableName, "id SERIAL PRIMARY KEY, code TEXT NOT NULL UNIQUE, owner TEXT NOT NULL")
+	createTestTable(t, ds, orders.DBTableName, "id SERIAL PRIMARY KEY, account_id INT")
+	if _, err := ds.db.Exec(`INSERT INTO test_accounts (id, code, owner) VALUES (1, 'A', 'tester'), (2, 'B', 'marko');
+		INSERT INTO test_account_orders (account_id) VALUES (1), (2)`); err != nil {
+		t.Fatal(err)
+	}
+	ds.Hooks.RowFilter("test_accounts", func(ctx context.Context, m *ModuleDefinition) (string, []interface{}, error) {
+		return "owner = $1", []interface{}{UserFromContext(ctx).ID}, nil
+	})
+	s := NewAPIServer(ds.config, ds)
+
+	rec := serveTest(s, http.MethodPost, "/api/modules/test_accounts/upsert", `{"key": ["code"], "records": [{"code": "B", "owner": "tester"}]}`, "", nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("upsert tuđeg zapisa: status %d, telo %s", rec.Code, rec.Body)
+	}
+	var owner string
+	if err := ds.db.QueryRow("SELECT owner FROM test_accounts WHERE code = 'B'").Scan(&owner); err != nil || owner != "marko" {
+		t.Errorf("zapis van filtera je izmenjen: owner = %q, %v", owner, err)
+	}
+	rec = serveTest(s, http.MethodPost, "/api/modules/test_accounts/upsert", `{"key": ["code"], "records": [{"code": "A", "owner": "tester"}]}`, "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"updated"`) {
+		t.Errorf("upsert sopstvenog zapisa: status %d, telo %s", rec.Code, rec.Body)
+	}
+
+	if rec = serveTest(s, http.MethodGet, "/api/modules/test_accounts/2/history", "", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("istorija zapisa van filtera: status %d, očekivano 404", rec.Code)
+	}
+	if rec = serveTest(s, http.MethodGet, "/api/modules/test_accounts/1/history", "", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("istorija sopstvenog zapisa: status %d, telo %s", rec.Code, rec.Body)
+	}
+
+	rec = serveTest(s, http.MethodGet, "/api/modules/test_account_orders?_expand=account_id&_sort=id", "", "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"A"`) || strings.Contains(rec.Body.String(), `"name":"B"`) {
+		t.Errorf("proširenje lookup-a van filtera: status %d, telo %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,319 @@
+// i18n.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sourceLanguage je jezik poruka koje su napisane u kodu (greške, validacija).
+const sourceLanguage = "sr"
+
+// I18nConfig struct for language negotiation and message catalogs.
+type I18nConfig struct {
+	DefaultLanguage string   `json:"default_language"` // Jezik kada Accept-Language ne odgovara nijednom podržanom
+	Languages       []string `json:"languages"`        // Podržani jezici; prazno = jezici iz kataloga poruka
+	MessagesPath    string   `json:"messages_path"`    // Direktorijum sa <jezik>.json katalozima (dopunjuju ugrađene)
+}
+
+// applyDefaults popunjava podrazumevane vrednosti za nepostavljena podešavanja.
+func (c *I18nConfig) applyDefaults() {
+	if c.DefaultLanguage == "" {
+		c.DefaultLanguage = sourceLanguage
+	}
+}
+
+// builtinMessages su ugrađeni prevodi, po jeziku i ključu. Ključevi su kodovi grešaka
+// (vidi errors.go) i "validation.<pravilo>" sa parametrima u vitičastim zagradama.
+var builtinMessages = map[string]map[string]string{
+	"sr": {
+		ErrCodeBadRequest:           "Nevažeći zahtev.",
+		ErrCodeValidation:           "Podaci nisu ispravni.",
+		ErrCodeForbidden:            "Nemate dozvolu za ovu operaciju.",
+		ErrCodeNotFound:             "Traženi resurs nije pronađen.",
+		ErrCodeMethodNotAllowed:     "Metod nije dozvoljen.",
+		ErrCodeConflict:             "Zahtev je u konfliktu sa trenutnim stanjem.",
+		ErrCodeUniqueViolation:      "Zapis sa istom vrednošću već postoji.",
+		ErrCodeForeignKeyViolation:  "Vrednost upućuje na nepostojeći zapis.",
+		ErrCodeRecordReferenced:     "Zapis se koristi u drugim zapisima i ne može se obrisati.",
+		ErrCodeNotNullViolation:     "Obavezno polje nema vrednost.",
+		ErrCodeCheckViolation:       "Vrednost ne ispunjava ograničenje.",
+		ErrCodeInvalidValue:         "Nevažeća vrednost.",
+		ErrCodePreconditionFailed:   "Zapis je u međuvremenu izmenjen.",
+		ErrCodePayloadTooLarge:      "Zahtev je prevelik.",
+		ErrCodeUnsupportedMediaType: "Nepodržan tip sadržaja.",
+		ErrCodeUnprocessable:        "Zahtev se ne može obraditi.",
+		ErrCodeFailedDependency:     "Operacija je preskočena jer prethodna nije uspela.",
+		ErrCodePatchTestFailed:      "JSON Patch test operacija nije uspela.",
+		ErrCodeIdempotencyKeyReused: "Idempotency-Key je već upotrebljen za drugačiji zahtev.",
+		ErrCodeRequestInProgress:    "Zahtev sa istim Idempotency-Key se još obrađuje.",
+		ErrCodeInternal:             "Interna serverska greška.",
+
+		"validation.required":     "polje '{field}' je obavezno",
+		"validation.required_if":  "polje '{field}' je obavezno kada je '{other}' = {value}",
+		"validation.gt_field":     "polje '{field}' mora biti veće od polja '{other}'",
+		"validation.gte_field":    "polje '{field}' ne sme biti manje od polja '{other}'",
+		"validation.lt_field":     "polje '{field}' mora biti manje od polja '{other}'",
+		"validation.lte_field":    "polje '{field}' ne sme biti veće od polja '{other}'",
+		"validation.type.string":  "polje '{field}' mora biti string",
+		"validation.type.integer": "polje '{field}' mora biti ceo broj",
+		"validation.type.float":   "polje '{field}' mora biti decimalni broj",
+		"validation.type.boolean": "polje '{field}' mora biti logička vrednost (true/false)",
+		"validation.type.lookup":  "polje '{field}' mora biti ID zapisa ili objekat sa poljem id",
+		"validation.min":          "polje '{field}' mora biti najmanje {min}",
+		"validation.min_length":   "polje '{field}' mora imati najmanje {min} karaktera",
+		"validation.max":          "polje '{field}' može biti najviše {max}",
+		"validation.max_length":   "polje '{field}' može imati najviše {max} karaktera",
+		"validation.email":        "polje '{field}' mora biti validna email adresa",
+		"validation.regex":        "polje '{field}' ne ispunjava zahtevani format",
+		"validation.choice":       "polje '{field}' mora imati jednu od ponuđenih vrednosti",
+		"validation.unique":       "zapis sa ovom vrednošću već postoji",
+		"validation.exists":       "polje '{field}' upućuje na zapis koji ne postoji u modulu '{module_name}'",
+		"validation.foreign_key":  "referencirani zapis ne postoji",
+		"validation.check":        "vrednost ne ispunjava ograničenje '{constraint}'",
+	},
+	"en": {
+		ErrCodeBadRequest:           "Invalid request.",
+		ErrCodeValidation:           "The submitted data is not valid.",
+		ErrCodeForbidden:            "You are not allowed to perform this operation.",
+		ErrCodeNotFound:             "The requested resource was not found.",
+		ErrCodeMethodNotAllowed:     "Method not allowed.",
+		ErrCodeConflict:             "The request conflicts with the current state.",
+		ErrCodeUniqueViolation:      "A record with the same value already exists.",
+		ErrCodeForeignKeyViolation:  "The value refers to a record that does not exist.",
+		ErrCodeRecordReferenced:     "The record is used by other records and cannot be deleted.",
+		ErrCodeNotNullViolation:     "A required field has no value.",
+		ErrCodeCheckViolation:       "The value violates a constraint.",
+		ErrCodeInvalidValue:         "Invalid value.",
+		ErrCodePreconditionFailed:   "The record has been modified in the meantime.",
+		ErrCodePayloadTooLarge:      "The request is too large.",
+		ErrCodeUnsupportedMediaType: "Unsupported content type.",
+		ErrCodeUnprocessable:        "The request cannot be processed.",
+		ErrCodeFailedDependency:     "The operation was skipped because a previous one failed.",
+		ErrCodePatchTestFailed:      "A JSON Patch test operation failed.",
+		ErrCodeIdempotencyKeyReused: "The Idempotency-Key was already used for a different request.",
+		ErrCodeRequestInProgress:    "A request with the same Idempotency-Key is still being processed.",
+		ErrCodeInternal:             "Internal server error.",
+
+		"validation.required":     "field '{field}' is required",
+		"validation.required_if":  "field '{field}' is required when '{other}' is {value}",
+		"validation.gt_field":     "field '{field}' must be greater than '{other}'",
+		"validation.gte_field":    "field '{field}' must not be less than '{other}'",
+		"validation.lt_field":     "field '{field}' must be less than '{other}'",
+		"validation.lte_field":    "field '{field}' must not be greater than '{other}'",
+		"validation.type.string":  "field '{field}' must be a string",
+		"validation.type.integer": "field '{field}' must be an integer",
+		"validation.type.float":   "field '{field}' must be a number",
+		"validation.type.boolean": "field '{field}' must be true or false",
+		"validation.type.lookup":  "field '{field}' must be a record ID or an object with an id field",
+		"validation.min":          "field '{field}' must be at least {min}",
+		"validation.min_length":   "field '{field}' must be at least {min} characters long",
+		"validation.max":          "field '{field}' must be at most {max}",
+		"validation.max_length":   "field '{field}' must be at most {max} characters long",
+		"validation.email":        "field '{field}' must be a valid email address",
+		"validation.regex":        "field '{field}' does not match the required format",
+		"validation.choice":       "field '{field}' must be one of the allowed values",
+		"validation.unique":       "a record with this value already exists",
+		"validation.exists":       "field '{field}' refers to a record that does not exist in module '{module_name}'",
+		"validation.foreign_key":  "the referenced record does not exist",
+		"validation.check":        "the value violates constraint '{constraint}'",
+	},
+}
+
+// MessageCatalog holds translated messages by language and key.
+type MessageCatalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// messages je katalog poruka procesa: ugrađeni prevodi dopunjeni datotekama iz messages_path.
+var messages = newMessageCatalog(builtinMessages)
+
+// newMessageCatalog pravi katalog sa kopijom datih prevoda.
+func newMessageCatalog(initial map[string]map[string]string) *MessageCatalog {
+	c := &MessageCatalog{messages: make(map[string]map[string]string)}
+	for lang, texts := range initial {
+		c.Add(lang, texts)
+	}
+	return c
+}
+
+// Add merges translations for a language into the catalog, overriding existing keys.
+func (c *MessageCatalog) Add(lang string, texts map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lang = strings.ToLower(lang)
+	if c.messages[lang] == nil {
+		c.messages[lang] = make(map[string]string)
+	}
+	for key, text := range texts {
+		c.messages[lang][key] = text
+	}
+}
+
+// LoadDir loads every <lang>.json file (a flat key -> text object) from dir.
+func (c *MessageCatalog) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("greška pri čitanju direktorijuma poruka '%s': %w", dir, err)
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("greška pri čitanju kataloga poruka '%s': %w", file, err)
+		}
+		var texts map[string]string
+		if err := json.Unmarshal(content, &texts); err != nil {
+			return fmt.Errorf("greška pri parsiranju kataloga poruka '%s': %w", file, err)
+		}
+		lang := strings.TrimSuffix(filepath.Base(file), ".json")
+		c.Add(lang, texts)
+		log.Printf("INFO: Učitan katalog poruka '%s' (%d poruka).", lang, len(texts))
+	}
+	return nil
+}
+
+// Languages returns the languages present in the catalog, sorted.
+func (c *MessageCatalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Format returns the message for key in lang with {param} placeholders filled in.
+// Za "sr-Latn" se koristi i "sr"; false znači da prevod ne postoji.
+func (c *MessageCatalog) Format(lang, key string, params map[string]interface{}) (string, bool) {
+	c.mu.RLock()
+	text, ok := c.messages[lang][key]
+	if !ok {
+		base, _, _ := strings.Cut(lang, "-")
+		text, ok = c.messages[base][key]
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	if len(params) == 0 {
+		return text, true
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", formatMessageParam(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text), true
+}
+
+// formatMessageParam formatira parametar poruke (brojevi bez suvišnih decimala).
+func formatMessageParam(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// localize vraća prevod teksta za jezik (ili osnovni jezik, npr. "en" za "en-US"), inače sam tekst.
+func localize(text string, translations map[string]string, lang string) string {
+	if t, ok := translations[lang]; ok && t != "" {
+		return t
+	}
+	base, _, _ := strings.Cut(lang, "-")
+	if t, ok := translations[base]; ok && t != "" {
+		return t
+	}
+	return text
+}
+
+// negotiateLanguage bira jezik iz Accept-Language zaglavlja (po q vrednostima) među podržanima.
+func negotiateLanguage(header string, supported []string, fallback string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		for _, lang := range supported {
+			if lang == tag || lang == base {
+				best, bestQ = lang, q
+				break
+			}
+		}
+	}
+	if best == "" {
+		return fallback
+	}
+	return best
+}
+
+// supportedLanguages vraća jezike koje API nudi klijentima.
+func (ac *AppConfig) supportedLanguages() []string {
+	if len(ac.Config.I18n.Languages) > 0 {
+		return ac.Config.I18n.Languages
+	}
+	return messages.Languages()
+}
+
+type languageContextKey struct{}
+
+// languageFromContext vraća jezik zahteva, ili izvorni jezik poruka ako nije određen.
+func languageFromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(languageContextKey{}).(string); ok {
+		return lang
+	}
+	return sourceLanguage
+}
+
+// languageMiddleware određuje jezik zahteva iz Accept-Language i vraća ga u Content-Language.
+func (s *APIServer) languageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fallback := s.config.Config.I18n.DefaultLanguage
+		if fallback == "" {
+			fallback = sourceLanguage
+		}
+		lang := negotiateLanguage(req.Header.Get("Accept-Language"), s.config.supportedLanguages(), fallback)
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), languageContextKey{}, lang)))
+	})
+}
+
+// localizeErrorBody prevodi poruku greške i greške polja na jezik odgovora. Detaljna poruka
+// iz koda (na izvornom jeziku) ostaje u polju detail.
+func localizeErrorBody(body *ErrorBody, lang string) {
+	if lang == "" || lang == sourceLanguage {
+		return
+	}
+	if text, ok := messages.Format(lang, body.Code, nil); ok {
+		body.Detail = body.Message
+		body.Message = text
+	}
+	for _, fieldErr := range body.Fields {
+		if text, ok := fieldErr.localizedMessage(lang); ok {
+			fieldErr.Message = text
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// i18n_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	supported := []string{"en", "sr"}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "sr"},
+		{"en", "en"},
+		{"EN-us", "en"},
+		{"de, en;q=0.5", "en"},
+		{"en;q=0.3, sr;q=0.8", "sr"},
+		{"sr;q=0, en;q=0.1", "en"},
+		{"de, fr;q=0.9, *", "sr"},
+		{"en;q=nije-broj", "en"},
+	}
+	for _, tt := range tests {
+		if got := negotiateLanguage(tt.header, supported, "sr"); got != tt.want {
+			t.Errorf("negotiateLanguage(%q) = %q, očekivano %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessageCatalogFallback(t *testing.T) {
+	catalog := newMessageCatalog(map[string]map[string]string{
+		"sr": {"pozdrav": "Zdravo, {ime}!", "limit": "najviše {n}"},
+		"en": {"pozdrav": "Hello, {ime}!"},
+	})
+
+	tests := []struct {
+		lang, key string
+		params    map[string]interface{}
+		want      string
+		found     bool
+	}{
+		{"en", "pozdrav", map[string]interface{}{"ime": "Ana"}, "Hello, Ana!", true},
+		{"en-GB", "pozdrav", map[string]interface{}{"ime": "Ana"}, "Hello, Ana!", true},
+		{"sr", "limit", map[string]interface{}{"n": 5.0}, "najviše 5", true},
+		{"en", "limit", nil, "", false},
+		{"de", "pozdrav", nil, "", false},
+	}
+	for _, tt := range tests {
+		got, found := catalog.Format(tt.lang, tt.key, tt.params)
+		if got != tt.want || found != tt.found {
+			t.Errorf("Format(%q, %q) = %q, %v; očekivano %q, %v", tt.lang, tt.key, got, found, tt.want, tt.found)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"pozdrav": "Hallo, {ime}!"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := catalog.Format("de", "pozdrav", map[string]interface{}{"ime": "Ana"}); got != "Hallo, Ana!" {
+		t.Errorf("poruka iz učitanog kataloga: %q", got)
+	}
+	if langs := strings.Join(catalog.Languages(), ","); langs != "de,en,sr" {
+		t.Errorf("Languages() = %q", langs)
+	}
+
+	translations := map[string]string{"en": "Employees", "en-GB": ""}
+	for lang, want := range map[string]string{"en": "Employees", "en-GB": "Employees", "de": "Zaposleni"} {
+		if got := localize("Zaposleni", translations, lang); got != want {
+			t.Errorf("localize(%q) = %q, očekivano %q", lang, got, want)
+		}
+	}
+}
+
+func TestLocalizeErrorBody(t *testing.T) {
+	columns := []ColumnDefinition{
+		{DBColumnName: "name", Name: "Ime", NameI18n: map[string]string{"en": "Name"}, Type: "string", Validation: "required"},
+		{DBColumnName: "age", Name: "Godine", Type: "integer", Validation: "min:18"},
+	}
+	cfg := &AppConfig{Rules: NewRuleRegistry()}
+	newBody := func() *ErrorBody {
+		body := clientErrorBody(validatePayload(map[string]interface{}{"age": 12.0}, columns, cfg))
+		if body == nil || len(body.Fields) != 2 {
+			t.Fatalf("greška validacije: %+v", body)
+		}
+		return body
+	}
+
+	body := newBody()
+	original := body.Message
+	localizeErrorBody(body, sourceLanguage)
+	if body.Message != original || body.Detail != "" || body.Fields[0].Message != "polje 'Ime' je obavezno" {
+		t.Errorf("izvorni jezik ne sme da menja poruke: %+v", body)
+	}
+
+	body = newBody()
+	localizeErrorBody(body, "en")
+	if body.Message != "The submitted data is not valid." || body.Detail != original {
+		t.Errorf("poruka greške: %q, detalj %q", body.Message, body.Detail)
+	}
+	if got := body.Fields[0].Message; got != "field 'Name' is required" {
+		t.Errorf("greška polja sa prevedenim nazivom: %q", got)
+	}
+	if got := body.Fields[1].Message; got != "field 'Godine' must be at least 18" {
+		t.Errorf("greška polja bez prevoda naziva: %q", got)
+	}
+}
+
+func TestTranslatedModuleNames(t *testing.T) {
+	module := testEmployeesModule()
+	module.NameI18n = map[string]string{"en": "Employees"}
+	module.Columns[1].NameI18n = map[string]string{"en": "Name"}
+	group := &ModuleDefinition{ID: "test_hr", Name: "Kadrovska", NameI18n: map[string]string{"en": "HR"}, Type: "group",
+		SubModules: []SubModuleDefinition{{ID: "employees", TargetModuleID: module.ID}}}
+	app := &ModuleDefinition{ID: "app", Name: "Aplikacija", Type: "root", Groups: []GroupLink{{TargetGroupID: group.ID}}}
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{module.ID: module, group.ID: group, app.ID: app}, Rules: NewRuleRegistry()}
+	s := NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+	english := map[string]string{"Accept-Language": "en-US,en;q=0.9"}
+
+	rec := serveTest(s, http.MethodGet, "/api/modules", "", "", english)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Language") != "en" || !strings.Contains(rec.Body.String(), `"name":"HR"`) || !strings.Contains(rec.Body.String(), `"name":"Employees"`) {
+		t.Errorf("lista modula na engleskom: status %d, jezik %q, telo %s", rec.Code, rec.Header().Get("Content-Language"), rec.Body)
+	}
+	if rec := serveTest(s, http.MethodGet, "/api/modules", "", "", nil); !strings.Contains(rec.Body.String(), `"name":"Zaposleni"`) {
+		t.Errorf("lista modula na izvornom jeziku: telo %s", rec.Body)
+	}
+
+	rec = serveTest(s, http.MethodGet, "/api/modules/test_employees/schema", "", "", english)
+	var schema ModuleSchema
+	if err := json.Unmarshal(rec.Body.Bytes(), &schema); err != nil {
+		t.Fatalf("šema: status %d, %v", rec.Code, err)
+	}
+	if schema.Name != "Employees" || schema.Columns[1].Name != "Name" || schema.Columns[0].Name != "ID" {
+		t.Errorf("prevedena šema: %+v", schema)
+	}
+}
@@ -0,0 +1,313 @@
+// idempotency.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// idempotencyKeyHeader je zaglavlje kojim klijent označava ponovljiv (retry) zahtev.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	idempotencyTable       = "idempotency_keys"
+	idempotencyMaxKeyLen   = 255
+	idempotencyMaxBodySize = 8 << 20 // 8MB
+	// Posle ovog vremena bez osvežavanja nezavršen zahtev (npr. pad servera) više ne blokira ključ
+	idempotencyLockTimeout = time.Minute
+	// Dok se zahtev obrađuje, zauzeće ključa se osvežava ovoliko često
+	idempotencyHeartbeat = idempotencyLockTimeout / 4
+)
+
+// Zaglavlja originalnog odgovora koja se vraćaju pri ponavljanju.
+var idempotencyReplayHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyConfig struct for Idempotency-Key handling.
+type IdempotencyConfig struct {
+	TTLHours int `json:"ttl_hours"` // Koliko dugo se čuva odgovor za ključ
+}
+
+// applyDefaults popunjava podrazumevane vrednosti za nepostavljena podešavanja.
+func (c *IdempotencyConfig) applyDefaults() {
+	if c.TTLHours <= 0 {
+		c.TTLHours = 24
+	}
+}
+
+// storedResponse je sačuvan odgovor na idempotentni zahtev.
+type storedResponse struct {
+	requestHash string
+	status      sql.NullInt64 // NULL dok se originalni zahtev još obrađuje
+	headers     map[string]string
+	body        []byte
+}
+
+// ensureIdempotencyTable kreira tabelu idempotentnih ključeva ako ne postoji.
+func (s *SQLDataset) ensureIdempotencyTable() error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			key TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT '',
+			request_hash TEXT NOT NULL,
+			status_code INT,
+			response_headers JSONB,
+			response_body BYTEA,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			expires_at TIMESTAMPTZ NOT NULL,
+			PRIMARY KEY (key, user_id)
+		)`, idempotencyTable),
+		// Tabele iz ranijih verzija
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now()", idempotencyTable),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_expires_idx ON %s (expires_at)", idempotencyTable, idempotencyTable),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("greška pri kreiranju tabele '%s': %w", idempotencyTable, err)
+		}
+	}
+	return nil
+}
+
+// claimIdempotencyKey zauzima ključ za novi zahtev. Vraća true ako je ključ slobodan
+// (nov, istekao ili napušten), a inače false i postojeći zapis. Zahtev je napušten tek kada
+// se njegovo zauzeće nije osvežilo idempotencyLockTimeout (vidi keepIdempotencyClaim).
+func (s *SQLDataset) claimIdempotencyKey(ctx context.Context, key, userID, requestHash string) (bool, *storedResponse, error) {
+	ttl := time.Duration(s.config.Config.Idempotency.TTLHours) * time.Hour
+	query := fmt.Sprintf(`INSERT INTO %s AS t (key, user_id, request_hash, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (key, user_id) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash, status_code = NULL, response_headers = NULL,
+			response_body = NULL, created_at = now(), heartbeat_at = now(), expires_at = EXCLUDED.expires_at
+		WHERE t.expires_at < now() OR (t.status_code IS NULL AND t.heartbeat_at < now() - make_interval(secs => $5))
+		RETURNING true`, idempotencyTable)
+	var claimed bool
+	err := s.db.QueryRowContext(ctx, query, key, userID, requestHash, ttl.Seconds(), idempotencyLockTimeout.Seconds()).Scan(&claimed)
+	if err == nil {
+		return true, nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, nil, fmt.Errorf("greška pri zauzimanju idempotentnog ključa: %w", err)
+	}
+
+	// Ključ je zauzet: vrati sačuvan odgovor (ili informaciju da je zahtev u toku)
+	stored := &storedResponse{}
+	var headers []byte
+	err = s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT request_hash, status_code, response_headers, response_body FROM %s WHERE key = $1 AND user_id = $2", idempotencyTable),
+		key, userID,
+	).Scan(&stored.requestHash, &stored.status, &headers, &stored.body)
+	if err != nil {
+		return false, nil, fmt.Errorf("greška pri čitanju idempotentnog ključa: %w", err)
+	}
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &stored.headers); err != nil {
+			return false, nil, fmt.Errorf("greška pri čitanju sačuvanih zaglavlja: %w", err)
+		}
+	}
+	return false, stored, nil
+}
+
+// refreshIdempotencyKey produžava zauzeće ključa čiji se zahtev još obrađuje.
+func (s *SQLDataset) refreshIdempotencyKey(ctx context.Context, key, userID string) error {
+	_, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET heartbeat_at = now() WHERE key = $1 AND user_id = $2 AND status_code IS NULL", idempotencyTable),
+		key, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("greška pri osvežavanju idempotentnog ključa: %w", err)
+	}
+	return nil
+}
+
+// keepIdempotencyClaim osvežava zauzeće ključa dok handler radi, da ponovljen zahtev ne bi
+// preuzeo ključ dugog zahteva (bulk, batch, uvoz) koji još može da potvrdi izmene.
+// Vraćena funkcija zaustavlja osvežavanje i čeka da se završi.
+func (s *APIServer) keepIdempotencyClaim(ctx context.Context, key, userID string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.dataset.refreshIdempotencyKey(ctx, key, userID); err != nil {
+					log.Printf("ERROR: %v (Idempotency-Key '%s')", err, key)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// saveIdempotentResponse čuva odgovor za ključ, da bi se vratio pri ponavljanju zahteva.
+func (s *SQLDataset) saveIdempotentResponse(ctx context.Context, key, userID string, rec *responseRecorder) error {
+	headers := make(map[string]string)
+	for _, name := range idempotencyReplayHeaders {
+		if v := rec.header.Get(name); v != "" {
+			headers[name] = v
+		}
+	}
+	headersJSON, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET status_code = $3, response_headers = $4, response_body = $5 WHERE key = $1 AND user_id = $2", idempotencyTable),
+		key, userID, rec.status, string(headersJSON), rec.body.Bytes(),
+	)
+	if err != nil {
+		return fmt.Errorf("greška pri čuvanju idempotentnog odgovora: %w", err)
+	}
+	return nil
+}
+
+// releaseIdempotencyKey oslobađa ključ posle serverske greške, da bi klijent mogao ponovo da pokuša.
+func (s *SQLDataset) releaseIdempotencyKey(ctx context.Context, key, userID string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE key = $1 AND user_id = $2", idempotencyTable), key, userID)
+	if err != nil {
+		return fmt.Errorf("greška pri oslobađanju idempotentnog ključa: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes keys whose TTL has passed.
+func (s *SQLDataset) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE expires_at < now()", idempotencyTable))
+	if err != nil {
+		return 0, fmt.Errorf("greška pri brisanju isteklih idempotentnih ključeva: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RunIdempotencyCleanup periodically deletes expired idempotency keys until ctx is cancelled.
+func (s *SQLDataset) RunIdempotencyCleanup(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := s.DeleteExpiredIdempotencyKeys(ctx); err != nil {
+			log.Printf("ERROR: %v", err)
+		} else if n > 0 {
+			log.Printf("INFO: Obrisano %d isteklih idempotentnih ključeva.", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// idempotent obavija handler podrškom za Idempotency-Key zaglavlje: prvi zahtev se izvršava
+// i njegov odgovor čuva, ponovljen zahtev sa istim telom dobija isti odgovor, a isti ključ
+// sa drugačijim zahtevom vraća 422. Zahtevi bez zaglavlja prolaze bez promene.
+// Ključevi su vezani za korisnika, pa ih anonimni pozivaoci ne mogu koristiti: inače bi
+// svi delili isti prostor ključeva i mogli da dobiju tuđi sačuvan odgovor.
+func (s *APIServer) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, req)
+			return
+		}
+		userID := userIDFromContext(req.Context())
+		if userID == "" {
+			writeErrorCode(w, ErrCodeBadRequest, "Idempotency-Key zahteva prijavljenog korisnika.", http.StatusBadRequest)
+			return
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			writeError(w, fmt.Sprintf("Idempotency-Key može imati najviše %d karaktera.", idempotencyMaxKeyLen), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, idempotencyMaxBodySize))
+		if err != nil {
+			writeError(w, fmt.Sprintf("Greška pri čitanju tela zahteva: %v", err), http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s\n%s\n", req.Method, req.URL.RequestURI())
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		claimed, stored, err := s.dataset.claimIdempotencyKey(req.Context(), key, userID, requestHash)
+		if err != nil {
+			writeInternalError(w, fmt.Sprintf("Greška pri obradi Idempotency-Key '%s'", key), err)
+			return
+		}
+		if !claimed {
+			switch {
+			case stored.requestHash != requestHash:
+				writeErrorCode(w, ErrCodeIdempotencyKeyReused, "Idempotency-Key je već upotrebljen za drugačiji zahtev.", http.StatusUnprocessableEntity)
+			case !stored.status.Valid:
+				w.Header().Set("Retry-After", "1")
+				writeErrorCode(w, ErrCodeRequestInProgress, "Zahtev sa istim Idempotency-Key se još obrađuje.", http.StatusConflict)
+			default:
+				for name, value := range stored.headers {
+					w.Header().Set(name, value)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(int(stored.status.Int64))
+				w.Write(stored.body)
+				log.Printf("INFO: Vraćen sačuvan odgovor za Idempotency-Key '%s' (%s %s).", key, req.Method, req.URL.Path)
+			}
+			return
+		}
+
+		rec := newResponseRecorder()
+		rec.header.Set(requestIDHeader, w.Header().Get(requestIDHeader)) // Za request_id u JSON greškama
+		rec.header.Set("Content-Language", w.Header().Get("Content-Language"))
+		stopHeartbeat := s.keepIdempotencyClaim(context.WithoutCancel(req.Context()), key, userID)
+		func() {
+			defer stopHeartbeat() // I kada handler panic-uje
+			next(rec, req)
+		}()
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.overflow {
+			log.Printf("ERROR: Odgovor za Idempotency-Key '%s' (%s %s) je veći od %d bajtova.", key, req.Method, req.URL.Path, recordedResponseMaxSize)
+			rec.status = http.StatusInternalServerError
+			rec.body.Reset()
+			rec.overflow = false
+			for _, name := range []string{"Content-Type", "Content-Length", "ETag", "Location"} {
+				rec.header.Del(name)
+			}
+			writeError(rec, "Odgovor je prevelik za čuvanje uz Idempotency-Key.", http.StatusInternalServerError)
+		}
+
+		// Serverska greška se ne pamti, da bi ponovljen zahtev imao šansu da uspe
+		if rec.status >= 500 {
+			err = s.dataset.releaseIdempotencyKey(context.WithoutCancel(req.Context()), key, userID)
+		} else {
+			err = s.dataset.saveIdempotentResponse(context.WithoutCancel(req.Context()), key, userID, rec)
+		}
+		if err != nil {
+			log.Printf("ERROR: %v (Idempotency-Key '%s')", err, key)
+		}
+
+		for name, values := range rec.header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rec.status)
+		w.Write(rec.body.Bytes())
+	}
+}
@@ -0,0 +1,83 @@
+// idempotency_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIdempotencyKeyRequiresUser(t *testing.T) {
+	module := testEmployeesModule()
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{module.ID: module}, Rules: NewRuleRegistry()}
+	s := NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/modules/test_employees", bytes.NewBufferString(`{"name": "Ana"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, "k1")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), idempotencyKeyHeader) {
+		t.Errorf("anonimni zahtev sa ključem: status %d, očekivano 400; telo %s", rec.Code, rec.Body)
+	}
+}
+
+func TestIdempotencyKeyReplaysResponse(t *testing.T) {
+	module := testEmployeesModule()
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, name TEXT NOT NULL, salary INT, version INT NOT NULL DEFAULT 1")
+	t.Cleanup(func() { ds.db.Exec("DELETE FROM idempotency_keys WHERE key LIKE 'test-%'") })
+	s := NewAPIServer(ds.config, ds)
+	key := map[string]string{idempotencyKeyHeader: "test-replay"}
+
+	first := serveTest(s, http.MethodPost, "/api/modules/test_employees", `{"name": "Ana"}`, "", key)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("prvi zahtev: status %d, telo %s", first.Code, first.Body)
+	}
+	second := serveTest(s, http.MethodPost, "/api/modules/test_employees", `{"name": "Ana"}`, "", key)
+	if second.Code != http.StatusCreated || second.Header().Get("Idempotent-Replayed") != "true" || second.Body.String() != first.Body.String() {
+		t.Errorf("ponovljen zahtev: status %d, zaglavlje %q, telo %s", second.Code, second.Header().Get("Idempotent-Replayed"), second.Body)
+	}
+	var count int
+	if err := ds.db.QueryRow("SELECT count(*) FROM test_employees").Scan(&count); err != nil || count != 1 {
+		t.Errorf("broj zapisa posle ponavljanja: %d, %v", count, err)
+	}
+
+	rec := serveTest(s, http.MethodPost, "/api/modules/test_employees", `{"name": "Marko"}`, "", key)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("isti ključ, drugo telo: status %d, očekivano 422", rec.Code)
+	}
+}
+
+func TestIdempotencyClaimIsKeptWhileRefreshed(t *testing.T) {
+	ds := newTestDataset(t)
+	ctx := context.Background()
+	const key, user = "test-heartbeat", "tester"
+	t.Cleanup(func() { ds.db.Exec("DELETE FROM idempotency_keys WHERE key = $1", key) })
+	ds.db.Exec("DELETE FROM idempotency_keys WHERE key = $1", key)
+
+	if claimed, _, err := ds.claimIdempotencyKey(ctx, key, user, "h1"); err != nil || !claimed {
+		t.Fatalf("prvo zauzimanje: %v, %v", claimed, err)
+	}
+	// Dug zahtev: ključ je zauzet davno, ali se osvežava, pa ga ponovljen zahtev ne sme preuzeti
+	if _, err := ds.db.Exec("UPDATE idempotency_keys SET created_at = now() - interval '1 hour', heartbeat_at = now() - interval '1 hour' WHERE key = $1", key); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.refreshIdempotencyKey(ctx, key, user); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, stored, err := ds.claimIdempotencyKey(ctx, key, user, "h1"); err != nil || claimed || stored.status.Valid {
+		t.Errorf("osvežen ključ je preuzet: %v, %+v, %v", claimed, stored, err)
+	}
+
+	// Napušten zahtev (bez osvežavanja) oslobađa ključ
+	if _, err := ds.db.Exec("UPDATE idempotency_keys SET heartbeat_at = now() - interval '1 hour' WHERE key = $1", key); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, _, err := ds.claimIdempotencyKey(ctx, key, user, "h1"); err != nil || !claimed {
+		t.Errorf("napušten ključ nije preuzet: %v, %v", claimed, err)
+	}
+}
@@ -0,0 +1,666 @@
+// import.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// Režimi uvoza: insert uvek kreira nove zapise, upsert menja postojeći zapis sa istim ključem.
+const (
+	ImportModeInsert = "insert"
+	ImportModeUpsert = "upsert"
+)
+
+const (
+	importMaxFileSize    = 32 << 20 // Maksimalna veličina datoteke za uvoz
+	importPreviewRows    = 20       // Broj redova koji se vraćaju u pregledu (dry run)
+	excelEpochOffsetDays = 25569    // Dani od 1899-12-30 (Excel) do 1970-01-01
+)
+
+// ImportOptions controls how a file is mapped and written to a module.
+type ImportOptions struct {
+	Mode       string            // ImportModeInsert ili ImportModeUpsert
+	KeyColumns []string          // Kolone po kojima upsert traži postojeći zapis (podrazumevano primarni ključ)
+	Mapping    map[string]string // Ručno mapiranje: zaglavlje -> db_column_name ("" = preskoči kolonu)
+	DryRun     bool
+}
+
+// ImportRowError describes why a single row of the file was not imported.
+type ImportRowError struct {
+	Row     int    `json:"row"` // Broj reda u datoteci (zaglavlje je red 1)
+	Field   string `json:"field,omitempty"`
+	Rule    string `json:"rule,omitempty"` // Pravilo validacije ili kod greške baze
+	Message string `json:"message"`
+}
+
+// ImportPreviewRow is a row as it would be written, shown in dry-run responses.
+type ImportPreviewRow struct {
+	Row    int                    `json:"row"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// ImportResult summarizes an import (or its dry run).
+type ImportResult struct {
+	DryRun         bool               `json:"dry_run"`
+	Mode           string             `json:"mode"`
+	Mapping        map[string]string  `json:"mapping"` // Zaglavlje -> db_column_name
+	IgnoredHeaders []string           `json:"ignored_headers,omitempty"`
+	TotalRows      int                `json:"total_rows"`
+	ValidRows      int                `json:"valid_rows"`
+	Inserted       int                `json:"inserted"`
+	Updated        int                `json:"updated"`
+	Errors         []ImportRowError   `json:"errors"`
+	Preview        []ImportPreviewRow `json:"preview,omitempty"`
+}
+
+// importRow je red pripremljen za upis.
+type importRow struct {
+	line    int
+	payload map[string]interface{}
+}
+
+// readImportFile čita CSV ili XLSX datoteku u redove tekstualnih ćelija.
+func readImportFile(data []byte, format string, delimiter rune, encoding string) ([][]string, error) {
+	switch format {
+	case "xlsx":
+		return readXLSXRows(bytes.NewReader(data), int64(len(data)))
+	case "csv":
+		var text string
+		switch encoding {
+		case "", "utf-8", "utf-8-bom":
+			text = strings.TrimPrefix(string(data), "\ufeff")
+		case "windows-1250", "cp1250":
+			text = decodeWindows1250(data)
+		default:
+			return nil, fmt.Errorf("nepodržana kodna strana '%s'", encoding)
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		r.Comma = delimiter
+		r.FieldsPerRecord = -1 // Redovi mogu imati različit broj ćelija
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("greška pri čitanju CSV datoteke: %w", err)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("nepodržan format uvoza '%s' (dozvoljeno: csv, xlsx)", format)
+	}
+}
+
+// decodeWindows1250 prekodira tekst iz Windows-1250 u UTF-8.
+func decodeWindows1250(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case windows1250High[c-0x80] != 0:
+			b.WriteRune(windows1250High[c-0x80])
+		default:
+			b.WriteRune(utf8.RuneError)
+		}
+	}
+	return b.String()
+}
+
+// importableColumn reports whether a column can receive values from an import file.
+// Ključne kolone za upsert se mapiraju i kada nisu editable (npr. primarni ključ).
+func importableColumn(colDef *ColumnDefinition, keyColumns []string) bool {
+	if colDef.IsAuto() || colDef.DBColumnName == "" {
+		return false
+	}
+	for _, key := range keyColumns {
+		if key == colDef.DBColumnName {
+			return true
+		}
+	}
+	return colDef.IsEditable && !colDef.IsReadOnly
+}
+
+// resolveImportMapping povezuje kolone datoteke sa kolonama modula: po ručnom mapiranju,
+// a inače po nazivu kolone (Name) ili db_column_name, bez obzira na velika/mala slova.
+func resolveImportMapping(moduleDef *ModuleDefinition, headers []string, opts ImportOptions) (map[int]*ColumnDefinition, map[string]string, []string, error) {
+	byIndex := make(map[int]*ColumnDefinition)
+	mapping := make(map[string]string)
+	ignored := make([]string, 0)
+	used := make(map[string]string) // db_column_name -> zaglavlje
+
+	for i, header := range headers {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+
+		var colDef *ColumnDefinition
+		if target, ok := opts.Mapping[header]; ok {
+			if target == "" {
+				ignored = append(ignored, header)
+				continue
+			}
+			colDef = getColumnByDBName(moduleDef.Columns, target)
+			if colDef == nil || !importableColumn(colDef, opts.KeyColumns) {
+				return nil, nil, nil, NewValidationError(target, fmt.Sprintf("kolona za zaglavlje '%s' ne postoji ili se ne može uvoziti", header))
+			}
+		} else {
+			for j := range moduleDef.Columns {
+				c := &moduleDef.Columns[j]
+				if importableColumn(c, opts.KeyColumns) && (strings.EqualFold(c.Name, header) || strings.EqualFold(c.DBColumnName, header)) {
+					colDef = c
+					break
+				}
+			}
+			if colDef == nil {
+				ignored = append(ignored, header)
+				continue
+			}
+		}
+
+		if other, dup := used[colDef.DBColumnName]; dup {
+			return nil, nil, nil, NewValidationError(colDef.DBColumnName, fmt.Sprintf("kolona je mapirana iz dva zaglavlja ('%s' i '%s')", other, header))
+		}
+		used[colDef.DBColumnName] = header
+		byIndex[i] = colDef
+		mapping[header] = colDef.DBColumnName
+	}
+
+	if len(byIndex) == 0 {
+		return nil, nil, nil, NewValidationError("", "nijedna kolona datoteke nije povezana sa kolonama modula")
+	}
+	for _, key := range opts.KeyColumns {
+		if _, ok := used[key]; !ok {
+			return nil, nil, nil, NewValidationError(key, "ključna kolona za upsert ne postoji u datoteci")
+		}
+	}
+	return byIndex, mapping, ignored, nil
+}
+
+// convertImportValue pretvara tekst ćelije u vrednost tipa kolone, u obliku koji
+// validatePayload očekuje (brojevi kao float64, kao iz JSON-a).
+func convertImportValue(colDef *ColumnDefinition, text string) (interface{}, error) {
+	switch colDef.Type {
+	case "integer", "float":
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			// Decimalni zarez (npr. "12,5")
+			if f, err = strconv.ParseFloat(strings.Replace(text, ",", ".", 1), 64); err != nil {
+				return nil, fmt.Errorf("'%s' nije broj", text)
+			}
+		}
+		return f, nil
+	case "boolean":
+		switch strings.ToLower(text) {
+		case "true", "1", "da", "yes", "t", "y":
+			return true, nil
+		case "false", "0", "ne", "no", "f", "n":
+			return false, nil
+		}
+		return nil, fmt.Errorf("'%s' nije logička vrednost", text)
+	default: // Datume iz XLSX ćelija formatiranih kao datum readXLSXRows već vraća kao tekst
+		return text, nil
+	}
+}
+
+// excelSerialToTime pretvara Excel serijski broj dana u vreme (UTC).
+func excelSerialToTime(serial float64) time.Time {
+	days, frac := math.Modf(serial)
+	t := time.Unix(int64(days-excelEpochOffsetDays)*86400, 0).UTC()
+	return t.Add(time.Duration(math.Round(frac*86400)) * time.Second)
+}
+
+// resolveImportLookups dohvata ID-eve lookup zapisa po prikaznoj vrednosti za sve vrednosti iz datoteke.
+// Vraća mapu prikazna vrednost -> ID; vrednosti koje odgovaraju više zapisa mapiraju se na nil.
+func (s *SQLDataset) resolveImportLookups(ctx context.Context, colDef *ColumnDefinition, values []string) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{})
+	if colDef.LookupModule == nil || len(values) == 0 {
+		return resolved, nil
+	}
+	lookupModule := colDef.LookupModule
+	lookupPKCol := s.getPrimaryKeyColumn(lookupModule)
+	if lookupPKCol == nil {
+		return nil, fmt.Errorf("lookup modul '%s' nema definisan primarni ključ", lookupModule.ID)
+	}
+	displayCol := lookupDisplayColumn(colDef, lookupPKCol)
+	if userLookupDisplayExpr(colDef, lookupPKCol, UserFromContext(ctx)) == lookupPKCol.DBColumnName {
+		displayCol = lookupPKCol.DBColumnName // Prikazna kolona je skrivena korisniku; vrednosti su ID-evi
+	}
+
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(fmt.Sprintf("%s::text = ANY($1)", displayCol), lookupModule, ScopeActive), lookupModule, []interface{}{pq.Array(values)})
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s, %s::text FROM %s WHERE %s",
+		lookupPKCol.DBColumnName, displayCol, lookupModule.DBTableName, where)
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri dohvatanju lookup vrednosti za kolonu '%s': %w", colDef.Name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id interface{}
+		var display string
+		if err := rows.Scan(&id, &display); err != nil {
+			return nil, fmt.Errorf("greška pri skeniranju lookup reda: %w", err)
+		}
+		if _, dup := resolved[display]; dup {
+			resolved[display] = nil // Dvosmisleno: više zapisa sa istom prikaznom vrednošću
+			continue
+		}
+		resolved[display] = normalizeDBValue(id)
+	}
+	return resolved, rows.Err()
+}
+
+// prepareImportRows pretvara redove datoteke u payload-e, razrešava lookup kolone i
+// validira svaki red. Vraća ispravne redove i greške po redovima.
+func (s *SQLDataset) prepareImportRows(ctx context.Context, moduleDef *ModuleDefinition, rows [][]string, columns map[int]*ColumnDefinition) ([]importRow, []ImportRowError, error) {
+	// Sve lookup vrednosti se razrešavaju jednim upitom po koloni
+	lookups := make(map[string]map[string]interface{})
+	for i, colDef := range columns {
+		if colDef.Type != "lookup" {
+			continue
+		}
+		distinct := make(map[string]struct{})
+		values := make([]string, 0)
+		for _, row := range rows[1:] {
+			if i < len(row) {
+				if v := strings.TrimSpace(row[i]); v != "" {
+					if _, seen := distinct[v]; !seen {
+						distinct[v] = struct{}{}
+						values = append(values, v)
+					}
+				}
+			}
+		}
+		resolved, err := s.resolveImportLookups(ctx, colDef, values)
+		if err != nil {
+			return nil, nil, err
+		}
+		lookups[colDef.DBColumnName] = resolved
+	}
+
+	valid := make([]importRow, 0, len(rows)-1)
+	rowErrors := make([]ImportRowError, 0)
+	for n, row := range rows[1:] {
+		line := n + 2 // Zaglavlje je red 1
+		if isBlankRow(row) {
+			continue
+		}
+
+		payload := make(map[string]interface{})
+		var rowErr *ImportRowError
+		for i, colDef := range columns {
+			if i >= len(row) {
+				continue
+			}
+			text := strings.TrimSpace(row[i])
+			if text == "" {
+				continue // Prazna ćelija: kolona se ne šalje (default vrednost ili bez izmene)
+			}
+			if colDef.Type == "lookup" {
+				id, found := lookups[colDef.DBColumnName][text]
+				switch {
+				case !found:
+					rowErr = &ImportRowError{Row: line, Field: colDef.DBColumnName, Message: fmt.Sprintf("vrednost '%s' nije pronađena u modulu '%s'", text, colDef.LookupModuleID)}
+				case id == nil:
+					rowErr = &ImportRowError{Row: line, Field: colDef.DBColumnName, Message: fmt.Sprintf("vrednost '%s' odgovara većem broju zapisa u modulu '%s'", text, colDef.LookupModuleID)}
+				default:
+					payload[colDef.DBColumnName] = id
+				}
+			} else {
+				val, err := convertImportValue(colDef, text)
+				if err != nil {
+					rowErr = &ImportRowError{Row: line, Field: colDef.DBColumnName, Message: err.Error()}
+				} else {
+					payload[colDef.DBColumnName] = val
+				}
+			}
+			if rowErr != nil {
+				break
+			}
+		}
+
+		if rowErr != nil {
+			rowErrors = append(rowErrors, *rowErr)
+			continue
+		}
+		// Pravila koja čitaju bazu se proveravaju u validateImportRow, kada je poznat postojeći zapis
+		if err := validatePayload(payload, moduleDef.Columns, s.config); err != nil {
+			rowErrors = append(rowErrors, importRowErrors(line, err)...)
+			continue
+		}
+		valid = append(valid, importRow{line: line, payload: payload})
+	}
+	return valid, rowErrors, nil
+}
+
+// importRowErrors pretvara grešku upisa ili validacije u greške reda (po jednu za svako polje).
+func importRowErrors(line int, err error) []ImportRowError {
+	var vErrs ValidationErrors
+	if errors.As(err, &vErrs) {
+		rowErrs := make([]ImportRowError, len(vErrs))
+		for i, vErr := range vErrs {
+			rowErrs[i] = ImportRowError{Row: line, Field: vErr.Field, Rule: vErr.Rule, Message: vErr.Message}
+		}
+		return rowErrs
+	}
+	var vErr *ValidationError
+	if errors.As(err, &vErr) {
+		return []ImportRowError{{Row: line, Field: vErr.Field, Rule: vErr.Rule, Message: vErr.Message}}
+	}
+	if body := databaseErrorBody(err); body != nil {
+		rowErr := ImportRowError{Row: line, Rule: body.Code, Message: body.Message}
+		if len(body.Fields) > 0 {
+			rowErr.Field = body.Fields[0].Field
+		}
+		return []ImportRowError{rowErr}
+	}
+	var notFound *RecordNotFoundError
+	if errors.As(err, &notFound) {
+		return []ImportRowError{{Row: line, Rule: ErrCodeNotFound, Message: notFound.Error()}}
+	}
+	// Ostale greške (npr. tekst greške baze) idu samo u log
+	log.Printf("ERROR: Greška pri uvozu reda %d: %v", line, err)
+	return []ImportRowError{{Row: line, Rule: ErrCodeInternal, Message: internalErrorMessage}}
+}
+
+// isBlankRow reports whether every cell of the row is empty.
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// findRecordByKey vraća ID aktivnog zapisa sa zadatim vrednostima ključnih kolona, ili nil.
+// Zapisi van row-level filtera korisnika se ne pronalaze.
+func (s *SQLDataset) findRecordByKey(ctx context.Context, q queryer, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, keyColumns []string, payload map[string]interface{}) (interface{}, error) {
+	conditions := make([]string, len(keyColumns))
+	args := make([]interface{}, len(keyColumns))
+	for i, key := range keyColumns {
+		val, ok := payload[key]
+		if !ok || val == nil {
+			return nil, NewValidationError(key, "ključna kolona za upsert nema vrednost")
+		}
+		conditions[i] = fmt.Sprintf("%s = $%d", key, i+1)
+		args[i] = val
+	}
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(strings.Join(conditions, " AND "), moduleDef, ScopeActive), moduleDef, args)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT 2", pkCol.DBColumnName, moduleDef.DBTableName, where)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri traženju zapisa po ključu u modulu '%s': %w", moduleDef.Name, err)
+	}
+	defer rows.Close()
+
+	ids := make([]interface{}, 0, 2)
+	for rows.Next() {
+		var id interface{}
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("greška pri skeniranju ID-a: %w", err)
+		}
+		ids = append(ids, normalizeDBValue(id))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	switch len(ids) {
+	case 0:
+		return nil, nil
+	case 1:
+		return ids[0], nil
+	default:
+		return nil, NewValidationError(strings.Join(keyColumns, ","), "ključ nije jedinstven: pronađeno je više zapisa")
+	}
+}
+
+// validateImportRow pronalazi zapis koji red ažurira (samo za upsert, po ključnim kolonama) i
+// validira red uključujući pravila koja čitaju bazu (unique, exists). Vraća ID postojećeg zapisa ili nil.
+func (s *SQLDataset) validateImportRow(ctx context.Context, q queryer, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, keyColumns []string, payload map[string]interface{}) (interface{}, error) {
+	var existingID interface{}
+	if len(keyColumns) > 0 {
+		id, err := s.findRecordByKey(ctx, q, moduleDef, pkCol, keyColumns, payload)
+		if err != nil {
+			return nil, err
+		}
+		existingID = id
+	}
+	target := validationTarget{Module: moduleDef}
+	if existingID != nil {
+		target.RecordID = fmt.Sprint(existingID)
+		target.Partial = true // Prazne ćelije ne menjaju postojeće vrednosti
+	}
+	if err := s.validateRecord(ctx, target, payload); err != nil {
+		return nil, err
+	}
+	return existingID, nil
+}
+
+// ImportRecords maps, validates and (unless DryRun) writes the rows of an import file.
+// Ispravni redovi se upisuju u jednoj transakciji; red koji ne uspe pri upisu poništava
+// se preko savepoint-a i prijavljuje kao greška, a ostali redovi se i dalje upisuju.
+func (s *SQLDataset) ImportRecords(ctx context.Context, moduleDef *ModuleDefinition, rows [][]string, opts ImportOptions) (*ImportResult, error) {
+	if moduleDef.Type != "table" {
+		return nil, NewValidationError("", fmt.Sprintf("uvoz nije podržan za modul tipa '%s'", moduleDef.Type))
+	}
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
+	}
+	if len(rows) == 0 {
+		return nil, NewValidationError("", "datoteka je prazna")
+	}
+	switch {
+	case opts.Mode != ImportModeUpsert:
+		opts.KeyColumns = nil // Ključ ima smisla samo za upsert
+	case len(opts.KeyColumns) == 0:
+		opts.KeyColumns = []string{pkCol.DBColumnName}
+	}
+
+	columns, mapping, ignored, err := resolveImportMapping(moduleDef, rows[0], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, rowErrors, err := s.prepareImportRows(ctx, moduleDef, rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidRows := make(map[int]bool) // Red može imati više grešaka (po jednu za svako polje)
+	for _, rowErr := range rowErrors {
+		invalidRows[rowErr.Row] = true
+	}
+
+	result := &ImportResult{
+		DryRun:         opts.DryRun,
+		Mode:           opts.Mode,
+		Mapping:        mapping,
+		IgnoredHeaders: ignored,
+		TotalRows:      len(valid) + len(invalidRows),
+		ValidRows:      len(valid),
+		Errors:         rowErrors,
+	}
+
+	if opts.DryRun {
+		for _, row := range valid {
+			if _, err := s.validateImportRow(ctx, s.conn(ctx), moduleDef, pkCol, opts.KeyColumns, row.payload); err != nil {
+				result.Errors = append(result.Errors, importRowErrors(row.line, err)...)
+				result.ValidRows--
+				continue
+			}
+			if len(result.Preview) < importPreviewRows {
+				result.Preview = append(result.Preview, ImportPreviewRow{Row: row.line, Values: row.payload})
+			}
+		}
+		return result, nil
+	}
+
+	err = s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, row := range valid {
+			updated := false
+			err := withSavepoint(ctx, tx, func() error {
+				existingID, err := s.validateImportRow(ctx, tx, moduleDef, pkCol, opts.KeyColumns, row.payload)
+				if err != nil {
+					return err
+				}
+				if existingID != nil {
+					updated = true
+					_, err := s.updateRecordTx(ctx, tx, moduleDef, pkCol, fmt.Sprint(existingID), row.payload, "")
+					return err
+				}
+				_, err = s.createRecordTx(ctx, tx, moduleDef, row.payload)
+				return err
+			})
+			if err != nil {
+				result.Errors = append(result.Errors, importRowErrors(row.line, err)...)
+				result.ValidRows--
+				continue
+			}
+			if updated {
+				result.Updated++
+			} else {
+				result.Inserted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("INFO: Uvoz u modul '%s': %d kreirano, %d ažurirano, %d grešaka.", moduleDef.ID, result.Inserted, result.Updated, len(result.Errors))
+	return result, nil
+}
+
+// ImportModuleRecords handles POST /api/modules/{moduleID}/import (multipart/form-data).
+// Polja forme: file (CSV ili XLSX), format, mode (insert|upsert), key, mapping (JSON),
+// dry_run, delimiter i encoding (za CSV).
+func (s *APIServer) ImportModuleRecords(w http.ResponseWriter, req *http.Request) {
+	moduleID := mux.Vars(req)["moduleID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+
+	// Svaki uvoz kreira zapise; dozvola se proverava pre čitanja datoteke
+	if !s.authorize(w, req, moduleDef, PermCreate) {
+		return
+	}
+	if moduleDef.Type != "table" {
+		writeError(w, fmt.Sprintf("Uvoz nije podržan za modul tipa '%s'.", moduleDef.Type), http.StatusBadRequest)
+		return
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, importMaxFileSize)
+	if err := req.ParseMultipartForm(importMaxFileSize); err != nil {
+		writeError(w, fmt.Sprintf("Greška pri čitanju multipart forme: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	opts := ImportOptions{
+		Mode:   strings.ToLower(req.FormValue("mode")),
+		DryRun: flagParam(req.Form, "dry_run"),
+	}
+	switch opts.Mode {
+	case "", ImportModeInsert:
+		opts.Mode = ImportModeInsert
+	case ImportModeUpsert:
+		if !s.authorize(w, req, moduleDef, PermUpdate) {
+			return
+		}
+	default:
+		writeError(w, fmt.Sprintf("Nepodržan režim uvoza '%s' (dozvoljeno: insert, upsert).", opts.Mode), http.StatusBadRequest)
+		return
+	}
+
+	// Učitavanje i upis velike datoteke traju duže od timeout-a servera
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("WARNING: Nije moguće ukloniti read deadline za uvoz: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("WARNING: Nije moguće ukloniti write deadline za uvoz: %v", err)
+	}
+
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		writeError(w, fmt.Sprintf("Nedostaje datoteka za uvoz (polje 'file'): %v", err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Greška pri čitanju datoteke: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if key := req.FormValue("key"); key != "" {
+		for _, col := range strings.Split(key, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				opts.KeyColumns = append(opts.KeyColumns, col)
+			}
+		}
+	}
+	if m := req.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &opts.Mapping); err != nil {
+			writeError(w, fmt.Sprintf("Nevažeće mapiranje kolona (očekuje se JSON objekat zaglavlje -> kolona): %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := strings.ToLower(req.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	delimiter, err := parseCSVDelimiter(req.FormValue("delimiter"))
+	if err != nil {
+		writeError(w, fmt.Sprintf("Greška u parametru delimiter: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := readImportFile(data, format, delimiter, strings.ToLower(req.FormValue("encoding")))
+	if err != nil {
+		writeError(w, fmt.Sprintf("Greška pri čitanju datoteke '%s': %v", header.Filename, err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.dataset.ImportRecords(req.Context(), moduleDef, rows, opts)
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri uvozu u modul '%s'", moduleID), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju rezultata uvoza: %v", err)
+	}
+}
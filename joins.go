@@ -0,0 +1,157 @@
+// joins.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// lookupJoin je LEFT JOIN ka tabeli lookup modula za jednu putanju (npr. "customer_id").
+// Tabela se spaja kao podupit sa soft delete i row-level filterom lookup modula, a kolone
+// podupita dobijaju prefiks aliasa (lk1_username), da se ne bi sudarale sa kolonama glavne tabele.
+type lookupJoin struct {
+	alias   string
+	module  *ModuleDefinition
+	pkCol   string
+	on      string   // Izraz kolone sa ID-em lookup zapisa, npr. orders.customer_id
+	columns []string // Kolone lookup modula koje upit koristi
+}
+
+// column registruje kolonu lookup modula u podupitu i vraća izraz kojim joj se pristupa.
+func (j *lookupJoin) column(name string) string {
+	if name != j.pkCol && !slices.Contains(j.columns, name) {
+		j.columns = append(j.columns, name)
+	}
+	return fmt.Sprintf("%s.%s_%s", j.alias, j.alias, name)
+}
+
+// queryJoins skuplja JOIN-ove za putanje kroz lookup kolone u filterima, sortiranju i pretrazi.
+type queryJoins struct {
+	user       *User
+	allowPaths bool // Da li su dozvoljene putanje kroz lookup kolone
+	joins      []*lookupJoin
+	byPath     map[string]*lookupJoin
+	denied     []string // Kolone i putanje koje korisnik ne sme da čita
+}
+
+// newQueryJoins vraća prazan skup JOIN-ova; kolone i putanje se proveravaju prema pravima korisnika.
+// Bez allowPaths podržane su samo kolone samog modula (npr. za module sa select_query).
+func newQueryJoins(user *User, allowPaths bool) *queryJoins {
+	return &queryJoins{user: user, allowPaths: allowPaths, byPath: make(map[string]*lookupJoin)}
+}
+
+// deny beleži kolonu ili putanju koju korisnik ne sme da čita.
+func (j *queryJoins) deny(path string) {
+	if j != nil {
+		j.denied = append(j.denied, path)
+	}
+}
+
+// deniedError vraća grešku validacije za svaku kolonu upita koju korisnik ne sme da čita,
+// da filter, sortiranje ili pretraga ne bi otkrivali vrednosti skrivenih kolona.
+func (j *queryJoins) deniedError() error {
+	if j == nil || len(j.denied) == 0 {
+		return nil
+	}
+	errs := make(ValidationErrors, len(j.denied))
+	for i, path := range j.denied {
+		errs[i] = NewValidationError(path, fmt.Sprintf("kolona '%s' nije dostupna za filtriranje, sortiranje ni pretragu", path))
+	}
+	return errs
+}
+
+// reader vraća korisnika čija prava važe za kolone upita; bez joins (nil) to je anonimni korisnik.
+func (j *queryJoins) reader() *User {
+	if j == nil {
+		return nil
+	}
+	return j.user
+}
+
+// resolveColumn vraća definiciju i SQL izraz kolone za ime ili putanju kroz lookup kolone
+// (npr. "customer_id.username"). Bez joins (nil) podržane su samo kolone samog modula.
+// Za nevažeću putanju ili kolonu koju korisnik ne sme da čita vraća nil; nedostupne
+// kolone se beleže u joins (deniedError).
+func (s *SQLDataset) resolveColumn(moduleDef *ModuleDefinition, joins *queryJoins, path string) (*ColumnDefinition, string) {
+	segments := strings.Split(path, ".")
+	if len(segments) == 1 {
+		colDef := getColumnByDBName(moduleDef.Columns, path)
+		if colDef == nil {
+			return nil, ""
+		}
+		if !colDef.UserCanRead(joins.reader()) {
+			joins.deny(path)
+			return nil, ""
+		}
+		return colDef, colDef.DBColumnName
+	}
+	if joins == nil || !joins.allowPaths || len(segments)-1 > lookupExpandMaxDepth {
+		return nil, ""
+	}
+
+	// Prvo se proveri cela putanja, da nevažeća putanja ne ostavi JOIN u upitu
+	colDefs := make([]*ColumnDefinition, len(segments))
+	module := moduleDef
+	for i, segment := range segments {
+		colDef := getColumnByDBName(module.Columns, segment)
+		if colDef == nil {
+			return nil, ""
+		}
+		if !colDef.UserCanRead(joins.user) {
+			joins.deny(path)
+			return nil, ""
+		}
+		colDefs[i] = colDef
+		if i == len(segments)-1 {
+			break
+		}
+		if colDef.Type != "lookup" || colDef.LookupModule == nil || colDef.LookupModule.DBTableName == "" ||
+			s.getPrimaryKeyColumn(colDef.LookupModule) == nil {
+			return nil, ""
+		}
+		if !colDef.LookupModule.UserCan(joins.user, PermRead) {
+			joins.deny(path)
+			return nil, ""
+		}
+		module = colDef.LookupModule
+	}
+
+	expr := moduleDef.DBTableName + "." + segments[0]
+	for i, colDef := range colDefs[:len(colDefs)-1] {
+		prefix := strings.Join(segments[:i+1], ".")
+		join := joins.byPath[prefix]
+		if join == nil {
+			join = &lookupJoin{
+				alias:  fmt.Sprintf("lk%d", len(joins.joins)+1),
+				module: colDef.LookupModule,
+				pkCol:  s.getPrimaryKeyColumn(colDef.LookupModule).DBColumnName,
+				on:     expr,
+			}
+			joins.joins = append(joins.joins, join)
+			joins.byPath[prefix] = join
+		}
+		expr = join.column(segments[i+1])
+	}
+	return colDefs[len(colDefs)-1], expr
+}
+
+// joinClauses vraća LEFT JOIN klauzule upita; parametri row-level filtera lookup modula dodaju se u args.
+func (s *SQLDataset) joinClauses(ctx context.Context, joins *queryJoins, args []interface{}) (string, []interface{}, error) {
+	var clauses []string
+	for _, join := range joins.joins {
+		selectCols := []string{fmt.Sprintf("%s AS %s_%s", join.pkCol, join.alias, join.pkCol)}
+		for _, col := range join.columns {
+			selectCols = append(selectCols, fmt.Sprintf("%s AS %s_%s", col, join.alias, col))
+		}
+		where, joinArgs, err := s.andRowFilter(ctx, andSoftDeleteCondition("TRUE", join.module, ScopeActive), join.module, args)
+		if err != nil {
+			return "", nil, err
+		}
+		args = joinArgs
+		clauses = append(clauses, fmt.Sprintf("LEFT JOIN (SELECT %s FROM %s WHERE %s) AS %s ON %s.%s_%s = %s",
+			strings.Join(selectCols, ", "), join.module.DBTableName, where, join.alias, join.alias, join.alias, join.pkCol, join.on))
+	}
+	return strings.Join(clauses, " "), args, nil
+}
@@ -0,0 +1,108 @@
+// joins_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// employeesModule je modul sa skrivenom, ograničenom i numeričkom kolonom.
+func employeesModule() *ModuleDefinition {
+	return &ModuleDefinition{ID: "employees", DBTableName: "employees", Columns: []ColumnDefinition{
+		{DBColumnName: "id", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+		{DBColumnName: "name", Type: "string", IsVisible: true},
+		{DBColumnName: "note", Type: "string", IsVisible: true, ReadRoles: []string{"hr"}},
+		{DBColumnName: "token", Type: "string"},
+		{DBColumnName: "age", Type: "integer", IsVisible: true},
+	}}
+}
+
+func TestResolveColumnChecksReadRoles(t *testing.T) {
+	s := &SQLDataset{}
+	module := employeesModule()
+	tests := []struct {
+		user    *User
+		noJoins bool
+		want    bool
+	}{
+		{&User{ID: "ops", Roles: []string{"ops"}}, false, false},
+		{&User{ID: "hr", Roles: []string{"hr"}}, false, true},
+		{nil, false, false},
+		{nil, true, false},
+	}
+	for _, tt := range tests {
+		joins := newQueryJoins(tt.user, true)
+		if tt.noJoins {
+			joins = nil
+		}
+		if colDef, _ := s.resolveColumn(module, joins, "note"); (colDef != nil) != tt.want {
+			t.Errorf("resolveColumn(note) za korisnika %v: pronađena = %v, očekivano %v", tt.user, colDef != nil, tt.want)
+		}
+		if colDef, _ := s.resolveColumn(module, joins, "name"); colDef == nil {
+			t.Errorf("resolveColumn(name) za korisnika %v: kolona bez ograničenja mora biti dostupna", tt.user)
+		}
+	}
+}
+
+func TestSearchUsesOnlyReadableVisibleStringColumns(t *testing.T) {
+	s := &SQLDataset{}
+	module := employeesModule()
+	joins := newQueryJoins(&User{ID: "ops", Roles: []string{"ops"}}, true)
+
+	var where []string
+	var args []interface{}
+	counter := 1
+	s.addSearchCondition(module, joins, "ana", "note,token,age,name", &where, &args, &counter)
+	if len(where) != 1 || where[0] != "(name ILIKE $1)" {
+		t.Errorf("_search_fields: uslov = %v, očekivano samo name", where)
+	}
+
+	where, args, counter = nil, nil, 1
+	s.addSearchCondition(module, joins, "ana", "note,token,age", &where, &args, &counter)
+	if len(where) != 0 {
+		t.Errorf("_search_fields bez pretraživih kolona: uslov = %v, očekivano bez uslova", where)
+	}
+
+	where, args, counter = nil, nil, 1
+	s.addSearchCondition(module, newQueryJoins(&User{ID: "hr", Roles: []string{"hr"}}, true), "ana", "", &where, &args, &counter)
+	if len(where) != 1 || !strings.Contains(where[0], "note ILIKE") || strings.Contains(where[0], "token") {
+		t.Errorf("podrazumevana pretraga za hr: uslov = %v", where)
+	}
+}
+
+func TestSelectQueryRejectsUnreadableColumns(t *testing.T) {
+	s := &SQLDataset{Hooks: NewHookRegistry()}
+	module := employeesModule()
+	ops := WithUser(context.Background(), &User{ID: "ops", Roles: []string{"ops"}})
+	hr := WithUser(context.Background(), &User{ID: "hr", Roles: []string{"hr"}})
+
+	for _, query := range []string{"note=x", "note__ilike=x", "_sort=-note", "_search=x&_search_fields=note"} {
+		params, _ := url.ParseQuery(query)
+		_, _, err := s.buildSelectQuery(ops, module, params)
+		var validationErrs ValidationErrors
+		if !errors.As(err, &validationErrs) || validationErrs[0].Field != "note" {
+			t.Errorf("?%s bez uloge hr: greška = %v, očekivana greška validacije za note", query, err)
+		}
+		if _, _, err := s.buildSelectQuery(hr, module, params); err != nil {
+			t.Errorf("?%s sa ulogom hr: neočekivana greška %v", query, err)
+		}
+	}
+	// Nepoznata kolona se i dalje samo preskače
+	if _, _, err := s.buildSelectQuery(ops, module, url.Values{"missing": {"1"}}); err != nil {
+		t.Errorf("nepoznata kolona: neočekivana greška %v", err)
+	}
+}
+
+func TestHideUnreadableChanges(t *testing.T) {
+	entries := []AuditEntry{{Changes: map[string]FieldChange{"name": {New: "Ana"}, "note": {New: "tajna"}}}}
+	hideUnreadableChanges(employeesModule(), &User{ID: "ops"}, entries)
+	if _, ok := entries[0].Changes["note"]; ok {
+		t.Errorf("izmene sadrže skrivenu kolonu: %v", entries[0].Changes)
+	}
+	if _, ok := entries[0].Changes["name"]; !ok {
+		t.Errorf("izmene ne sadrže vidljivu kolonu: %v", entries[0].Changes)
+	}
+}
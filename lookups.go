@@ -0,0 +1,444 @@
+// lookups.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	lookupSearchDefaultLimit = 20
+	lookupSearchMaxLimit     = 100
+	lookupExpandMaxDepth     = 3          // Najviše nivoa u _expand putanji (npr. product_id.category_id.parent_id)
+	lookupDisplayAlias       = "_display" // Alias izraza prikazne vrednosti u upitima lookup-a
+)
+
+// lookupTemplateField prepoznaje polja u šablonu prikaza, npr. "{username} ({email})".
+var lookupTemplateField = regexp.MustCompile(`\{(\w+)\}`)
+
+// LookupExpand is a tree of lookup columns expanded in depth: column -> lookups of its lookup module.
+type LookupExpand map[string]LookupExpand
+
+// LookupOption is one choice of a lookup dropdown, in the same shape as an expanded lookup value.
+type LookupOption struct {
+	ID   interface{} `json:"id"`
+	Name interface{} `json:"name"`
+}
+
+// LookupSearchResult is one page of lookup options.
+type LookupSearchResult struct {
+	Items   []LookupOption `json:"items"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+	HasMore bool           `json:"has_more"` // Postoji sledeća strana
+}
+
+// LookupSearch describes a search over the lookup module of a column.
+type LookupSearch struct {
+	Query   string                 // Tekst koji se traži u prikaznoj koloni (podstring, bez obzira na velika/mala slova)
+	Filters map[string]interface{} // Kolona lookup modula -> vrednost (zavisni filteri iz forme)
+	Limit   int
+	Offset  int
+}
+
+// SearchLookup vraća opcije lookup kolone sortirane po prikaznoj vrednosti. Važe soft delete
+// i row-level filter lookup modula, kao i za proveru reference pri upisu.
+func (s *SQLDataset) SearchLookup(ctx context.Context, colDef *ColumnDefinition, search LookupSearch) (*LookupSearchResult, error) {
+	lookupModule := colDef.LookupModule
+	pkCol := s.getPrimaryKeyColumn(lookupModule)
+	if pkCol == nil {
+		return nil, fmt.Errorf("lookup modul '%s' nema definisan primarni ključ", lookupModule.ID)
+	}
+	displayCol := userLookupDisplayExpr(colDef, pkCol, UserFromContext(ctx))
+
+	var conditions []string
+	var args []interface{}
+	if search.Query != "" {
+		args = append(args, "%"+escapeLike(search.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("%s::text ILIKE $%d", displayCol, len(args)))
+	}
+	filterCols := make([]string, 0, len(search.Filters))
+	for col := range search.Filters {
+		filterCols = append(filterCols, col)
+	}
+	sort.Strings(filterCols) // Stabilan redosled parametara
+	for _, col := range filterCols {
+		if search.Filters[col] == nil {
+			conditions = append(conditions, fmt.Sprintf("%s IS NULL", col))
+			continue
+		}
+		args = append(args, search.Filters[col])
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", col, len(args)))
+	}
+	where := "TRUE"
+	if len(conditions) > 0 {
+		where = strings.Join(conditions, " AND ")
+	}
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(where, lookupModule, ScopeActive), lookupModule, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Jedan red više od limita otkriva da li postoji sledeća strana
+	orderBy := displayCol + ", " + pkCol.DBColumnName
+	if displayCol == pkCol.DBColumnName {
+		orderBy = pkCol.DBColumnName
+	}
+	args = append(args, search.Limit+1, search.Offset)
+	query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d",
+		pkCol.DBColumnName, displayCol, lookupModule.DBTableName, where, orderBy, len(args)-1, len(args))
+	log.Printf("DEBUG: Pretraga lookup-a '%s': %s sa parametrima: %v", colDef.DBColumnName, query, args)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri pretrazi lookup modula '%s': %w", lookupModule.ID, err)
+	}
+	defer rows.Close()
+
+	result := &LookupSearchResult{Items: []LookupOption{}, Limit: search.Limit, Offset: search.Offset}
+	for rows.Next() {
+		var id, name interface{}
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("greška pri čitanju lookup opcije: %w", err)
+		}
+		if b, ok := name.([]byte); ok {
+			name = string(b)
+		}
+		if len(result.Items) == search.Limit {
+			result.HasMore = true
+			break
+		}
+		result.Items = append(result.Items, LookupOption{ID: id, Name: name})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("greška nakon iteracije lookup opcija: %w", err)
+	}
+	return result, nil
+}
+
+// lookupTemplateFields vraća kolone lookup modula navedene u šablonu prikaza.
+func lookupTemplateFields(template string) []string {
+	var fields []string
+	for _, m := range lookupTemplateField.FindAllStringSubmatch(template, -1) {
+		fields = append(fields, m[1])
+	}
+	return fields
+}
+
+// userLookupDisplayExpr vraća izraz prikazne vrednosti lookup-a za korisnika. Ako prikaz koristi
+// kolonu lookup modula koju korisnik ne sme da vidi (read_roles), prikazuje se primarni ključ,
+// da se skrivena vrednost ne bi videla niti pretraživala preko lookup-a.
+func userLookupDisplayExpr(colDef *ColumnDefinition, pkCol *ColumnDefinition, user *User) string {
+	displayCols := lookupTemplateFields(colDef.LookupDisplayTemplate)
+	if colDef.LookupDisplayTemplate == "" {
+		displayCols = []string{lookupDisplayColumn(colDef, pkCol)}
+	}
+	for _, col := range displayCols {
+		if lookupCol := getColumnByDBName(colDef.LookupModule.Columns, col); lookupCol != nil && !lookupCol.UserCanRead(user) {
+			return pkCol.DBColumnName
+		}
+	}
+	return lookupDisplayExpr(colDef, pkCol)
+}
+
+// lookupDisplayExpr vraća SQL izraz prikazne vrednosti lookup-a: šablon LookupDisplayTemplate
+// preveden u CONCAT (NULL polje daje prazan tekst), ili kolonu iz lookupDisplayColumn.
+func lookupDisplayExpr(colDef *ColumnDefinition, pkCol *ColumnDefinition) string {
+	template := colDef.LookupDisplayTemplate
+	if template == "" {
+		return lookupDisplayColumn(colDef, pkCol)
+	}
+	var parts []string
+	last := 0
+	for _, m := range lookupTemplateField.FindAllStringSubmatchIndex(template, -1) {
+		if m[0] > last {
+			parts = append(parts, sqlStringLiteral(template[last:m[0]]))
+		}
+		parts = append(parts, template[m[2]:m[3]]+"::text")
+		last = m[1]
+	}
+	if last < len(template) {
+		parts = append(parts, sqlStringLiteral(template[last:]))
+	}
+	return "CONCAT(" + strings.Join(parts, ", ") + ")"
+}
+
+// sqlStringLiteral vraća tekst kao SQL string literal (za delove šablona iz definicije modula).
+func sqlStringLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// expandLookups proširuje lookup kolone modula u zapisima; sa all=false samo kolone iz expand.
+func (s *SQLDataset) expandLookups(ctx context.Context, records []map[string]interface{}, module *ModuleDefinition, expand LookupExpand, all bool) error {
+	for i := range module.Columns {
+		colDef := &module.Columns[i]
+		if colDef.Type != "lookup" || colDef.LookupModule == nil {
+			continue
+		}
+		nested, requested := expand[colDef.DBColumnName]
+		if !all && !requested {
+			continue
+		}
+		if err := s.expandLookupColumn(ctx, records, colDef, nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandLookupColumn zamenjuje ID-eve jedne lookup kolone objektima {"id", "name", LookupFields...};
+// kolone iz nested se čitaju i rekurzivno proširuju unutar objekta.
+func (s *SQLDataset) expandLookupColumn(ctx context.Context, records []map[string]interface{}, colDef *ColumnDefinition, nested LookupExpand) error {
+	lookupModule := colDef.LookupModule
+	pkCol := s.getPrimaryKeyColumn(lookupModule)
+	if pkCol == nil {
+		log.Printf("WARNING: Lookup modul '%s' za kolonu '%s' nema definisan primarni ključ, preskačem proširenje", lookupModule.ID, colDef.Name)
+		return nil
+	}
+
+	// Sakupi sve jedinstvene lookup ID-eve iz zapisa
+	var args []interface{}
+	var placeholders []string
+	seen := make(map[interface{}]bool)
+	for _, record := range records {
+		if id := record[colDef.DBColumnName]; id != nil && !seen[id] {
+			seen[id] = true
+			args = append(args, id)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	// Dodatna polja objekta: LookupFields koje korisnik sme da vidi i kolone koje se proširuju dalje
+	// (njihovu vidljivost je proverio parseLookupExpand). Bez dozvole za čitanje lookup modula
+	// objekat ima samo ID i prikazni naziv.
+	user := UserFromContext(ctx)
+	canReadModule := lookupModule.UserCan(user, PermRead)
+	fields := make([]string, 0, len(colDef.LookupFields)+len(nested))
+	for _, field := range colDef.LookupFields {
+		if lookupCol := getColumnByDBName(lookupModule.Columns, field); canReadModule && lookupCol != nil && lookupCol.UserCanRead(user) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range nested {
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	selectCols := append([]string{pkCol.DBColumnName, userLookupDisplayExpr(colDef, pkCol, user) + " AS " + lookupDisplayAlias}, fields...)
+	// Zapisi lookup modula van row-level filtera korisnika se ne prikazuju (kao da su obrisani)
+	where, args, err := s.andRowFilter(ctx, andSoftDeleteCondition(fmt.Sprintf("%s IN (%s)", pkCol.DBColumnName, strings.Join(placeholders, ", ")), lookupModule, ScopeActive), lookupModule, args)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		strings.Join(selectCols, ", "),
+		lookupModule.DBTableName,
+		where,
+	)
+
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("greška pri dohvatanju lookup podataka za kolonu '%s': %w", colDef.Name, err)
+	}
+	lookupRecords, err := scanRecords(rows)
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("greška pri čitanju lookup podataka za kolonu '%s': %w", colDef.Name, err)
+	}
+
+	objects := make([]map[string]interface{}, 0, len(lookupRecords))
+	byID := make(map[interface{}]map[string]interface{}, len(lookupRecords))
+	for _, lookupRecord := range lookupRecords {
+		object := make(map[string]interface{}, len(fields)+2)
+		for _, field := range fields {
+			object[field] = lookupRecord[field]
+		}
+		object["id"] = lookupRecord[pkCol.DBColumnName]
+		object["name"] = lookupRecord[lookupDisplayAlias]
+		objects = append(objects, object)
+		byID[object["id"]] = object
+	}
+	if len(nested) > 0 {
+		if err := s.expandLookups(ctx, objects, lookupModule, nested, false); err != nil {
+			return err
+		}
+	}
+
+	// Ažuriraj zapise; svaki dobija svoju kopiju objekta
+	for _, record := range records {
+		if id := record[colDef.DBColumnName]; id != nil {
+			if object, found := byID[id]; found {
+				record[colDef.DBColumnName] = maps.Clone(object)
+			} else {
+				record[colDef.DBColumnName] = nil
+			}
+		}
+	}
+	return nil
+}
+
+type lookupExpandKey struct{}
+
+// lookupExpansion vezuje _expand putanje za modul zahteva, da se ne primene na submodule.
+type lookupExpansion struct {
+	module *ModuleDefinition
+	paths  LookupExpand
+}
+
+// withLookupExpand vraća kontekst sa _expand putanjama za čitanje zapisa modula.
+func withLookupExpand(ctx context.Context, moduleDef *ModuleDefinition, paths LookupExpand) context.Context {
+	if len(paths) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, lookupExpandKey{}, lookupExpansion{module: moduleDef, paths: paths})
+}
+
+// lookupExpandFromContext vraća _expand putanje iz konteksta ako se odnose na dati modul.
+func lookupExpandFromContext(ctx context.Context, moduleDef *ModuleDefinition) LookupExpand {
+	if expansion, ok := ctx.Value(lookupExpandKey{}).(lookupExpansion); ok && expansion.module == moduleDef {
+		return expansion.paths
+	}
+	return nil
+}
+
+// parseLookupExpand parsira _expand parametar (npr. "product_id.category_id,customer_id") u stablo.
+// Svaki deo putanje mora biti lookup kolona koju korisnik sme da vidi, a od drugog nivoa i
+// modul mora biti dostupan za čitanje.
+func parseLookupExpand(moduleDef *ModuleDefinition, user *User, value string) (LookupExpand, error) {
+	expand := LookupExpand{}
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		segments := strings.Split(path, ".")
+		if len(segments) > lookupExpandMaxDepth {
+			return nil, fmt.Errorf("putanja '%s' ima više od %d nivoa", path, lookupExpandMaxDepth)
+		}
+		module, node := moduleDef, expand
+		for _, segment := range segments {
+			colDef := getColumnByDBName(module.Columns, segment)
+			if colDef == nil || colDef.Type != "lookup" || colDef.LookupModule == nil || !colDef.UserCanRead(user) {
+				return nil, fmt.Errorf("'%s' u putanji '%s' nije lookup kolona modula '%s'", segment, path, module.ID)
+			}
+			if module != moduleDef && !module.UserCan(user, PermRead) {
+				return nil, fmt.Errorf("nema prava čitanja modula '%s' iz putanje '%s'", module.ID, path)
+			}
+			if node[segment] == nil {
+				node[segment] = LookupExpand{}
+			}
+			node, module = node[segment], colDef.LookupModule
+		}
+	}
+	return expand, nil
+}
+
+// expandContext dodaje _expand putanje zahteva u kontekst; pri nevažećoj putanji upisuje 400 i vraća false.
+func expandContext(w http.ResponseWriter, req *http.Request, moduleDef *ModuleDefinition) (context.Context, bool) {
+	ctx := req.Context()
+	value := req.URL.Query().Get("_expand")
+	if value == "" {
+		return ctx, true
+	}
+	paths, err := parseLookupExpand(moduleDef, UserFromContext(ctx), value)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Nevažeća vrednost za _expand: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return withLookupExpand(ctx, moduleDef, paths), true
+}
+
+// escapeLike escapuje džoker znakove LIKE obrasca, da bi se tekst pretrage tražio doslovno.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SearchLookupOptions handles GET /api/modules/{moduleID}/lookups/{column}?q=...&limit=...&offset=...
+// za padajuće liste sa pretragom. Zavisni filteri se šalju kao query parametri sa imenima
+// polja forme (vidi ColumnDefinition.LookupFilters); pristup određuje pravo čitanja lookup modula
+// i izvorne kolone (read_roles).
+func (s *APIServer) SearchLookupOptions(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	moduleID := vars["moduleID"]
+	column := vars["column"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	// Kolona koju korisnik ne sme da vidi (read_roles) se prijavljuje kao nepostojeća
+	colDef := getColumnByDBName(moduleDef.Columns, column)
+	if colDef == nil || colDef.Type != "lookup" || colDef.LookupModule == nil || !colDef.UserCanRead(UserFromContext(req.Context())) {
+		writeError(w, fmt.Sprintf("Modul '%s' nema lookup kolonu '%s'.", moduleID, column), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, colDef.LookupModule, PermRead) {
+		return
+	}
+
+	query := req.URL.Query()
+	search := LookupSearch{Query: strings.TrimSpace(query.Get("q")), Limit: lookupSearchDefaultLimit, Filters: map[string]interface{}{}}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeError(w, fmt.Sprintf("Nevažeća vrednost za limit: '%s'.", v), http.StatusBadRequest)
+			return
+		}
+		search.Limit = min(limit, lookupSearchMaxLimit)
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			writeError(w, fmt.Sprintf("Nevažeća vrednost za offset: '%s'.", v), http.StatusBadRequest)
+			return
+		}
+		search.Offset = offset
+	}
+	for lookupCol, formField := range colDef.LookupFilters {
+		values, sent := query[formField]
+		if !sent {
+			continue // Polje forme još nije popunjeno; filter se ne primenjuje
+		}
+		if values[0] == "" {
+			search.Filters[lookupCol] = nil
+			continue
+		}
+		value, err := convertValueToColumnType(values[0], getColumnByDBName(colDef.LookupModule.Columns, lookupCol).Type)
+		if err != nil {
+			writeErrorBody(w, &ErrorBody{
+				Code:    ErrCodeValidation,
+				Message: fmt.Sprintf("Nevažeća vrednost polja '%s': %v", formField, err),
+				Status:  http.StatusBadRequest,
+				Fields:  ValidationErrors{NewValidationError(formField, "nevažeća vrednost")},
+			})
+			return
+		}
+		search.Filters[lookupCol] = value
+	}
+
+	result, err := s.dataset.SearchLookup(req.Context(), colDef, search)
+	if err != nil {
+		writeInternalError(w, fmt.Sprintf("Greška pri pretrazi lookup-a '%s'", column), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju lookup opcija: %v", err)
+	}
+}
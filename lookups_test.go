@@ -0,0 +1,122 @@
+// lookups_test.go
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUserLookupDisplayExprHidesUnreadableColumns(t *testing.T) {
+	users := &ModuleDefinition{ID: "users", Columns: []ColumnDefinition{
+		{DBColumnName: "id", Type: "integer", IsPrimaryKey: true},
+		{DBColumnName: "username", Type: "string"},
+		{DBColumnName: "email", Type: "string", ReadRoles: []string{"admin"}},
+	}}
+	pkCol := &users.Columns[0]
+	staff := &User{ID: "ana", Roles: []string{"staff"}}
+	admin := &User{ID: "root", Roles: []string{"admin"}}
+
+	tests := []struct {
+		col  ColumnDefinition
+		user *User
+		want string
+	}{
+		{ColumnDefinition{LookupDisplayField: "username"}, staff, "username"},
+		{ColumnDefinition{LookupDisplayField: "email"}, staff, "id"},
+		{ColumnDefinition{LookupDisplayField: "email"}, admin, "email"},
+		{ColumnDefinition{LookupDisplayTemplate: "{username} ({email})"}, staff, "id"},
+		{ColumnDefinition{LookupDisplayTemplate: "{username} ({email})"}, admin, "CONCAT(username::text, ' (', email::text, ')')"},
+	}
+	for _, tt := range tests {
+		tt.col.LookupModule = users
+		if got := userLookupDisplayExpr(&tt.col, pkCol, tt.user); got != tt.want {
+			t.Errorf("userLookupDisplayExpr(%q%q, %s) = %q, očekivano %q", tt.col.LookupDisplayField, tt.col.LookupDisplayTemplate, tt.user.ID, got, tt.want)
+		}
+	}
+}
+
+func TestExpandedLookupHidesUnreadableFields(t *testing.T) {
+	users := &ModuleDefinition{ID: "test_lookup_users", Name: "Korisnici", Type: "table", DBTableName: "test_lookup_users",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "username", Name: "Korisničko ime", Type: "string", IsVisible: true},
+			{DBColumnName: "email", Name: "Email", Type: "string", IsVisible: true, ReadRoles: []string{"admin"}},
+		}}
+	orders := &ModuleDefinition{ID: "test_lookup_orders", Name: "Porudžbine", Type: "table", DBTableName: "test_lookup_orders",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "user_id", Name: "Korisnik", Type: "lookup", IsVisible: true, LookupModuleID: users.ID, LookupModule: users,
+				LookupDisplayTemplate: "{username} ({email})", LookupFields: []string{"username", "email"}},
+		}}
+	ds := newTestDataset(t, users, orders)
+	createTestTable(t, ds, users.DBTableName, "id SERIAL PRIMARY KEY, username TEXT, email TEXT")
+	createTestTable(t, ds, orders.DBTableName, "id SERIAL PRIMARY KEY, user_id INT")
+	if _, err := ds.db.Exec(`INSERT INTO test_lookup_users (id, username, email) VALUES (1, 'ana', 'ana@example.com');
+		INSERT INTO test_lookup_orders (user_id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAPIServer(ds.config, ds)
+
+	rec := serveTest(s, http.MethodGet, "/api/modules/test_lookup_orders?_expand=user_id", "", "staff", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "ana@example.com") || !strings.Contains(rec.Body.String(), `"username":"ana"`) {
+		t.Errorf("staff: status %d, telo %s", rec.Code, rec.Body)
+	}
+	rec = serveTest(s, http.MethodGet, "/api/modules/test_lookup_orders?_expand=user_id", "", "admin", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"ana (ana@example.com)"`) {
+		t.Errorf("admin: status %d, telo %s", rec.Code, rec.Body)
+	}
+}
+
+func TestExpandedLookupFieldsRequireModuleRead(t *testing.T) {
+	users := &ModuleDefinition{ID: "test_lookup_users", Name: "Korisnici", Type: "table", DBTableName: "test_lookup_users",
+		Permissions: map[string][]string{PermRead: {"admin"}},
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "username", Name: "Korisničko ime", Type: "string", IsVisible: true},
+			{DBColumnName: "email", Name: "Email", Type: "string", IsVisible: true},
+		}}
+	orders := &ModuleDefinition{ID: "test_lookup_orders", Name: "Porudžbine", Type: "table", DBTableName: "test_lookup_orders",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "user_id", Name: "Korisnik", Type: "lookup", IsVisible: true, LookupModuleID: users.ID, LookupModule: users,
+				LookupDisplayField: "username", LookupFields: []string{"email"}},
+		}}
+	ds := newTestDataset(t, users, orders)
+	createTestTable(t, ds, users.DBTableName, "id SERIAL PRIMARY KEY, username TEXT, email TEXT")
+	createTestTable(t, ds, orders.DBTableName, "id SERIAL PRIMARY KEY, user_id INT")
+	if _, err := ds.db.Exec(`INSERT INTO test_lookup_users (id, username, email) VALUES (1, 'ana', 'ana@example.com');
+		INSERT INTO test_lookup_orders (user_id) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAPIServer(ds.config, ds)
+
+	rec := serveTest(s, http.MethodGet, "/api/modules/test_lookup_orders", "", "staff", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "ana@example.com") || !strings.Contains(rec.Body.String(), `"name":"ana"`) {
+		t.Errorf("staff: status %d, telo %s", rec.Code, rec.Body)
+	}
+	rec = serveTest(s, http.MethodGet, "/api/modules/test_lookup_orders", "", "admin", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"email":"ana@example.com"`) {
+		t.Errorf("admin: status %d, telo %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSearchLookupOptionsChecksSourceColumnReadRoles(t *testing.T) {
+	customers := &ModuleDefinition{ID: "customers", Type: "table", DBTableName: "customers", Columns: []ColumnDefinition{
+		{DBColumnName: "id", Type: "integer", IsPrimaryKey: true},
+		{DBColumnName: "name", Type: "string"},
+	}}
+	orders := &ModuleDefinition{ID: "orders", Type: "table", DBTableName: "orders", Columns: []ColumnDefinition{
+		{DBColumnName: "id", Type: "integer", IsPrimaryKey: true},
+		{DBColumnName: "customer_id", Type: "lookup", LookupModuleID: "customers", LookupModule: customers, ReadRoles: []string{"sales"}},
+	}}
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{"customers": customers, "orders": orders}, Rules: NewRuleRegistry()}
+	s := NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+
+	for _, path := range []string{"/api/modules/orders/lookups/customer_id", "/api/modules/orders/lookups/missing"} {
+		rec := serveTest(s, http.MethodGet, path+"?q=a", "", "staff", nil)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, očekivano 404", path, rec.Code)
+		}
+	}
+}
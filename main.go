@@ -16,6 +16,14 @@ import (
 )
 
 func main() {
+	// Komande (npr. "demo openapi -o openapi.json") se izvršavaju bez pokretanja servera
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("Fatal: %v", err)
+		}
+		return
+	}
+
 	// Učitavanje konfiguracije
 	config, err := LoadConfigFromFile("config.json")
 	if err != nil {
@@ -38,6 +46,15 @@ func main() {
 	// Inicijalizacija API servera
 	apiServer := NewAPIServer(appConfig, dataset) // Kreiramo instancu APIServera
 
+	// Pozadinski procesi (webhook worker, čišćenje idempotentnih ključeva, listener izmena) rade dok se ne zatraži gašenje
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go NewWebhookWorker(dataset, appConfig.Config.Webhooks).Run(workerCtx)
+	go dataset.RunIdempotencyCleanup(workerCtx)
+	if err := dataset.StartChangeListener(workerCtx); err != nil {
+		log.Printf("WARNING: %v; SSE događaji se objavljuju samo u ovoj instanci.", err)
+	}
+
 	// Postavljanje HTTP servera
 	serverAddr := ":8080" // Može se prebaciti u config
 	srv := &http.Server{
@@ -62,6 +79,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("INFO: Gašenje servera...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1,49 +1,104 @@
 // models.go
 package main
 
+import "fmt"
+
 // ColumnDefinition defines the structure of a column in a module.
 type ColumnDefinition struct {
-	ID                 string      `json:"id"`
-	Name               string      `json:"name"`
-	Type               string      `json:"type"` // e.g., "string", "integer", "float", "boolean", "date", "datetime", "lookup"
-	DBColumnName       string      `json:"db_column_name"`
-	IsPrimaryKey       bool        `json:"is_primary_key"`
-	IsSearchable       bool        `json:"is_searchable"`
-	IsSortable         bool        `json:"is_sortable"`
-	IsVisible          bool        `json:"is_visible"`
-	IsEditable         bool        `json:"is_editable"`
-	IsReadOnly         bool        `json:"is_read_only"`         // Dodato za read-only polja (npr. auto-increment ID)
-	Validation         string      `json:"validation"`           // e.g., "required,min:5,max:100,email,regex:^[A-Za-z]+$"
-	DefaultValue       interface{} `json:"default_value"`        // Defaultna vrednost za kreiranje
-	LookupModuleID     string      `json:"lookup_module_id"`     // ID modula za lookup polja
-	LookupDisplayField string      `json:"lookup_display_field"` // Polje iz lookup modula koje se prikazuje
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Type         string `json:"type"` // e.g., "string", "integer", "float", "boolean", "date", "datetime", "lookup"
+	DBColumnName string `json:"db_column_name"`
+	IsPrimaryKey bool   `json:"is_primary_key"`
+	IsSearchable bool   `json:"is_searchable"`
+	IsSortable   bool   `json:"is_sortable"`
+	IsVisible    bool   `json:"is_visible"`
+	IsEditable   bool   `json:"is_editable"`
+	IsReadOnly   bool   `json:"is_read_only"` // Dodato za read-only polja (npr. auto-increment ID)
+	Validation   string `json:"validation"`   // e.g., "required,min:5,max:100,email,regex:^[A-Za-z]+$"
+	// Strukturirana pravila (pored Validation), npr. [{"name": "regex", "arg": "^[a-z,]+$"}, {"name": "gt_field", "arg": "start_date"}]
+	Rules              []ValidationRule `json:"rules,omitempty"`
+	DefaultValue       interface{}      `json:"default_value"`        // Defaultna vrednost za kreiranje
+	LookupModuleID     string           `json:"lookup_module_id"`     // ID modula za lookup polja
+	LookupDisplayField string           `json:"lookup_display_field"` // Polje iz lookup modula koje se prikazuje
+	// Šablon prikaza iz više polja lookup modula, npr. "{username} ({email})"; ima prednost nad LookupDisplayField
+	LookupDisplayTemplate string `json:"lookup_display_template,omitempty"`
+	// Dodatna polja lookup zapisa koja se vraćaju uz {"id", "name"}, npr. ["email", "phone"]
+	LookupFields []string `json:"lookup_fields,omitempty"`
+	// Zavisni filteri lookup pretrage: kolona lookup modula -> polje forme ovog modula, npr. {"country_id": "country_id"}
+	LookupFilters map[string]string `json:"lookup_filters,omitempty"`
+	Auto          string            `json:"auto,omitempty"`       // Automatska kolona: "created_at", "updated_at", "created_by", "updated_by"
+	ReadRoles     []string          `json:"read_roles,omitempty"` // Uloge koje smeju da vide kolonu; prazno = svi sa pravom čitanja modula
+	// Prevodi naziva po jeziku (npr. {"en": "Name"}); bez prevoda se koristi Name
+	NameI18n map[string]string `json:"name_i18n,omitempty"`
+	Choices  []ColumnChoice    `json:"choices,omitempty"` // Dozvoljene vrednosti sa (prevodivim) oznakama
 	// Runtime fields (populated during app initialization)
 	LookupModule *ModuleDefinition `json:"-"` // Pointer to the actual ModuleDefinition for lookup
 }
 
+// ColumnChoice is one allowed value of a column with its display label.
+type ColumnChoice struct {
+	Value     interface{}       `json:"value"`
+	Label     string            `json:"label"`
+	LabelI18n map[string]string `json:"label_i18n,omitempty"`
+}
+
+// HasChoice proverava da li je vrednost među ponuđenim (poređenje po tekstualnom obliku,
+// jer JSON brojevi stižu kao float64).
+func (c *ColumnDefinition) HasChoice(value interface{}) bool {
+	for _, choice := range c.Choices {
+		if fmt.Sprint(choice.Value) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChoiceValues vraća dozvoljene vrednosti kolone.
+func (c *ColumnDefinition) ChoiceValues() []interface{} {
+	values := make([]interface{}, len(c.Choices))
+	for i, choice := range c.Choices {
+		values[i] = choice.Value
+	}
+	return values
+}
+
 // SubModuleDefinition defines a submodule relationship.
 type SubModuleDefinition struct {
-	ID                   string `json:"id"`
-	DisplayName          string `json:"display_name"`
-	TargetModuleID       string `json:"target_module_id"`        // ID modula koji predstavlja submodule
-	ChildForeignKeyField string `json:"child_foreign_key_field"` // Polje u target modulu koje referencira primarni ključ roditelja
+	ID                   string            `json:"id"`
+	DisplayName          string            `json:"display_name"`
+	DisplayNameI18n      map[string]string `json:"display_name_i18n,omitempty"` // Prevodi DisplayName po jeziku
+	TargetModuleID       string            `json:"target_module_id"`            // ID modula koji predstavlja submodule
+	ChildForeignKeyField string            `json:"child_foreign_key_field"`     // Polje u target modulu koje referencira primarni ključ roditelja
+	DisplayOrder         int               `json:"display_order"`               // Redosled prikaza ispod zapisa roditelja
 	// Runtime fields
 	TargetModule *ModuleDefinition `json:"-"` // Pointer to the actual ModuleDefinition for the target module
 }
 
 // ModuleDefinition defines the structure of a data module.
 type ModuleDefinition struct {
-	ID           string                 `json:"id"`
-	Name         string                 `json:"name"`
-	Type         string                 `json:"type"` // e.g., "table", "group", "root", "report", "custom"
-	Description  string                 `json:"description"`
-	DBTableName  string                 `json:"db_table_name"` // Used for "table" type modules
-	DisplayField string                 `json:"display_field"` // Field to display in lists (e.g., "name" or "title")
-	SelectQuery  string                 `json:"select_query"`  // Used for "report" or "custom" type modules
-	Columns      []ColumnDefinition     `json:"columns"`
-	SubModules   []SubModuleDefinition  `json:"sub_modules"`
-	Properties   map[string]interface{} `json:"properties,omitempty"` // Dodaj ako već nema
-	Groups       []GroupLink            `json:"groups,omitempty"`     // <-- NOVO: Dodaj ovo polje za "app" modul
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"` // e.g., "table", "group", "root", "report", "custom"
+	Description string `json:"description"`
+	// Prevodi naziva i opisa po jeziku (npr. {"en": "Customers"})
+	NameI18n        map[string]string      `json:"name_i18n,omitempty"`
+	DescriptionI18n map[string]string      `json:"description_i18n,omitempty"`
+	DBTableName     string                 `json:"db_table_name"` // Used for "table" type modules
+	DisplayField    string                 `json:"display_field"` // Field to display in lists (e.g., "name" or "title")
+	SelectQuery     string                 `json:"select_query"`  // Used for "report" or "custom" type modules
+	Columns         []ColumnDefinition     `json:"columns"`
+	SubModules      []SubModuleDefinition  `json:"sub_modules"`
+	Properties      map[string]interface{} `json:"properties,omitempty"` // Dodaj ako već nema
+	Groups          []GroupLink            `json:"groups,omitempty"`     // <-- NOVO: Dodaj ovo polje za "app" modul
+	// Soft delete: ako je DeletedAtColumn postavljen, DELETE samo označava zapis kao obrisan
+	DeletedAtColumn string `json:"deleted_at_column,omitempty"` // Kolona sa vremenom brisanja (NULL = aktivan zapis)
+	DeletedByColumn string `json:"deleted_by_column,omitempty"` // Opciona kolona sa ID-em korisnika koji je obrisao zapis
+	VersionColumn   string `json:"version_column,omitempty"`    // Kolona verzije (integer ili updated_at) za ETag/If-Match
+	// Prirodni unique ključevi (npr. [["code"], ["company_id", "sku"]]) za upsert; u bazi mora postojati odgovarajući UNIQUE indeks
+	UniqueKeys [][]string `json:"unique_keys,omitempty"`
+	// Uloge kojima je dozvoljena operacija ("read", "create", "update", "delete"); bez unosa = svima
+	Permissions map[string][]string `json:"permissions,omitempty"`
 }
 
 // GroupLink defines a link to a group, used within the root module (e.g., app.json)
@@ -52,4 +107,6 @@ type GroupLink struct {
 	Type          string `json:"type"` // e.g., "group" or "group_link"
 	DisplayName   string `json:"display_name"`
 	DisplayOrder  int    `json:"display_order"`
+	// Prevodi DisplayName po jeziku
+	DisplayNameI18n map[string]string `json:"display_name_i18n,omitempty"`
 }
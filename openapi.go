@@ -0,0 +1,1019 @@
+// openapi.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+// openAPIVersion je verzija OpenAPI specifikacije koju generator proizvodi.
+const openAPIVersion = "3.0.3"
+
+// JSONSchema is a JSON Schema (OpenAPI 3.0 dialect) object.
+type JSONSchema map[string]interface{}
+
+// OpenAPIDocument is the root of an OpenAPI 3 document.
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+// OpenAPIInfo holds the document title and version.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPathItem maps lowercase HTTP methods to operations.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+// OpenAPIOperation describes a single route and method.
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter is a path, query or header parameter.
+type OpenAPIParameter struct {
+	Name        string     `json:"name"`
+	In          string     `json:"in"`
+	Description string     `json:"description,omitempty"`
+	Required    bool       `json:"required,omitempty"`
+	Schema      JSONSchema `json:"schema"`
+}
+
+// OpenAPIRequestBody describes the accepted request payloads.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType wraps the schema of a body for one content type.
+type OpenAPIMediaType struct {
+	Schema JSONSchema `json:"schema"`
+}
+
+// OpenAPIResponse describes a response; Ref upućuje na components.responses.
+type OpenAPIResponse struct {
+	Ref         string                      `json:"$ref,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Headers     map[string]OpenAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIHeader describes a response header.
+type OpenAPIHeader struct {
+	Description string     `json:"description,omitempty"`
+	Schema      JSONSchema `json:"schema"`
+}
+
+// OpenAPIComponents holds reusable schemas and responses.
+type OpenAPIComponents struct {
+	Schemas   map[string]JSONSchema      `json:"schemas"`
+	Responses map[string]OpenAPIResponse `json:"responses"`
+}
+
+// Operatori filtera liste po tipu kolone (vidi buildWhereClause); prazan operator je jednakost.
+var (
+	openAPIStringOperators  = []string{"", "ne", "like", "ilike", "in"}
+	openAPIOrderedOperators = []string{"", "ne", "gt", "gte", "lt", "lte", "in"}
+	openAPIBoolOperators    = []string{"", "ne"}
+)
+
+// schemaRef vraća referencu na šemu iz components.schemas.
+func schemaRef(name string) JSONSchema {
+	return JSONSchema{"$ref": "#/components/schemas/" + name}
+}
+
+// responseRef vraća referencu na odgovor iz components.responses.
+func responseRef(name string) OpenAPIResponse {
+	return OpenAPIResponse{Ref: "#/components/responses/" + name}
+}
+
+// jsonContent vraća sadržaj tipa application/json sa datom šemom.
+func jsonContent(schema JSONSchema) map[string]OpenAPIMediaType {
+	return map[string]OpenAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// openAPITypeName pretvara ID modula u ime tipa (npr. "module_products" -> "ModuleProducts").
+func openAPITypeName(id string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// columnJSONSchema pravi JSON Schema za kolonu na osnovu tipa i validacionih pravila.
+func (s *APIServer) columnJSONSchema(colDef *ColumnDefinition) JSONSchema {
+	schema := JSONSchema{}
+	colType := colDef.Type
+	if colType == "lookup" {
+		colType = "integer"
+		if colDef.LookupModule != nil {
+			if pk := s.dataset.getPrimaryKeyColumn(colDef.LookupModule); pk != nil {
+				colType = pk.Type
+			}
+		}
+		schema["x-lookup-module"] = colDef.LookupModuleID
+	}
+	switch colType {
+	case "integer":
+		schema["type"] = "integer"
+	case "float":
+		schema["type"] = "number"
+	case "boolean":
+		schema["type"] = "boolean"
+	case "date":
+		schema["type"] = "string"
+		schema["format"] = "date"
+	case "datetime":
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+	default:
+		schema["type"] = "string"
+	}
+	if colDef.Name != "" {
+		schema["title"] = colDef.Name
+	}
+	if colDef.DefaultValue != nil {
+		schema["default"] = colDef.DefaultValue
+	}
+	if len(colDef.Choices) > 0 {
+		schema["enum"] = colDef.ChoiceValues()
+	}
+
+	for _, rule := range colDef.ValidationRules() {
+		switch rule.Name {
+		case "min", "max":
+			n, err := strconv.ParseFloat(rule.Arg, 64)
+			if err != nil {
+				continue
+			}
+			if schema["type"] == "string" {
+				key := map[string]string{"min": "minLength", "max": "maxLength"}[rule.Name]
+				schema[key] = int(n)
+			} else {
+				key := map[string]string{"min": "minimum", "max": "maximum"}[rule.Name]
+				schema[key] = n
+			}
+		case "email":
+			schema["format"] = "email"
+		case "regex":
+			schema["pattern"] = rule.Arg
+		}
+	}
+	if !colDef.IsRequired() {
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+// isInputColumn javlja da li klijent šalje kolonu pri kreiranju/izmeni.
+func isInputColumn(colDef *ColumnDefinition) bool {
+	return colDef.IsEditable && !colDef.IsPrimaryKey && !colDef.IsReadOnly && !colDef.IsAuto()
+}
+
+// openAPIView određuje šta dokument opisuje: sve module i kolone (komanda openapi)
+// ili samo ono što korisnik sme da čita (GET /api/openapi.json).
+type openAPIView struct {
+	user *User
+	all  bool
+}
+
+// canReadModule javlja da li dokument opisuje modul.
+func (v openAPIView) canReadModule(moduleDef *ModuleDefinition) bool {
+	return v.all || moduleDef.UserCan(v.user, PermRead)
+}
+
+// canReadColumn javlja da li dokument opisuje kolonu.
+func (v openAPIView) canReadColumn(colDef *ColumnDefinition) bool {
+	return v.all || colDef.UserCanRead(v.user)
+}
+
+// addModuleSchemas dodaje šeme zapisa modula: Record (odgovor), Input (POST/PUT) i Patch (PATCH).
+func (s *APIServer) addModuleSchemas(doc *OpenAPIDocument, moduleDef *ModuleDefinition, view openAPIView) {
+	name := openAPITypeName(moduleDef.ID)
+	record := JSONSchema{"type": "object", "title": moduleDef.Name}
+	input := JSONSchema{"type": "object"}
+	patch := JSONSchema{"type": "object"}
+	recordProps, inputProps, patchProps := JSONSchema{}, JSONSchema{}, JSONSchema{}
+	var required []string
+
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if !view.canReadColumn(colDef) {
+			continue
+		}
+		prop := s.columnJSONSchema(colDef)
+		out := JSONSchema{}
+		if colDef.Type == "lookup" {
+			// Pri čitanju se lookup vrednost proširuje u {"id": ..., "name": ..., LookupFields...} (vidi performLookupExpansion)
+			id := JSONSchema{}
+			for k, v := range prop {
+				if k != "nullable" && k != "default" && k != "title" {
+					id[k] = v
+				}
+			}
+			out = JSONSchema{
+				"type":       "object",
+				"title":      colDef.Name,
+				"nullable":   true,
+				"properties": JSONSchema{"id": id, "name": JSONSchema{"type": "string"}},
+				// Dublje proširene lookup kolone (_expand) dodaju se kao dodatna polja
+				"additionalProperties": true,
+			}
+			if colDef.LookupModule != nil && view.canReadModule(colDef.LookupModule) {
+				for _, field := range colDef.LookupFields {
+					if fieldDef := getColumnByDBName(colDef.LookupModule.Columns, field); fieldDef != nil {
+						out["properties"].(JSONSchema)[field] = s.columnJSONSchema(fieldDef)
+					}
+				}
+			}
+		} else {
+			for k, v := range prop {
+				out[k] = v
+			}
+		}
+		if colDef.IsPrimaryKey || colDef.IsReadOnly || colDef.IsAuto() {
+			out["readOnly"] = true
+		}
+		if len(colDef.ReadRoles) > 0 {
+			out["description"] = fmt.Sprintf("Vidljivo samo ulogama: %s", strings.Join(colDef.ReadRoles, ", "))
+		}
+		recordProps[colDef.DBColumnName] = out
+
+		if moduleDef.Type != "table" || !isInputColumn(colDef) {
+			continue
+		}
+		if colDef.Type == "lookup" {
+			prop["description"] = fmt.Sprintf("ID postojećeg zapisa modula '%s'; prihvata se i objekat {\"id\": ...}", colDef.LookupModuleID)
+		}
+		inputProps[colDef.DBColumnName] = prop
+		patchProps[colDef.DBColumnName] = prop
+		if colDef.IsRequired() {
+			required = append(required, colDef.DBColumnName)
+		}
+	}
+
+	record["properties"] = recordProps
+	doc.Components.Schemas[name+"Record"] = record
+	if moduleDef.Type != "table" {
+		return
+	}
+	input["properties"] = inputProps
+	if len(required) > 0 {
+		input["required"] = required
+	}
+	patch["properties"] = patchProps
+	doc.Components.Schemas[name+"Input"] = input
+	doc.Components.Schemas[name+"Patch"] = patch
+}
+
+// listParameters vraća query parametre liste: paginaciju, sortiranje, pretragu i filtere po kolonama.
+func (s *APIServer) listParameters(moduleDef *ModuleDefinition, view openAPIView) []OpenAPIParameter {
+	params := []OpenAPIParameter{
+		{Name: "_limit", In: "query", Description: "Najveći broj zapisa", Schema: JSONSchema{"type": "integer", "minimum": 0}},
+		{Name: "_offset", In: "query", Description: "Broj preskočenih zapisa", Schema: JSONSchema{"type": "integer", "minimum": 0}},
+		{Name: "_sort", In: "query", Description: "Kolone za sortiranje odvojene zarezom; prefiks '-' za opadajući redosled; dozvoljene su putanje kroz lookup kolone (customer_id.username)", Schema: JSONSchema{"type": "string"}},
+		{Name: "_search", In: "query", Description: "Pretraga po podstringu (ILIKE) u vidljivim string kolonama koje korisnik sme da čita, ili u kolonama iz _search_fields", Schema: JSONSchema{"type": "string"}},
+		{Name: "_search_fields", In: "query", Description: "Vidljive string kolone ili putanje kroz lookup kolone za _search, odvojene zarezom (npr. customer_id.username)", Schema: JSONSchema{"type": "string"}},
+	}
+	if moduleDef.HasSoftDelete() {
+		params = append(params,
+			OpenAPIParameter{Name: "_with_deleted", In: "query", Description: "Uključi obrisane zapise", Schema: JSONSchema{"type": "boolean"}},
+			OpenAPIParameter{Name: "_only_deleted", In: "query", Description: "Samo obrisani zapisi", Schema: JSONSchema{"type": "boolean"}},
+		)
+	}
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if !view.canReadColumn(colDef) {
+			continue
+		}
+		operators := openAPIOrderedOperators
+		switch colDef.Type {
+		case "string", "text":
+			operators = openAPIStringOperators
+		case "boolean":
+			operators = openAPIBoolOperators
+		}
+		for _, op := range operators {
+			param := OpenAPIParameter{Name: colDef.DBColumnName, In: "query", Schema: s.columnJSONSchema(colDef)}
+			delete(param.Schema, "nullable")
+			delete(param.Schema, "default")
+			if op != "" {
+				param.Name += "__" + op
+			}
+			switch op {
+			case "":
+				param.Description = fmt.Sprintf("Filter: %s jednako", colDef.Name)
+				if colDef.Type == "lookup" && moduleDef.SelectQuery == "" {
+					param.Description += fmt.Sprintf("; po kolonama modula '%s' filtrira se putanjom (%s.<kolona>__<operator>)", colDef.LookupModuleID, colDef.DBColumnName)
+				}
+			case "in":
+				param.Description = fmt.Sprintf("Filter: %s u listi vrednosti odvojenih zarezom", colDef.Name)
+				param.Schema = JSONSchema{"type": "string"}
+			case "like", "ilike":
+				param.Description = fmt.Sprintf("Filter: %s sadrži vrednost (%s)", colDef.Name, strings.ToUpper(op))
+				param.Schema = JSONSchema{"type": "string"}
+			default:
+				param.Description = fmt.Sprintf("Filter: %s %s", colDef.Name, op)
+			}
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// openAPIModuleOp opisuje rutu modula za dati metod; nil znači da ruta ne važi za modul.
+type openAPIModuleOp func(s *APIServer, moduleDef *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation
+
+// withErrors dodaje zajedničke odgovore o greškama.
+func withErrors(responses map[string]OpenAPIResponse, codes ...string) map[string]OpenAPIResponse {
+	for _, code := range append([]string{"400", "403", "500"}, codes...) {
+		if _, ok := responses[code]; !ok {
+			responses[code] = responseRef(map[string]string{
+				"400": "BadRequest", "403": "Forbidden", "404": "NotFound", "409": "Conflict",
+				"412": "PreconditionFailed", "415": "UnsupportedMediaType", "422": "UnprocessableEntity", "500": "InternalError",
+			}[code])
+		}
+	}
+	return responses
+}
+
+// expandParameter opisuje _expand parametar čitanja (vidi parseLookupExpand).
+var expandParameter = OpenAPIParameter{
+	Name:        "_expand",
+	In:          "query",
+	Description: fmt.Sprintf("Lookup putanje koje se proširuju u dubinu, odvojene zarezom (npr. product_id.category_id); najviše %d nivoa", lookupExpandMaxDepth),
+	Schema:      JSONSchema{"type": "string"},
+}
+
+var etagHeader = map[string]OpenAPIHeader{"ETag": {Description: "Verzija zapisa za If-Match", Schema: JSONSchema{"type": "string"}}}
+
+var ifMatchParam = OpenAPIParameter{Name: "If-Match", In: "header", Description: "ETag zapisa; izmena se odbija sa 412 ako se verzija promenila", Schema: JSONSchema{"type": "string"}}
+
+var idempotencyKeyParam = OpenAPIParameter{Name: idempotencyKeyHeader, In: "header", Description: "Ključ za bezbedno ponavljanje zahteva (samo za prijavljene korisnike, ključevi su po korisniku)", Schema: JSONSchema{"type": "string", "maxLength": idempotencyMaxKeyLen}}
+
+// openAPIModuleRoutes opisuje rute modula po šablonu putanje i metodu (vidi InitRoutes).
+var openAPIModuleRoutes = map[string]map[string]openAPIModuleOp{
+	"/api/modules/{moduleID}": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return &OpenAPIOperation{
+				OperationID: "list" + name, Summary: "Lista zapisa: " + m.Name,
+				Parameters: append(s.listParameters(m, view), expandParameter),
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Zapisi", Content: jsonContent(JSONSchema{"type": "array", "items": schemaRef(name + "Record")})},
+				}),
+			}
+		},
+		"POST": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "create" + name, Summary: "Kreiranje zapisa: " + m.Name,
+				Parameters:  []OpenAPIParameter{idempotencyKeyParam},
+				RequestBody: &OpenAPIRequestBody{Required: true, Content: jsonContent(schemaRef(name + "Input"))},
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"201": {Description: "Zapis je kreiran", Headers: etagHeader, Content: jsonContent(JSONSchema{
+						"type": "object",
+						"properties": JSONSchema{
+							"message": JSONSchema{"type": "string"},
+							"id":      JSONSchema{},
+							"record":  schemaRef(name + "Record"),
+						},
+					})},
+				}, "409", "422"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/{recordID}": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return &OpenAPIOperation{
+				OperationID: "get" + name, Summary: "Zapis po ID-u: " + m.Name,
+				Parameters: []OpenAPIParameter{{Name: "If-None-Match", In: "header", Schema: JSONSchema{"type": "string"}}, expandParameter},
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Zapis", Headers: etagHeader, Content: jsonContent(schemaRef(name + "Record"))},
+					"304": {Description: "Zapis nije promenjen"},
+				}, "404"),
+			}
+		},
+		"PUT": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "replace" + name, Summary: "Zamena zapisa (izostavljena polja dobijaju podrazumevane vrednosti): " + m.Name,
+				Parameters:  []OpenAPIParameter{ifMatchParam},
+				RequestBody: &OpenAPIRequestBody{Required: true, Content: jsonContent(schemaRef(name + "Input"))},
+				Responses:   withErrors(map[string]OpenAPIResponse{"200": {Ref: "#/components/responses/" + name + "Updated"}}, "404", "412"),
+			}
+		},
+		"PATCH": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "patch" + name, Summary: "Delimična izmena zapisa: " + m.Name,
+				Parameters: []OpenAPIParameter{ifMatchParam},
+				RequestBody: &OpenAPIRequestBody{Required: true, Content: map[string]OpenAPIMediaType{
+					"application/json":    {Schema: schemaRef(name + "Patch")},
+					mergePatchContentType: {Schema: schemaRef(name + "Patch")},
+					jsonPatchContentType:  {Schema: schemaRef("JSONPatch")},
+				}},
+				Responses: withErrors(map[string]OpenAPIResponse{"200": {Ref: "#/components/responses/" + name + "Updated"}}, "404", "409", "412", "415"),
+			}
+		},
+		"DELETE": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "delete" + name, Summary: "Brisanje zapisa: " + m.Name,
+				Parameters: []OpenAPIParameter{ifMatchParam},
+				Responses:  withErrors(map[string]OpenAPIResponse{"200": responseRef("Message")}, "404", "412"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/{recordID}/history": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "history" + name, Summary: "Istorija izmena zapisa: " + m.Name,
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Audit zapisi", Content: jsonContent(JSONSchema{"type": "array", "items": schemaRef("AuditEntry")})},
+				}, "404"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/{recordID}/restore": {
+		"POST": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" || !m.HasSoftDelete() {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "restore" + name, Summary: "Vraćanje obrisanog zapisa: " + m.Name,
+				Responses: withErrors(map[string]OpenAPIResponse{"200": responseRef("Message")}, "404"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/schema": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return &OpenAPIOperation{
+				OperationID: "schema" + name, Summary: "Opis modula za generisanje formi: " + m.Name,
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Šema modula filtrirana po dozvolama", Headers: etagHeader, Content: jsonContent(schemaRef("ModuleSchema"))},
+					"304": {Description: "Šema nije promenjena"},
+				}, "404"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/actions": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return &OpenAPIOperation{
+				OperationID: "actions" + name, Summary: "Serverske akcije dostupne korisniku: " + m.Name,
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Akcije", Content: jsonContent(JSONSchema{"type": "array", "items": JSONSchema{"type": "object"}})},
+				}, "404"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/events": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "events" + name, Summary: "Server-Sent Events sa izmenama zapisa: " + m.Name,
+				Parameters: []OpenAPIParameter{
+					{Name: "Last-Event-ID", In: "header", Description: "SSE id poslednjeg primljenog bloka (kursor za nastavak, ne ID događaja)", Schema: JSONSchema{"type": "string"}},
+					{Name: "last_event_id", In: "query", Description: "Isto kao Last-Event-ID", Schema: JSONSchema{"type": "integer"}},
+				},
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Tok događaja (ChangeEvent u data polju). Pri nastavku se događaji mogu ponoviti (prepoznaju se po polju id); " +
+						"događaj \"reset\" znači da je propušteno previše izmena i da podatke treba ponovo učitati", Content: map[string]OpenAPIMediaType{"text/event-stream": {Schema: JSONSchema{"type": "string"}}}},
+				}, "404"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/export": {
+		"GET": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			params := append(s.listParameters(m, view),
+				OpenAPIParameter{Name: "_format", In: "query", Schema: JSONSchema{"type": "string", "enum": []string{"csv", "xlsx"}}},
+				OpenAPIParameter{Name: "_delimiter", In: "query", Schema: JSONSchema{"type": "string"}},
+				OpenAPIParameter{Name: "_encoding", In: "query", Schema: JSONSchema{"type": "string", "enum": []string{"utf-8", "utf-8-bom", "windows-1250"}}},
+			)
+			return &OpenAPIOperation{
+				OperationID: "export" + name, Summary: "Izvoz zapisa u CSV/XLSX: " + m.Name,
+				Parameters: params,
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Datoteka", Content: map[string]OpenAPIMediaType{
+						"text/csv": {Schema: JSONSchema{"type": "string"}},
+						"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": {Schema: JSONSchema{"type": "string", "format": "binary"}},
+					}},
+				}, "404"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/import": {
+		"POST": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if m.Type != "table" {
+				return nil
+			}
+			return &OpenAPIOperation{
+				OperationID: "import" + name, Summary: "Uvoz zapisa iz CSV/XLSX: " + m.Name,
+				RequestBody: &OpenAPIRequestBody{Required: true, Content: map[string]OpenAPIMediaType{"multipart/form-data": {Schema: JSONSchema{
+					"type":     "object",
+					"required": []string{"file"},
+					"properties": JSONSchema{
+						"file":      JSONSchema{"type": "string", "format": "binary"},
+						"format":    JSONSchema{"type": "string", "enum": []string{"csv", "xlsx"}},
+						"mode":      JSONSchema{"type": "string", "enum": []string{ImportModeInsert, ImportModeUpsert}},
+						"key":       JSONSchema{"type": "string"},
+						"mapping":   JSONSchema{"type": "string", "description": "JSON objekat zaglavlje -> kolona"},
+						"dry_run":   JSONSchema{"type": "boolean"},
+						"delimiter": JSONSchema{"type": "string"},
+						"encoding":  JSONSchema{"type": "string"},
+					},
+				}}}},
+				Responses: withErrors(map[string]OpenAPIResponse{
+					"200": {Description: "Rezultat uvoza", Content: jsonContent(schemaRef("ImportResult"))},
+				}, "404", "422"),
+			}
+		},
+	},
+	"/api/modules/{moduleID}/bulk": {
+		"POST": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return bulkOperation(m, "bulkCreate"+name, "Grupno kreiranje zapisa: "+m.Name, JSONSchema{
+				"records": JSONSchema{"type": "array", "items": schemaRef(name + "Input")},
+			})
+		},
+		"PATCH": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return bulkOperation(m, "bulkUpdate"+name, "Grupna izmena zapisa: "+m.Name, JSONSchema{
+				"items": JSONSchema{"type": "array", "items": JSONSchema{
+					"type": "object",
+					"properties": JSONSchema{
+						"id":       JSONSchema{},
+						"changes":  schemaRef(name + "Patch"),
+						"if_match": JSONSchema{"type": "string"},
+					},
+				}},
+				"filter":  JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}},
+				"changes": schemaRef(name + "Patch"),
+			})
+		},
+		"DELETE": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			return bulkOperation(m, "bulkDelete"+name, "Grupno brisanje zapisa: "+m.Name, JSONSchema{
+				"ids":    JSONSchema{"type": "array", "items": JSONSchema{}},
+				"filter": JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}},
+			})
+		},
+	},
+	"/api/modules/{moduleID}/upsert": {
+		"POST": func(s *APIServer, m *ModuleDefinition, name string, view openAPIView) *OpenAPIOperation {
+			if len(m.UniqueKeys) == 0 {
+				return nil
+			}
+			return bulkOperation(m, "upsert"+name, "Upis ili izmena zapisa po unique ključu (soft-obrisan zapis se ne menja; vraća se preko /restore): "+m.Name, JSONSchema{
+				"key":     JSONSchema{"type": "array", "items": JSONSchema{"type": "string"}},
+				"records": JSONSchema{"type": "array", "items": schemaRef(name + "Input")},
+			})
+		},
+	},
+}
+
+// bulkOperation opisuje grupnu rutu; telo sadrži mode i polja specifična za operaciju.
+func bulkOperation(m *ModuleDefinition, operationID, summary string, props JSONSchema) *OpenAPIOperation {
+	if m.Type != "table" {
+		return nil
+	}
+	props["mode"] = JSONSchema{"type": "string", "enum": []string{BulkModeAtomic, BulkModeBestEffort}}
+	return &OpenAPIOperation{
+		OperationID: operationID, Summary: summary,
+		Parameters:  []OpenAPIParameter{idempotencyKeyParam},
+		RequestBody: &OpenAPIRequestBody{Required: true, Content: jsonContent(JSONSchema{"type": "object", "properties": props})},
+		Responses: withErrors(map[string]OpenAPIResponse{
+			"200": {Description: "Rezultat po stavkama", Content: jsonContent(schemaRef("BulkResult"))},
+			"422": {Description: "Atomic operacija je poništena", Content: jsonContent(schemaRef("BulkResult"))},
+		}, "404"),
+	}
+}
+
+// openAPIGlobalRoutes opisuje rute koje ne pripadaju pojedinačnom modulu.
+var openAPIGlobalRoutes = map[string]map[string]*OpenAPIOperation{
+	"/api/modules": {
+		"GET": {OperationID: "listModules", Summary: "Stablo modula aplikacije", Responses: withErrors(map[string]OpenAPIResponse{
+			"200": {Description: "Stablo modula", Content: jsonContent(JSONSchema{"type": "object"})},
+		})},
+	},
+	"/api/batch": {
+		"POST": {
+			OperationID: "runBatch", Summary: "Više operacija u jednom zahtevu, opciono u jednoj transakciji",
+			Parameters:  []OpenAPIParameter{idempotencyKeyParam},
+			RequestBody: &OpenAPIRequestBody{Required: true, Content: jsonContent(schemaRef("BatchRequest"))},
+			Responses: withErrors(map[string]OpenAPIResponse{
+				"200": {Description: "Rezultati operacija", Content: jsonContent(schemaRef("BatchResponse"))},
+				"422": {Description: "Transakcija je poništena", Content: jsonContent(schemaRef("BatchResponse"))},
+			}),
+		},
+	},
+	"/api/openapi.json": {
+		"GET": {OperationID: "getOpenAPI", Summary: "Ovaj dokument", Responses: map[string]OpenAPIResponse{
+			"200": {Description: "OpenAPI dokument", Content: jsonContent(JSONSchema{"type": "object"})},
+		}},
+	},
+}
+
+// openAPISharedSchemas vraća šeme zajedničke za sve module.
+func openAPISharedSchemas() map[string]JSONSchema {
+	return map[string]JSONSchema{
+		"Message": {"type": "object", "properties": JSONSchema{"message": JSONSchema{"type": "string"}}},
+		"ErrorResponse": {"type": "object", "required": []string{"error"}, "properties": JSONSchema{
+			"error": JSONSchema{"type": "object", "required": []string{"code", "message", "status"}, "properties": JSONSchema{
+				"code":       JSONSchema{"type": "string"},
+				"message":    JSONSchema{"type": "string", "description": "Poruka na jeziku iz Accept-Language (Content-Language)"},
+				"detail":     JSONSchema{"type": "string", "description": "Detaljna poruka servera kada je message preveden"},
+				"status":     JSONSchema{"type": "integer"},
+				"request_id": JSONSchema{"type": "string"},
+				"fields":     JSONSchema{"type": "array", "items": schemaRef("FieldError")},
+				"current":    JSONSchema{"type": "object", "description": "Samo za 412: trenutno stanje zapisa"},
+			}},
+		}},
+		"FieldError": {"type": "object", "required": []string{"message"}, "properties": JSONSchema{
+			"field":   JSONSchema{"type": "string"},
+			"rule":    JSONSchema{"type": "string", "description": "required, required_if, type, min, max, email, regex, choice, gt_field, gte_field, lt_field, lte_field, unique, exists, check ili pravilo registrovano u Go kodu"},
+			"params":  JSONSchema{"type": "object"},
+			"message": JSONSchema{"type": "string"},
+		}},
+		"JSONPatch": {"type": "array", "items": JSONSchema{
+			"type":     "object",
+			"required": []string{"op", "path"},
+			"properties": JSONSchema{
+				"op":    JSONSchema{"type": "string", "enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+				"path":  JSONSchema{"type": "string"},
+				"from":  JSONSchema{"type": "string"},
+				"value": JSONSchema{},
+			},
+		}},
+		"LookupSearchResult": {"type": "object", "properties": JSONSchema{
+			"items": JSONSchema{"type": "array", "items": JSONSchema{"type": "object", "properties": JSONSchema{
+				"id":   JSONSchema{},
+				"name": JSONSchema{},
+			}}},
+			"limit":    JSONSchema{"type": "integer"},
+			"offset":   JSONSchema{"type": "integer"},
+			"has_more": JSONSchema{"type": "boolean"},
+		}},
+		"AuditEntry": {"type": "object", "properties": JSONSchema{
+			"id":         JSONSchema{"type": "integer"},
+			"module_id":  JSONSchema{"type": "string"},
+			"record_id":  JSONSchema{"type": "string"},
+			"operation":  JSONSchema{"type": "string", "enum": []string{AuditCreate, AuditUpdate, AuditDelete}},
+			"user_id":    JSONSchema{"type": "string", "nullable": true},
+			"changed_at": JSONSchema{"type": "string", "format": "date-time"},
+			"changes": JSONSchema{"type": "object", "additionalProperties": JSONSchema{
+				"type": "object", "properties": JSONSchema{"old": JSONSchema{}, "new": JSONSchema{}},
+			}},
+		}},
+		"BulkResult": {"type": "object", "properties": JSONSchema{
+			"mode":      JSONSchema{"type": "string"},
+			"succeeded": JSONSchema{"type": "integer"},
+			"failed":    JSONSchema{"type": "integer"},
+			"items": JSONSchema{"type": "array", "items": JSONSchema{"type": "object", "properties": JSONSchema{
+				"index":  JSONSchema{"type": "integer"},
+				"id":     JSONSchema{},
+				"status": JSONSchema{"type": "string", "enum": []string{BulkStatusOK, BulkStatusError, BulkStatusSkipped}},
+				"action": JSONSchema{"type": "string", "enum": []string{UpsertInserted, UpsertUpdated}},
+				"field":  JSONSchema{"type": "string"},
+				"code":   JSONSchema{"type": "string"},
+				"error":  JSONSchema{"type": "string"},
+				"fields": JSONSchema{"type": "array", "items": schemaRef("FieldError")},
+			}}},
+		}},
+		"ImportResult": {"type": "object", "properties": JSONSchema{
+			"dry_run":         JSONSchema{"type": "boolean"},
+			"mode":            JSONSchema{"type": "string"},
+			"mapping":         JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}},
+			"ignored_headers": JSONSchema{"type": "array", "items": JSONSchema{"type": "string"}},
+			"total_rows":      JSONSchema{"type": "integer"},
+			"valid_rows":      JSONSchema{"type": "integer"},
+			"inserted":        JSONSchema{"type": "integer"},
+			"updated":         JSONSchema{"type": "integer"},
+			"errors": JSONSchema{"type": "array", "items": JSONSchema{"type": "object", "properties": JSONSchema{
+				"row": JSONSchema{"type": "integer"}, "field": JSONSchema{"type": "string"}, "rule": JSONSchema{"type": "string"}, "message": JSONSchema{"type": "string"},
+			}}},
+			"preview": JSONSchema{"type": "array", "items": JSONSchema{"type": "object"}},
+		}},
+		"BatchRequest": {"type": "object", "required": []string{"operations"}, "properties": JSONSchema{
+			"transaction": JSONSchema{"type": "boolean"},
+			"operations": JSONSchema{"type": "array", "maxItems": batchMaxOperations, "items": JSONSchema{
+				"type":     "object",
+				"required": []string{"method", "path"},
+				"properties": JSONSchema{
+					"id":      JSONSchema{"type": "string"},
+					"method":  JSONSchema{"type": "string", "enum": []string{"GET", "POST", "PUT", "PATCH", "DELETE"}},
+					"path":    JSONSchema{"type": "string", "description": "Može sadržati reference ${opID.polje}"},
+					"body":    JSONSchema{},
+					"headers": JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}},
+				},
+			}},
+		}},
+		"BatchResponse": {"type": "object", "properties": JSONSchema{
+			"transaction": JSONSchema{"type": "boolean"},
+			"rolled_back": JSONSchema{"type": "boolean"},
+			"results": JSONSchema{"type": "array", "items": JSONSchema{"type": "object", "properties": JSONSchema{
+				"id":      JSONSchema{"type": "string"},
+				"status":  JSONSchema{"type": "integer"},
+				"headers": JSONSchema{"type": "object", "additionalProperties": JSONSchema{"type": "string"}},
+				"body":    JSONSchema{},
+			}}},
+		}},
+		"ModuleSchema": {"type": "object", "description": "Vidi GET /api/modules/{moduleID}/schema"},
+	}
+}
+
+// openAPIErrorResponses vraća zajedničke odgovore o greškama; svi koriste JSON envelope (ErrorResponse).
+func openAPIErrorResponses() map[string]OpenAPIResponse {
+	errorContent := jsonContent(schemaRef("ErrorResponse"))
+	responses := map[string]OpenAPIResponse{
+		"BadRequest":           {Description: "Nevažeći zahtev ili greška validacije (code: bad_request, validation_failed)", Content: errorContent},
+		"Forbidden":            {Description: "Korisnik nema dozvolu za operaciju", Content: errorContent},
+		"NotFound":             {Description: "Modul ili zapis nije pronađen", Content: errorContent},
+		"Conflict":             {Description: "Konflikt (code: unique_violation, record_referenced, patch_test_failed, request_in_progress)", Content: errorContent},
+		"PreconditionFailed":   {Description: "If-Match ne odgovara trenutnoj verziji zapisa; error.current sadrži trenutni zapis", Content: errorContent},
+		"UnsupportedMediaType": {Description: "Nepodržan Content-Type", Content: errorContent},
+		"UnprocessableEntity":  {Description: "Prekršeno ograničenje baze ili ponovljen Idempotency-Key (code: foreign_key_violation, not_null_violation, check_violation, invalid_value, idempotency_key_reused)", Content: errorContent},
+		"InternalError":        {Description: "Serverska greška (poruka je generička, detalji su u logu servera pod request_id)", Content: errorContent},
+		"Message":              {Description: "Operacija je uspela", Content: jsonContent(schemaRef("Message"))},
+	}
+	return responses
+}
+
+// BuildOpenAPI generates the OpenAPI document from the registered routes and module definitions.
+// Rute se čitaju iz router-a (InitRoutes), pa dokument prati stvarno registrovane putanje.
+// Dokument opisuje sve module, kolone i akcije; klijentima se servira BuildOpenAPIForUser.
+func (s *APIServer) BuildOpenAPI() (*OpenAPIDocument, error) {
+	return s.buildOpenAPI(openAPIView{all: true})
+}
+
+// BuildOpenAPIForUser generates the OpenAPI document as seen by the given user: samo moduli
+// koje sme da čita, bez kolona koje ne sme da čita i akcija koje ne sme da pokrene.
+func (s *APIServer) BuildOpenAPIForUser(user *User) (*OpenAPIDocument, error) {
+	return s.buildOpenAPI(openAPIView{user: user})
+}
+
+// buildOpenAPI pravi dokument za dati pogled.
+func (s *APIServer) buildOpenAPI(view openAPIView) (*OpenAPIDocument, error) {
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    OpenAPIInfo{Title: "API", Version: "1.0.0"},
+		Paths:   make(map[string]OpenAPIPathItem),
+		Components: OpenAPIComponents{
+			Schemas:   openAPISharedSchemas(),
+			Responses: openAPIErrorResponses(),
+		},
+	}
+
+	// Moduli sortirani po ID-u, da bi dokument bio stabilan između generisanja
+	modules := make([]*ModuleDefinition, 0, len(s.config.Modules))
+	for _, moduleDef := range s.config.Modules {
+		switch moduleDef.Type {
+		case "root":
+			doc.Info.Title = moduleDef.Name
+		case "group":
+		default:
+			if view.canReadModule(moduleDef) {
+				modules = append(modules, moduleDef)
+			}
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].ID < modules[j].ID })
+	for _, moduleDef := range modules {
+		s.addModuleSchemas(doc, moduleDef, view)
+		name := openAPITypeName(moduleDef.ID)
+		doc.Components.Responses[name+"Updated"] = OpenAPIResponse{
+			Description: "Zapis je izmenjen",
+			Headers:     etagHeader,
+			Content: jsonContent(JSONSchema{"type": "object", "properties": JSONSchema{
+				"message": JSONSchema{"type": "string"},
+				"record":  schemaRef(name + "Record"),
+			}}),
+		}
+	}
+
+	err := s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			s.addRouteOperations(doc, modules, template, method, view)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("greška pri čitanju ruta: %w", err)
+	}
+	return doc, nil
+}
+
+// addRouteOperations dodaje operacije za jednu registrovanu rutu; rute modula se
+// razvijaju u konkretne putanje po modulu (i po akciji za /actions/{name}).
+func (s *APIServer) addRouteOperations(doc *OpenAPIDocument, modules []*ModuleDefinition, template, method string, view openAPIView) {
+	add := func(path string, op *OpenAPIOperation, params ...OpenAPIParameter) {
+		if op == nil {
+			return
+		}
+		op.Parameters = append(params, op.Parameters...)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = OpenAPIPathItem{}
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+	}
+
+	if !strings.Contains(template, "{moduleID}") {
+		if op, ok := openAPIGlobalRoutes[template][method]; ok {
+			copied := *op
+			copied.Tags = []string{"system"}
+			add(template, &copied)
+			return
+		}
+		add(template, &OpenAPIOperation{
+			OperationID: strings.ToLower(method) + openAPITypeName(strings.NewReplacer("{", "", "}", "").Replace(template)),
+			Tags:        []string{"system"},
+			Responses:   withErrors(map[string]OpenAPIResponse{"200": {Description: "Uspešno"}}),
+		}, pathParams(template)...)
+		return
+	}
+
+	for _, moduleDef := range modules {
+		name := openAPITypeName(moduleDef.ID)
+		path := strings.Replace(template, "{moduleID}", moduleDef.ID, 1)
+		var params []OpenAPIParameter
+		if strings.Contains(template, "{recordID}") {
+			pkSchema := JSONSchema{"type": "string"}
+			if pk := s.dataset.getPrimaryKeyColumn(moduleDef); pk != nil {
+				pkSchema = s.columnJSONSchema(pk)
+				delete(pkSchema, "nullable")
+			} else if moduleDef.Type != "table" {
+				continue
+			}
+			params = append(params, OpenAPIParameter{Name: "recordID", In: "path", Required: true, Schema: pkSchema})
+		}
+
+		if strings.HasSuffix(template, "/actions/{name}") {
+			onRecord := strings.Contains(template, "{recordID}")
+			actions := s.Actions.List(moduleDef.ID)
+			if !view.all {
+				actions = s.Actions.ListAllowed(moduleDef.ID, view.user)
+			}
+			for _, action := range actions {
+				if action.OnRecord != onRecord {
+					continue
+				}
+				add(strings.Replace(path, "{name}", action.Name, 1), s.actionOperation(moduleDef, name, action), params...)
+			}
+			continue
+		}
+
+		if strings.HasSuffix(template, "/lookups/{column}") {
+			for i := range moduleDef.Columns {
+				colDef := &moduleDef.Columns[i]
+				if colDef.Type != "lookup" || colDef.LookupModule == nil || !view.canReadColumn(colDef) {
+					continue
+				}
+				add(strings.Replace(path, "{column}", colDef.DBColumnName, 1), s.lookupSearchOperation(moduleDef, name, colDef), params...)
+			}
+			continue
+		}
+
+		describe, ok := openAPIModuleRoutes[template][method]
+		if !ok {
+			add(path, &OpenAPIOperation{
+				OperationID: strings.ToLower(method) + name + openAPITypeName(strings.TrimPrefix(template, "/api/modules/{moduleID}")),
+				Tags:        []string{moduleDef.ID},
+				Responses:   withErrors(map[string]OpenAPIResponse{"200": {Description: "Uspešno"}}),
+			}, params...)
+			continue
+		}
+		if op := describe(s, moduleDef, name, view); op != nil {
+			op.Tags = []string{moduleDef.ID}
+			add(path, op, params...)
+		}
+	}
+}
+
+// actionOperation opisuje poziv serverske akcije; parametri se validiraju kao kolone.
+func (s *APIServer) actionOperation(moduleDef *ModuleDefinition, name string, action *ActionDefinition) *OpenAPIOperation {
+	props := JSONSchema{}
+	var required []string
+	for i := range action.Params {
+		param := &action.Params[i]
+		props[param.DBColumnName] = s.columnJSONSchema(param)
+		if param.IsRequired() {
+			required = append(required, param.DBColumnName)
+		}
+	}
+	body := JSONSchema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		body["required"] = required
+	}
+	return &OpenAPIOperation{
+		OperationID: "action" + name + openAPITypeName(action.Name),
+		Summary:     action.Description,
+		Tags:        []string{moduleDef.ID},
+		RequestBody: &OpenAPIRequestBody{Required: len(required) > 0, Content: jsonContent(body)},
+		Responses: withErrors(map[string]OpenAPIResponse{
+			"200": {Description: "Rezultat akcije", Content: jsonContent(JSONSchema{"type": "object", "properties": JSONSchema{"result": JSONSchema{}}})},
+		}, "404"),
+	}
+}
+
+// lookupSearchOperation opisuje pretragu opcija lookup kolone; zavisni filteri su query parametri sa imenima polja forme.
+func (s *APIServer) lookupSearchOperation(moduleDef *ModuleDefinition, name string, colDef *ColumnDefinition) *OpenAPIOperation {
+	params := []OpenAPIParameter{
+		{Name: "q", In: "query", Description: "Tekst koji se traži u prikaznoj koloni lookup modula", Schema: JSONSchema{"type": "string"}},
+		{Name: "limit", In: "query", Description: fmt.Sprintf("Najveći broj opcija (podrazumevano %d, najviše %d)", lookupSearchDefaultLimit, lookupSearchMaxLimit), Schema: JSONSchema{"type": "integer", "minimum": 1, "maximum": lookupSearchMaxLimit}},
+		{Name: "offset", In: "query", Description: "Broj preskočenih opcija", Schema: JSONSchema{"type": "integer", "minimum": 0}},
+	}
+	lookupCols := make([]string, 0, len(colDef.LookupFilters))
+	for lookupCol := range colDef.LookupFilters {
+		lookupCols = append(lookupCols, lookupCol)
+	}
+	sort.Strings(lookupCols)
+	for _, lookupCol := range lookupCols {
+		params = append(params, OpenAPIParameter{
+			Name:        colDef.LookupFilters[lookupCol],
+			In:          "query",
+			Description: fmt.Sprintf("Vrednost polja forme; filtrira opcije po koloni '%s' (prazno = NULL)", lookupCol),
+			Schema:      JSONSchema{"type": "string"},
+		})
+	}
+	return &OpenAPIOperation{
+		OperationID: "lookup" + name + openAPITypeName(colDef.DBColumnName),
+		Summary:     fmt.Sprintf("Opcije za '%s' iz modula '%s'", colDef.Name, colDef.LookupModuleID),
+		Tags:        []string{moduleDef.ID},
+		Parameters:  params,
+		Responses: withErrors(map[string]OpenAPIResponse{
+			"200": {Description: "Strana opcija", Content: jsonContent(schemaRef("LookupSearchResult"))},
+		}, "404"),
+	}
+}
+
+// pathParams vraća path parametre iz šablona rute (npr. {webhookID}).
+func pathParams(template string) []OpenAPIParameter {
+	var params []OpenAPIParameter
+	for _, part := range strings.Split(template, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			params = append(params, OpenAPIParameter{Name: strings.Trim(part, "{}"), In: "path", Required: true, Schema: JSONSchema{"type": "string"}})
+		}
+	}
+	return params
+}
+
+// GetOpenAPI handles GET /api/openapi.json. Dokument se pravi za korisnika zahteva.
+func (s *APIServer) GetOpenAPI(w http.ResponseWriter, req *http.Request) {
+	doc, err := s.BuildOpenAPIForUser(UserFromContext(req.Context()))
+	if err != nil {
+		log.Printf("ERROR: Greška pri generisanju OpenAPI dokumenta: %v", err)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Add("Vary", strings.Join([]string{userIDHeader, userRolesHeader}, ", "))
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju OpenAPI dokumenta: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+// openapi_test.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// testOpenAPIServer pravi server bez baze sa modulom zaposlenih, modulom dostupnim samo
+// administratorima, lookup kolonom i akcijama. Oba modula imaju jedinstvene ključeve i
+// soft delete, pa za njih važe sve rute modula.
+func testOpenAPIServer(t *testing.T) *APIServer {
+	t.Helper()
+	secrets := &ModuleDefinition{ID: "test_secrets", Name: "Tajne", Type: "table", DBTableName: "test_secrets",
+		UniqueKeys: [][]string{{"secret_value"}}, DeletedAtColumn: "deleted_at",
+		Permissions: map[string][]string{PermRead: {"admin"}},
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true},
+			{DBColumnName: "secret_value", Name: "Vrednost", Type: "string", IsEditable: true},
+		}}
+	employees := testEmployeesModule()
+	employees.Columns = append(employees.Columns, ColumnDefinition{DBColumnName: "secret_id", Name: "Tajna", Type: "lookup", IsEditable: true,
+		LookupModuleID: secrets.ID, LookupModule: secrets, LookupDisplayField: "secret_value", LookupFields: []string{"secret_value"}})
+	employees.UniqueKeys = [][]string{{"name"}}
+	employees.DeletedAtColumn = "deleted_at"
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{employees.ID: employees, secrets.ID: secrets}, Rules: NewRuleRegistry()}
+	s := NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+
+	noop := func(ctx context.Context, call *ActionCall) (interface{}, error) { return nil, nil }
+	for _, action := range []ActionDefinition{
+		{Name: "izvestaj", Handler: noop},
+		{Name: "povisica", Handler: noop, OnRecord: true},
+		{Name: "otpusti", Handler: noop, OnRecord: true, Roles: []string{"admin"}},
+	} {
+		if err := s.Actions.Register(employees.ID, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestBuildOpenAPIDescribesEveryModuleRoute(t *testing.T) {
+	s := testOpenAPIServer(t)
+	doc, err := s.BuildOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checked := 0
+	err = s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !strings.Contains(template, "{moduleID}") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, moduleDef := range s.config.Modules {
+			path := strings.Replace(template, "{moduleID}", moduleDef.ID, 1)
+			var paths []string
+			switch {
+			case strings.HasSuffix(template, "/actions/{name}"):
+				for _, action := range s.Actions.List(moduleDef.ID) {
+					if action.OnRecord == strings.Contains(template, "{recordID}") {
+						paths = append(paths, strings.Replace(path, "{name}", action.Name, 1))
+					}
+				}
+			case strings.HasSuffix(template, "/lookups/{column}"):
+				for _, col := range moduleDef.Columns {
+					if col.Type == "lookup" {
+						paths = append(paths, strings.Replace(path, "{column}", col.DBColumnName, 1))
+					}
+				}
+			default:
+				paths = []string{path}
+			}
+			for _, p := range paths {
+				for _, method := range methods {
+					checked++
+					if op := doc.Paths[p][strings.ToLower(method)]; op == nil || op.OperationID == "" {
+						t.Errorf("nedostaje operacija %s %s", method, p)
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked == 0 {
+		t.Fatal("nijedna ruta modula nije proverena")
+	}
+
+	record := doc.Components.Schemas["TestEmployeesRecord"]["properties"].(JSONSchema)
+	if _, ok := record["salary"]; !ok {
+		t.Error("potpun dokument mora da opisuje sve kolone")
+	}
+}
+
+func TestGetOpenAPIIsBuiltPerCaller(t *testing.T) {
+	s := testOpenAPIServer(t)
+
+	tests := []struct {
+		roles    string
+		contains []string
+		excludes []string
+	}{
+		{"staff",
+			[]string{`"/api/modules/test_employees"`, `"/api/modules/test_employees/{recordID}/actions/povisica"`},
+			[]string{`"/api/modules/test_secrets"`, `"TestSecretsRecord"`, `"salary"`, `"salary__gt"`, `/actions/otpusti"`, `"secret_value"`}},
+		{"hr,admin",
+			[]string{`"/api/modules/test_secrets"`, `"salary"`, `"salary__gt"`, `/actions/otpusti"`, `"secret_value"`},
+			nil},
+	}
+	for _, tt := range tests {
+		rec := serveTest(s, http.MethodGet, "/api/openapi.json", "", tt.roles, nil)
+		var doc OpenAPIDocument
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &doc) != nil {
+			t.Fatalf("uloge %q: status %d", tt.roles, rec.Code)
+		}
+		if vary := rec.Header().Values("Vary"); !strings.Contains(strings.Join(vary, ","), userRolesHeader) {
+			t.Errorf("uloge %q: Vary %v", tt.roles, vary)
+		}
+		body := rec.Body.String()
+		for _, want := range tt.contains {
+			if !strings.Contains(body, want) {
+				t.Errorf("uloge %q: dokument ne sadrži %s", tt.roles, want)
+			}
+		}
+		for _, unwanted := range tt.excludes {
+			if strings.Contains(body, unwanted) {
+				t.Errorf("uloge %q: dokument ne sme da sadrži %s", tt.roles, unwanted)
+			}
+		}
+	}
+}
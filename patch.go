@@ -0,0 +1,215 @@
+// patch.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Content-Type vrednosti koje PATCH prihvata (pored application/json, koji se tretira kao merge patch).
+const (
+	mergePatchContentType = "application/merge-patch+json" // RFC 7386
+	jsonPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+// JSONPatchOperation is a single RFC 6902 operation. Putanje se odnose na kolone
+// zapisa ("/price"), jer su zapisi ravni.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// errPatchTestFailed označava neuspelu "test" operaciju; handler vraća 409.
+var errPatchTestFailed = errors.New("test operacija JSON Patch-a nije uspela")
+
+// errPatchColumnHidden označava "from" ili "test" putanju na kolonu koju korisnik ne sme da vidi; handler vraća 403.
+var errPatchColumnHidden = errors.New("JSON Patch čita kolonu koja korisniku nije dostupna")
+
+// sqlDefault je vrednost u payload-u koja se u UPDATE-u upisuje kao DEFAULT
+// (PUT postavlja izostavljena opciona polja na podrazumevanu vrednost iz baze).
+type sqlDefault struct{}
+
+// takeSQLDefaults uklanja DEFAULT vrednosti iz payload-a i vraća kolone kojima pripadaju,
+// da ih handler-i i validacija ne bi videli kao vrednosti.
+func takeSQLDefaults(payload map[string]interface{}) []string {
+	var cols []string
+	for col, val := range payload {
+		if _, ok := val.(sqlDefault); ok {
+			cols = append(cols, col)
+			delete(payload, col)
+		}
+	}
+	return cols
+}
+
+// replacementPayload dopunjuje PUT payload izostavljenim kolonama koje klijent sme da menja:
+// dobijaju default_value iz definicije modula, a ako ga nema, DEFAULT iz baze.
+func replacementPayload(moduleDef *ModuleDefinition, payload map[string]interface{}) map[string]interface{} {
+	full := make(map[string]interface{}, len(moduleDef.Columns))
+	for k, v := range payload {
+		full[k] = v
+	}
+	for _, colDef := range moduleDef.Columns {
+		if !colDef.IsEditable || colDef.IsPrimaryKey || colDef.IsReadOnly || colDef.IsAuto() ||
+			colDef.DBColumnName == moduleDef.VersionColumn || colDef.DBColumnName == moduleDef.DeletedAtColumn ||
+			colDef.DBColumnName == moduleDef.DeletedByColumn {
+			continue
+		}
+		if _, ok := full[colDef.DBColumnName]; ok {
+			continue
+		}
+		if colDef.DefaultValue != nil {
+			full[colDef.DBColumnName] = colDef.DefaultValue
+		} else {
+			full[colDef.DBColumnName] = sqlDefault{}
+		}
+	}
+	return full
+}
+
+// jsonPatchColumn pretvara JSON Pointer u ime kolone; podržane su samo putanje prvog nivoa.
+func jsonPatchColumn(moduleDef *ModuleDefinition, pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", NewValidationError("", fmt.Sprintf("nepodržana JSON Patch putanja '%s' (dozvoljeno: /kolona)", pointer))
+	}
+	col := strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:])
+	if getColumnByDBName(moduleDef.Columns, col) == nil {
+		return "", NewValidationError(col, "kolona ne postoji u modulu")
+	}
+	return col, nil
+}
+
+// jsonComparable svodi vrednost iz baze i vrednost iz JSON-a na isti oblik za "test" operaciju.
+func jsonComparable(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// applyJSONPatch primenjuje operacije na trenutno stanje zapisa i vraća payload
+// sa kolonama koje su operacije promenile. "remove" briše vrednost (NULL), jer kolona ostaje.
+// "test" i "from" ("move", "copy") smeju da čitaju samo kolone koje korisnik vidi (ReadRoles).
+func applyJSONPatch(moduleDef *ModuleDefinition, user *User, current map[string]interface{}, ops []JSONPatchOperation) (map[string]interface{}, error) {
+	readable := func(col string) bool {
+		colDef := getColumnByDBName(moduleDef.Columns, col)
+		return colDef != nil && colDef.UserCanRead(user)
+	}
+	doc := make(map[string]interface{}, len(current))
+	for k, v := range current {
+		doc[k] = v
+	}
+	payload := make(map[string]interface{})
+	set := func(col string, v interface{}) {
+		doc[col] = v
+		payload[col] = v
+	}
+
+	for i, op := range ops {
+		col, err := jsonPatchColumn(moduleDef, op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operacija %d: %w", i, err)
+		}
+		var value interface{}
+		switch op.Op {
+		case "add", "replace", "test":
+			if len(op.Value) == 0 {
+				return nil, fmt.Errorf("operacija %d: %w", i, NewValidationError(col, fmt.Sprintf("operacija '%s' zahteva 'value'", op.Op)))
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("operacija %d: %w", i, NewValidationError(col, fmt.Sprintf("nevažeća vrednost: %v", err)))
+			}
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			set(col, value)
+		case "remove":
+			set(col, nil)
+		case "test":
+			if !readable(col) {
+				return nil, fmt.Errorf("operacija %d (kolona '%s'): %w", i, col, errPatchColumnHidden)
+			}
+			if !reflect.DeepEqual(jsonComparable(doc[col]), value) {
+				return nil, fmt.Errorf("operacija %d (kolona '%s'): %w", i, col, errPatchTestFailed)
+			}
+		case "move", "copy":
+			from, err := jsonPatchColumn(moduleDef, op.From)
+			if err != nil {
+				return nil, fmt.Errorf("operacija %d: %w", i, err)
+			}
+			if !readable(from) {
+				return nil, fmt.Errorf("operacija %d (kolona '%s'): %w", i, from, errPatchColumnHidden)
+			}
+			set(col, jsonComparable(doc[from]))
+			if op.Op == "move" && from != col {
+				set(from, nil)
+			}
+		default:
+			return nil, fmt.Errorf("operacija %d: %w", i, NewValidationError("", fmt.Sprintf("nepodržana JSON Patch operacija '%s'", op.Op)))
+		}
+	}
+
+	if len(payload) == 0 {
+		return nil, NewValidationError("", "JSON Patch ne menja nijednu kolonu")
+	}
+	for col := range payload {
+		if colDef := getColumnByDBName(moduleDef.Columns, col); !colDef.IsEditable || colDef.IsPrimaryKey || colDef.IsReadOnly || colDef.IsAuto() {
+			return nil, NewValidationError(col, "kolona se ne može menjati")
+		}
+	}
+	return payload, nil
+}
+
+// ReplaceRecord replaces a record (PUT): izostavljena opciona polja se vraćaju na podrazumevane vrednosti.
+func (s *SQLDataset) ReplaceRecord(ctx context.Context, moduleDef *ModuleDefinition, recordID string, payload map[string]interface{}, ifMatch string) (map[string]interface{}, error) {
+	return s.UpdateRecord(ctx, moduleDef, recordID, replacementPayload(moduleDef, payload), ifMatch)
+}
+
+// PatchRecord applies an RFC 6902 JSON Patch to a record. Zapis se zaključava, patch se
+// primenjuje na njegovo trenutno stanje, a promenjena polja se validiraju pre UPDATE-a.
+func (s *SQLDataset) PatchRecord(ctx context.Context, moduleDef *ModuleDefinition, recordID string, ops []JSONPatchOperation, ifMatch string) (map[string]interface{}, error) {
+	if moduleDef.Type != "table" {
+		return nil, fmt.Errorf("ažuriranje zapisa nije podržano za modul tipa '%s'", moduleDef.Type)
+	}
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ za ažuriranje", moduleDef.Name)
+	}
+
+	var after map[string]interface{}
+	err := s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		current, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeActive)
+		if err == sql.ErrNoRows {
+			return errRecordNotFound("zapis sa ID '%s' nije pronađen ili ažuriran u modulu '%s'", recordID, moduleDef.Name)
+		}
+		if err != nil {
+			return err
+		}
+		payload, err := applyJSONPatch(moduleDef, UserFromContext(ctx), current, ops)
+		if err != nil {
+			return err
+		}
+		if err := s.validateRecord(ctx, validationTarget{Module: moduleDef, RecordID: recordID, Partial: true}, payload); err != nil {
+			return err
+		}
+		after, err = s.updateRecordTx(ctx, tx, moduleDef, pkCol, recordID, payload, ifMatch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
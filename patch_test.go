@@ -0,0 +1,110 @@
+// patch_test.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// patchTestModule je modul sa jednom kolonom vidljivom samo ulozi "hr".
+func patchTestModule() *ModuleDefinition {
+	return &ModuleDefinition{ID: "employees", Columns: []ColumnDefinition{
+		{DBColumnName: "id", Type: "integer", IsPrimaryKey: true, IsReadOnly: true},
+		{DBColumnName: "name", Type: "string", IsEditable: true},
+		{DBColumnName: "nickname", Type: "string", IsEditable: true},
+		{DBColumnName: "salary", Type: "float", IsEditable: true, ReadRoles: []string{"hr"}},
+	}}
+}
+
+func patchOps(t *testing.T, raw string) []JSONPatchOperation {
+	t.Helper()
+	var ops []JSONPatchOperation
+	if err := json.Unmarshal([]byte(raw), &ops); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	current := map[string]interface{}{"id": int64(1), "name": "Ana", "nickname": nil, "salary": 1000.0}
+	cases := []struct {
+		name string
+		ops  string
+		want map[string]interface{}
+	}{
+		{"replace", `[{"op":"replace","path":"/name","value":"Ivana"}]`, map[string]interface{}{"name": "Ivana"}},
+		{"remove", `[{"op":"remove","path":"/name"}]`, map[string]interface{}{"name": nil}},
+		{"copy", `[{"op":"copy","from":"/name","path":"/nickname"}]`, map[string]interface{}{"nickname": "Ana"}},
+		{"move", `[{"op":"move","from":"/name","path":"/nickname"}]`, map[string]interface{}{"nickname": "Ana", "name": nil}},
+		{"test then replace", `[{"op":"test","path":"/name","value":"Ana"},{"op":"add","path":"/nickname","value":"A"}]`, map[string]interface{}{"nickname": "A"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := applyJSONPatch(patchTestModule(), nil, current, patchOps(t, c.ops))
+			if err != nil {
+				t.Fatalf("neočekivana greška: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("payload = %v, očekivano %v", got, c.want)
+			}
+		})
+	}
+	if current["name"] != "Ana" {
+		t.Errorf("patch ne sme da menja trenutni zapis, name = %v", current["name"])
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	current := map[string]interface{}{"id": int64(1), "name": "Ana", "nickname": nil, "salary": 1000.0}
+	hr := &User{ID: "1", Roles: []string{"hr"}}
+	cases := []struct {
+		name string
+		user *User
+		ops  string
+		want error
+	}{
+		{"failed test", nil, `[{"op":"test","path":"/name","value":"Marko"},{"op":"remove","path":"/name"}]`, errPatchTestFailed},
+		{"test hidden column", nil, `[{"op":"test","path":"/salary","value":1000},{"op":"remove","path":"/name"}]`, errPatchColumnHidden},
+		{"copy from hidden column", nil, `[{"op":"copy","from":"/salary","path":"/nickname"}]`, errPatchColumnHidden},
+		{"move from hidden column", nil, `[{"op":"move","from":"/salary","path":"/nickname"}]`, errPatchColumnHidden},
+		{"read only column", nil, `[{"op":"replace","path":"/id","value":2}]`, nil},
+		{"nested path", nil, `[{"op":"replace","path":"/name/first","value":"A"}]`, nil},
+		{"unknown op", nil, `[{"op":"merge","path":"/name","value":"A"}]`, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := applyJSONPatch(patchTestModule(), c.user, current, patchOps(t, c.ops))
+			if err == nil {
+				t.Fatal("očekivana greška")
+			}
+			var validationErr *ValidationError
+			if c.want != nil && !errors.Is(err, c.want) {
+				t.Errorf("greška = %v, očekivano %v", err, c.want)
+			}
+			if c.want == nil && !errors.As(err, &validationErr) {
+				t.Errorf("očekivana greška validacije, dobijeno %v", err)
+			}
+		})
+	}
+
+	// Uloga koja vidi kolonu sme da je čita
+	got, err := applyJSONPatch(patchTestModule(), hr, current, patchOps(t, `[{"op":"copy","from":"/salary","path":"/nickname"}]`))
+	if err != nil || got["nickname"] != 1000.0 {
+		t.Errorf("copy sa ulogom hr: payload = %v, greška = %v", got, err)
+	}
+}
+
+func TestTakeSQLDefaults(t *testing.T) {
+	payload := replacementPayload(patchTestModule(), map[string]interface{}{"name": "Ana"})
+	defaults := takeSQLDefaults(payload)
+	if len(defaults) != 2 {
+		t.Errorf("DEFAULT kolone = %v, očekivane nickname i salary", defaults)
+	}
+	for col, val := range payload {
+		if _, ok := val.(sqlDefault); ok {
+			t.Errorf("payload i dalje sadrži DEFAULT za '%s'", col)
+		}
+	}
+}
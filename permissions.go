@@ -0,0 +1,66 @@
+// permissions.go
+package main
+
+// Operacije nad modulom za koje se mogu ograničiti uloge (ModuleDefinition.Permissions).
+const (
+	PermRead   = "read"
+	PermCreate = "create"
+	PermUpdate = "update"
+	PermDelete = "delete"
+)
+
+// HasAnyRole reports whether the user has at least one of the given roles.
+// Prazna lista uloga znači da ograničenje ne postoji.
+func (u *User) HasAnyRole(roles []string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	if u == nil {
+		return false
+	}
+	for _, want := range roles {
+		for _, have := range u.Roles {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UserCan reports whether the user may perform the operation on the module.
+// Ako za operaciju nisu navedene uloge, operacija je dozvoljena svima.
+func (m *ModuleDefinition) UserCan(user *User, operation string) bool {
+	return user.HasAnyRole(m.Permissions[operation])
+}
+
+// UserCanRead reports whether the user may see the column's values.
+func (c *ColumnDefinition) UserCanRead(user *User) bool {
+	return user.HasAnyRole(c.ReadRoles)
+}
+
+// hideUnreadableColumns uklanja iz zapisa kolone koje korisnik ne sme da vidi.
+func hideUnreadableColumns(moduleDef *ModuleDefinition, user *User, records ...map[string]interface{}) {
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if colDef.UserCanRead(user) {
+			continue
+		}
+		for _, record := range records {
+			delete(record, colDef.DBColumnName)
+		}
+	}
+}
+
+// hideUnreadableChanges uklanja iz audit zapisa izmene kolona koje korisnik ne sme da vidi.
+func hideUnreadableChanges(moduleDef *ModuleDefinition, user *User, entries []AuditEntry) {
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if colDef.UserCanRead(user) {
+			continue
+		}
+		for _, entry := range entries {
+			delete(entry.Changes, colDef.DBColumnName)
+		}
+	}
+}
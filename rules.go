@@ -0,0 +1,416 @@
+// rules.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidationRule is one validation rule of a column: parsed from the legacy string
+// ("min:5") or declared in the structured "rules" list of the column JSON.
+type ValidationRule struct {
+	Name   string                 `json:"name"`
+	Arg    string                 `json:"arg,omitempty"`    // Argument pravila (npr. "5" za min, obrazac za regex)
+	Params map[string]interface{} `json:"params,omitempty"` // Dodatni parametri (npr. {"field": "type", "value": "company"})
+}
+
+// parseValidationRules razlaže validacioni string ("required,min:5") na pravila.
+// Argument ne sme da sadrži zarez; za takve obrasce koristi se strukturirani format ("rules").
+func parseValidationRules(validation string) []ValidationRule {
+	var rules []ValidationRule
+	for _, rule := range strings.Split(validation, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(rule, ":")
+		rules = append(rules, ValidationRule{Name: name, Arg: arg})
+	}
+	return rules
+}
+
+// ValidationRules returns the column's rules: legacy validation string first, then structured rules.
+func (c *ColumnDefinition) ValidationRules() []ValidationRule {
+	return append(parseValidationRules(c.Validation), c.Rules...)
+}
+
+// IsRequired javlja da li kolona ima pravilo "required".
+func (c *ColumnDefinition) IsRequired() bool {
+	for _, rule := range c.ValidationRules() {
+		if rule.Name == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// param vraća parametar pravila po imenu; za "field" i "value" prihvata i oblik arg-a "field=value".
+func (r ValidationRule) param(name string) (interface{}, bool) {
+	if v, ok := r.Params[name]; ok {
+		return v, true
+	}
+	field, value, hasValue := strings.Cut(r.Arg, "=")
+	switch {
+	case name == "field" && r.Arg != "":
+		return field, true
+	case name == "value" && hasValue:
+		return value, true
+	}
+	return nil, false
+}
+
+// otherField vraća polje na koje se odnosi pravilo koje poredi više polja.
+func (r ValidationRule) otherField() string {
+	if v, ok := r.param("field"); ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// RuleContext is passed to a validation rule: the value, the whole payload and,
+// for database rules, access to the dataset.
+type RuleContext struct {
+	Context  context.Context
+	Config   *AppConfig
+	Dataset  *SQLDataset       // nil kada validacija nema pristup bazi (npr. validatePayload)
+	Module   *ModuleDefinition // nil za parametre akcija
+	RecordID string            // ID zapisa koji se menja; prazno pri kreiranju
+	Payload  map[string]interface{}
+	Column   *ColumnDefinition
+	Value    interface{} // nil kada polje nije poslato ili je null
+	Rule     ValidationRule
+
+	run *validationRun
+}
+
+// Field returns the value of another field: iz payload-a, a kod delimične izmene
+// iz postojećeg zapisa. False ako vrednost nije poznata.
+func (rc *RuleContext) Field(name string) (interface{}, bool) {
+	if v, ok := rc.Payload[name]; ok {
+		return v, true
+	}
+	return rc.run.existingValue(rc.Context, name)
+}
+
+// Fail reports that the rule is not satisfied; poruka je "validation.<pravilo>" iz kataloga.
+func (rc *RuleContext) Fail(params map[string]interface{}) error {
+	return rc.FailKey("validation."+rc.Rule.Name, params)
+}
+
+// FailKey reports that the rule is not satisfied with an explicit message key.
+func (rc *RuleContext) FailKey(key string, params map[string]interface{}) error {
+	return &ruleFailure{key: key, params: params}
+}
+
+// ruleFailure je neispunjeno pravilo; ostale greške pravila su greške same provere (npr. baze).
+type ruleFailure struct {
+	key    string
+	params map[string]interface{}
+}
+
+func (f *ruleFailure) Error() string { return f.key }
+
+// RuleFunc checks one rule. Vraća nil kada je pravilo ispunjeno, rc.Fail(...) kada nije,
+// a drugu grešku ako sama provera nije uspela.
+type RuleFunc func(rc *RuleContext) error
+
+// RuleOptions controls when a registered rule runs.
+type RuleOptions struct {
+	OnEmpty    bool // Izvršava se i kada vrednost nije poslata ili je null (npr. required_if)
+	CrossField bool // Poredi sa drugim poljem (param "field"); kod delimične izmene se proverava i kada je poslato samo to polje
+	Database   bool // Čita bazu; izvršava se samo uz pristup bazi i ako su ostala pravila kolone ispunjena
+}
+
+type registeredRule struct {
+	fn   RuleFunc
+	opts RuleOptions
+}
+
+// RuleRegistry stores named validation rules usable from module JSON.
+type RuleRegistry struct {
+	mu    sync.RWMutex
+	rules map[string]registeredRule
+}
+
+// NewRuleRegistry creates a registry with the built-in rules.
+func NewRuleRegistry() *RuleRegistry {
+	r := &RuleRegistry{rules: make(map[string]registeredRule)}
+	r.Register("required_if", ruleRequiredIf, RuleOptions{OnEmpty: true, CrossField: true})
+	r.Register("min", ruleMin, RuleOptions{})
+	r.Register("max", ruleMax, RuleOptions{})
+	r.Register("email", ruleEmail, RuleOptions{})
+	r.Register("regex", ruleRegex, RuleOptions{})
+	r.Register("gt_field", compareFieldRule(func(c int) bool { return c > 0 }), RuleOptions{CrossField: true})
+	r.Register("gte_field", compareFieldRule(func(c int) bool { return c >= 0 }), RuleOptions{CrossField: true})
+	r.Register("lt_field", compareFieldRule(func(c int) bool { return c < 0 }), RuleOptions{CrossField: true})
+	r.Register("lte_field", compareFieldRule(func(c int) bool { return c <= 0 }), RuleOptions{CrossField: true})
+	r.Register("unique", ruleUnique, RuleOptions{Database: true})
+	r.Register("exists", ruleExists, RuleOptions{Database: true})
+	return r
+}
+
+// Register adds (or replaces) a named rule.
+func (r *RuleRegistry) Register(name string, fn RuleFunc, opts RuleOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules[name] = registeredRule{fn: fn, opts: opts}
+}
+
+// get vraća registrovano pravilo po imenu.
+func (r *RuleRegistry) get(name string) (registeredRule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rule, ok := r.rules[name]
+	return rule, ok
+}
+
+// ruleRequiredIf: polje je obavezno kada drugo polje ima zadatu vrednost ("required_if:type=company").
+func ruleRequiredIf(rc *RuleContext) error {
+	if rc.Value != nil {
+		return nil
+	}
+	other := rc.Rule.otherField()
+	expected, _ := rc.Rule.param("value")
+	actual, ok := rc.Field(other)
+	if !ok || actual == nil || fmt.Sprint(actual) != fmt.Sprint(expected) {
+		return nil
+	}
+	return rc.Fail(map[string]interface{}{"other": other, "value": expected})
+}
+
+// ruleMin: najmanja vrednost broja, odnosno najmanja dužina stringa.
+func ruleMin(rc *RuleContext) error {
+	minVal, err := strconv.ParseFloat(rc.Rule.Arg, 64)
+	if err != nil {
+		log.Printf("ERROR: Greška pri parsiranju min validacije '%s' za '%s': %v", rc.Rule.Arg, rc.Column.Name, err)
+		return nil
+	}
+	params := map[string]interface{}{"min": minVal}
+	switch v := rc.Value.(type) {
+	case float64:
+		if v < minVal {
+			return rc.Fail(params)
+		}
+	case string:
+		if float64(len(v)) < minVal {
+			return rc.FailKey("validation.min_length", params)
+		}
+	}
+	return nil
+}
+
+// ruleMax: najveća vrednost broja, odnosno najveća dužina stringa.
+func ruleMax(rc *RuleContext) error {
+	maxVal, err := strconv.ParseFloat(rc.Rule.Arg, 64)
+	if err != nil {
+		log.Printf("ERROR: Greška pri parsiranju max validacije '%s' za '%s': %v", rc.Rule.Arg, rc.Column.Name, err)
+		return nil
+	}
+	params := map[string]interface{}{"max": maxVal}
+	switch v := rc.Value.(type) {
+	case float64:
+		if v > maxVal {
+			return rc.Fail(params)
+		}
+	case string:
+		if float64(len(v)) > maxVal {
+			return rc.FailKey("validation.max_length", params)
+		}
+	}
+	return nil
+}
+
+// ruleEmail proverava format email adrese.
+func ruleEmail(rc *RuleContext) error {
+	vStr, ok := rc.Value.(string)
+	if !ok {
+		return nil
+	}
+	re, found := rc.Config.GetCompiledRegex("emailValidation")
+	if !found {
+		log.Printf("ERROR: Kompilirani email regex nije pronađen. Proverite AppConfig.CompileRegexes().")
+		return nil
+	}
+	if !re.MatchString(vStr) {
+		return rc.Fail(nil)
+	}
+	return nil
+}
+
+// ruleRegex proverava string prema obrascu (kompiliran pri učitavanju modula).
+func ruleRegex(rc *RuleContext) error {
+	vStr, ok := rc.Value.(string)
+	if !ok {
+		return nil
+	}
+	re, found := rc.Config.GetCompiledRegex(rc.Rule.Arg)
+	if !found {
+		log.Printf("ERROR: Regex '%s' nije kompilovan za kolonu '%s'. Proverite config.go CompileRegexes.", rc.Rule.Arg, rc.Column.Name)
+		return nil
+	}
+	if !re.MatchString(vStr) {
+		return rc.Fail(map[string]interface{}{"pattern": rc.Rule.Arg})
+	}
+	return nil
+}
+
+// compareFieldRule pravi pravilo koje poredi vrednost sa drugim poljem (npr. end_date > start_date).
+// Pravilo se preskače ako vrednost drugog polja nije poznata ili vrednosti nisu uporedive.
+func compareFieldRule(ok func(cmp int) bool) RuleFunc {
+	return func(rc *RuleContext) error {
+		other := rc.Rule.otherField()
+		otherVal, known := rc.Field(other)
+		if rc.Value == nil || !known || otherVal == nil {
+			return nil
+		}
+		cmp, comparable := compareValues(rc.Value, otherVal)
+		if comparable && !ok(cmp) {
+			return rc.Fail(map[string]interface{}{"other": other})
+		}
+		return nil
+	}
+}
+
+// comparisonTimeLayouts su formati datuma koje compareValues prepoznaje u stringovima.
+var comparisonTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// compareValues poredi dve vrednosti kao brojeve, datume ili stringove; false ako nisu uporedive.
+func compareValues(a, b interface{}) (int, bool) {
+	if fa, ok := comparableNumber(a); ok {
+		if fb, ok := comparableNumber(b); ok {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if ta, ok := comparableTime(a); ok {
+		if tb, ok := comparableTime(b); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if okA && okB {
+		return strings.Compare(sa, sb), true
+	}
+	return 0, false
+}
+
+// comparableNumber vraća broj iz JSON (float64) ili baze (int64).
+func comparableNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// comparableTime vraća vreme iz baze (time.Time) ili iz stringa u jednom od poznatih formata.
+func comparableTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		for _, layout := range comparisonTimeLayouts {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// ruleUnique: vrednost ne sme postojati u drugom (aktivnom) zapisu modula. Opcioni param
+// "with" (lista kolona) proverava jedinstvenost kombinacije, npr. šifra unutar firme.
+func ruleUnique(rc *RuleContext) error {
+	moduleDef := rc.Module
+	if moduleDef == nil || moduleDef.DBTableName == "" {
+		return nil
+	}
+	conditions := []string{fmt.Sprintf("%s = $1", rc.Column.DBColumnName)}
+	args := []interface{}{rc.Value}
+	var with []string
+	if list, ok := rc.Rule.Params["with"].([]interface{}); ok {
+		for _, item := range list {
+			col := fmt.Sprint(item)
+			val, known := rc.Field(col)
+			if !known {
+				return nil // Kombinacija nije poznata; proveriće je UNIQUE indeks baze
+			}
+			with = append(with, col)
+			if val == nil {
+				conditions = append(conditions, fmt.Sprintf("%s IS NULL", col))
+				continue
+			}
+			args = append(args, val)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", col, len(args)))
+		}
+	}
+	if pkCol := rc.Dataset.getPrimaryKeyColumn(moduleDef); pkCol != nil && rc.RecordID != "" {
+		args = append(args, rc.RecordID)
+		conditions = append(conditions, fmt.Sprintf("%s <> $%d", pkCol.DBColumnName, len(args)))
+	}
+	where := andSoftDeleteCondition(strings.Join(conditions, " AND "), moduleDef, ScopeActive)
+	found, err := rc.Dataset.rowExists(rc.Context, moduleDef.DBTableName, where, args...)
+	if err != nil || !found {
+		return err
+	}
+	params := map[string]interface{}(nil)
+	if len(with) > 0 {
+		params = map[string]interface{}{"with": with}
+	}
+	return rc.Fail(params)
+}
+
+// ruleExists: vrednost mora biti ID postojećeg (aktivnog i korisniku dostupnog) zapisa u modulu
+// iz arg-a, odnosno u lookup modulu kolone. Lookup kolone ga imaju i bez navođenja.
+func ruleExists(rc *RuleContext) error {
+	target := rc.Column.LookupModule
+	if rc.Rule.Arg != "" {
+		target = rc.Config.GetModuleByID(rc.Rule.Arg)
+	}
+	if target == nil || target.DBTableName == "" {
+		log.Printf("WARNING: Pravilo 'exists' za kolonu '%s' nema ciljni modul.", rc.Column.Name)
+		return nil
+	}
+	pkCol := rc.Dataset.getPrimaryKeyColumn(target)
+	if pkCol == nil {
+		return nil
+	}
+	// Referenca sme da upućuje samo na aktivan zapis koji korisnik vidi (row-level filter)
+	where, args, err := rc.Dataset.andRowFilter(rc.Context, andSoftDeleteCondition(fmt.Sprintf("%s = $1", pkCol.DBColumnName), target, ScopeActive), target, []interface{}{rc.Value})
+	if err != nil {
+		return err
+	}
+	found, err := rc.Dataset.rowExists(rc.Context, target.DBTableName, where, args...)
+	if err != nil || found {
+		return err
+	}
+	return rc.Fail(map[string]interface{}{"module": target.ID})
+}
+
+// rowExists javlja da li tabela ima red koji ispunjava uslov.
+func (s *SQLDataset) rowExists(ctx context.Context, table, where string, args ...interface{}) (bool, error) {
+	var one int
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE %s LIMIT 1", table, where)
+	err := s.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("greška pri proveri postojanja zapisa u tabeli '%s': %w", table, err)
+	}
+	return true, nil
+}
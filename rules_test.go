@@ -0,0 +1,166 @@
+// rules_test.go
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// failedRules vraća neispunjena pravila iz greške validacije kao sortiranu listu "polje:pravilo".
+func failedRules(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("očekivana ValidationErrors, dobijeno %T: %v", err, err)
+	}
+	var failed []string
+	for _, e := range errs {
+		failed = append(failed, e.Field+":"+e.Rule)
+	}
+	sort.Strings(failed)
+	return strings.Join(failed, ",")
+}
+
+func TestParseValidationRules(t *testing.T) {
+	got := parseValidationRules("required, min:5,,regex:^a:b$,required_if:type=company")
+	want := []ValidationRule{
+		{Name: "required"},
+		{Name: "min", Arg: "5"},
+		{Name: "regex", Arg: "^a:b$"},
+		{Name: "required_if", Arg: "type=company"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseValidationRules = %+v, očekivano %+v", got, want)
+	}
+
+	rule := want[3]
+	if field := rule.otherField(); field != "type" {
+		t.Errorf("otherField = %q, očekivano \"type\"", field)
+	}
+	if value, ok := rule.param("value"); !ok || value != "company" {
+		t.Errorf("param(value) = %v, %v", value, ok)
+	}
+}
+
+func TestValidatePayloadRules(t *testing.T) {
+	columns := []ColumnDefinition{
+		{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsReadOnly: true, Validation: "required"},
+		{DBColumnName: "type", Name: "Tip", Type: "string", Validation: "required"},
+		{DBColumnName: "company_name", Name: "Firma", Type: "string", Validation: "required_if:type=company,max:10"},
+		{DBColumnName: "email", Name: "Email", Type: "string", Validation: "email"},
+		{DBColumnName: "age", Name: "Godine", Type: "integer", Validation: "min:18,max:99"},
+		{DBColumnName: "code", Name: "Šifra", Type: "string", Rules: []ValidationRule{{Name: "regex", Arg: "^[0-9]{2,4}$"}}},
+		{DBColumnName: "start_date", Name: "Početak", Type: "date"},
+		{DBColumnName: "end_date", Name: "Kraj", Type: "date", Validation: "gte_field:start_date"},
+		{DBColumnName: "discount", Name: "Popust", Type: "float", Rules: []ValidationRule{{Name: "lt_field", Params: map[string]interface{}{"field": "price"}}}},
+		{DBColumnName: "price", Name: "Cena", Type: "float"},
+	}
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{"test_rules": {ID: "test_rules", Columns: columns}}, Rules: NewRuleRegistry()}
+	cfg.CompileRegexes()
+	if _, ok := cfg.GetCompiledRegex("^[0-9]{2,4}$"); !ok {
+		t.Fatal("regex sa zarezom iz strukturiranih pravila nije kompiliran")
+	}
+
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    string
+	}{
+		{"ispravan", map[string]interface{}{"type": "person", "email": "ana@example.com", "age": 30.0, "code": "123"}, ""},
+		{"nedostaje obavezno", map[string]interface{}{}, "type:required"},
+		{"required_if ispunjen uslov", map[string]interface{}{"type": "company"}, "company_name:required_if"},
+		{"required_if drugi tip", map[string]interface{}{"type": "person", "company_name": nil}, ""},
+		{"dužina stringa", map[string]interface{}{"type": "company", "company_name": "Predugačak naziv"}, "company_name:max"},
+		{"email", map[string]interface{}{"type": "person", "email": "nije-email"}, "email:email"},
+		{"opseg broja", map[string]interface{}{"type": "person", "age": 12.0}, "age:min"},
+		{"pogrešan tip preskače pravila", map[string]interface{}{"type": "person", "age": 12.5}, "age:type"},
+		{"regex sa zarezom", map[string]interface{}{"type": "person", "code": "12345"}, "code:regex"},
+		{"datum pre početka", map[string]interface{}{"type": "person", "start_date": "2024-05-10", "end_date": "2024-05-01"}, "end_date:gte_field"},
+		{"isti datum", map[string]interface{}{"type": "person", "start_date": "2024-05-10", "end_date": "2024-05-10"}, ""},
+		{"nepoznato drugo polje", map[string]interface{}{"type": "person", "end_date": "2024-05-01"}, ""},
+		{"poređenje brojeva", map[string]interface{}{"type": "person", "discount": 20.0, "price": 10.0}, "discount:lt_field"},
+		{"sve greške odjednom", map[string]interface{}{"email": "x", "age": 100.0}, "age:max,email:email,type:required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failedRules(t, validatePayload(tt.payload, columns, cfg)); got != tt.want {
+				t.Errorf("greške %q, očekivano %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleRegistryCustomRule(t *testing.T) {
+	cfg := &AppConfig{Rules: NewRuleRegistry()}
+	cfg.Rules.Register("even", func(rc *RuleContext) error {
+		if n, ok := rc.Value.(float64); ok && int(n)%2 != 0 {
+			return rc.Fail(nil)
+		}
+		return nil
+	}, RuleOptions{})
+	columns := []ColumnDefinition{
+		{DBColumnName: "count", Name: "Broj", Type: "integer", Validation: "even,nepoznato"},
+	}
+
+	if got := failedRules(t, validatePayload(map[string]interface{}{"count": 3.0}, columns, cfg)); got != "count:even" {
+		t.Errorf("neparan broj: greške %q", got)
+	}
+	if err := validatePayload(map[string]interface{}{"count": 4.0}, columns, cfg); err != nil {
+		t.Errorf("paran broj: %v", err)
+	}
+	if _, ok := cfg.Rules.get("even"); !ok {
+		t.Error("registrovano pravilo nije pronađeno")
+	}
+}
+
+func TestDatabaseRules(t *testing.T) {
+	companies := &ModuleDefinition{ID: "test_rule_companies", Name: "Firme", Type: "table", DBTableName: "test_rule_companies",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsReadOnly: true},
+			{DBColumnName: "name", Name: "Naziv", Type: "string"},
+		}}
+	products := &ModuleDefinition{ID: "test_rule_products", Name: "Proizvodi", Type: "table", DBTableName: "test_rule_products",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsReadOnly: true},
+			{DBColumnName: "company_id", Name: "Firma", Type: "lookup", LookupModuleID: companies.ID, LookupModule: companies, LookupDisplayField: "name"},
+			{DBColumnName: "sku", Name: "Šifra", Type: "string", Rules: []ValidationRule{{Name: "unique", Params: map[string]interface{}{"with": []interface{}{"company_id"}}}}},
+			{DBColumnName: "barcode", Name: "Barkod", Type: "string", Validation: "unique"},
+		}}
+	ds := newTestDataset(t, companies, products)
+	createTestTable(t, ds, companies.DBTableName, "id SERIAL PRIMARY KEY, name TEXT")
+	createTestTable(t, ds, products.DBTableName, "id SERIAL PRIMARY KEY, company_id INT, sku TEXT, barcode TEXT")
+	if _, err := ds.db.Exec(`INSERT INTO test_rule_companies (id, name) VALUES (1, 'Prva'), (2, 'Druga');
+		INSERT INTO test_rule_products (id, company_id, sku, barcode) VALUES (1, 1, 'A-1', '111')`); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		recordID string
+		payload  map[string]interface{}
+		want     string
+	}{
+		{"nova šifra", "", map[string]interface{}{"company_id": 1.0, "sku": "A-2", "barcode": "222"}, ""},
+		{"ista šifra u istoj firmi", "", map[string]interface{}{"company_id": 1.0, "sku": "A-1"}, "sku:unique"},
+		{"ista šifra u drugoj firmi", "", map[string]interface{}{"company_id": 2.0, "sku": "A-1"}, ""},
+		{"postojeći barkod", "", map[string]interface{}{"barcode": "111"}, "barcode:unique"},
+		{"izmena istog zapisa", "1", map[string]interface{}{"company_id": 1.0, "sku": "A-1", "barcode": "111"}, ""},
+		{"nepostojeća firma", "", map[string]interface{}{"company_id": 99.0}, "company_id:exists"},
+		{"lookup u obliku objekta", "", map[string]interface{}{"company_id": map[string]interface{}{"id": 2.0, "name": "Druga"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ds.validateRecord(ctx, validationTarget{Module: products, RecordID: tt.recordID}, tt.payload)
+			if got := failedRules(t, err); got != tt.want {
+				t.Errorf("greške %q, očekivano %q", got, tt.want)
+			}
+		})
+	}
+}
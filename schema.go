@@ -0,0 +1,225 @@
+// schema.go
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ModuleSchema is the client-facing definition of a module used to build grids and forms.
+type ModuleSchema struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Type         string            `json:"type"`
+	Description  string            `json:"description,omitempty"`
+	DisplayField string            `json:"display_field,omitempty"`
+	PrimaryKey   string            `json:"primary_key,omitempty"`
+	Columns      []ColumnSchema    `json:"columns"`
+	SubModules   []SubModuleSchema `json:"sub_modules"`
+	Operations   []string          `json:"operations"` // Operacije koje korisnik sme da izvrši
+	Actions      []ActionSchema    `json:"actions,omitempty"`
+	UniqueKeys   [][]string        `json:"unique_keys,omitempty"`
+	SoftDelete   bool              `json:"soft_delete"`
+	Versioned    bool              `json:"versioned"` // Zapisi imaju ETag i prihvataju If-Match
+}
+
+// ColumnSchema describes a single column of a module.
+type ColumnSchema struct {
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	Field        string           `json:"field"` // Ključ u zapisima (DB kolona)
+	Type         string           `json:"type"`
+	IsPrimaryKey bool             `json:"is_primary_key"`
+	IsVisible    bool             `json:"is_visible"`
+	IsEditable   bool             `json:"is_editable"`
+	IsSearchable bool             `json:"is_searchable"`
+	IsSortable   bool             `json:"is_sortable"`
+	Required     bool             `json:"required"`
+	Rules        []ValidationRule `json:"rules,omitempty"`
+	DefaultValue interface{}      `json:"default_value,omitempty"`
+	Auto         string           `json:"auto,omitempty"`
+	Lookup       *LookupSchema    `json:"lookup,omitempty"`
+	Choices      []ChoiceSchema   `json:"choices,omitempty"`
+}
+
+// ChoiceSchema is an allowed column value with its label in the requested language.
+type ChoiceSchema struct {
+	Value interface{} `json:"value"`
+	Label string      `json:"label"`
+}
+
+// LookupSchema describes the target of a lookup column.
+type LookupSchema struct {
+	ModuleID        string   `json:"module_id"`
+	ValueField      string   `json:"value_field"`                // Primarni ključ lookup modula
+	DisplayField    string   `json:"display_field"`              // Polje koje se prikazuje korisniku
+	DisplayTemplate string   `json:"display_template,omitempty"` // Šablon prikaza iz više polja
+	Fields          []string `json:"fields,omitempty"`           // Dodatna polja u proširenoj vrednosti
+	// Pretraga opcija i zavisni filteri (kolona lookup modula -> polje forme)
+	SearchURL string            `json:"search_url,omitempty"` // Samo za kolone modula, ne i za parametre akcija
+	Filters   map[string]string `json:"filters,omitempty"`
+}
+
+// SubModuleSchema describes a child module shown under a record.
+type SubModuleSchema struct {
+	ModuleID             string `json:"module_id"`
+	DisplayName          string `json:"display_name"`
+	DisplayOrder         int    `json:"display_order"`
+	ChildForeignKeyField string `json:"child_foreign_key_field"`
+}
+
+// ActionSchema describes a server action available to the user.
+type ActionSchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	OnRecord    bool           `json:"on_record"`
+	Params      []ColumnSchema `json:"params,omitempty"`
+}
+
+// columnSchema pravi opis kolone na jeziku lang; editable je false ako korisnik ne sme da menja zapise.
+func (s *APIServer) columnSchema(colDef *ColumnDefinition, canWrite bool, lang string) ColumnSchema {
+	col := ColumnSchema{
+		ID:           colDef.ID,
+		Name:         localize(colDef.Name, colDef.NameI18n, lang),
+		Field:        colDef.DBColumnName,
+		Type:         colDef.Type,
+		IsPrimaryKey: colDef.IsPrimaryKey,
+		IsVisible:    colDef.IsVisible,
+		IsEditable:   canWrite && colDef.IsEditable && !colDef.IsReadOnly && !colDef.IsAuto(),
+		IsSearchable: colDef.IsSearchable,
+		IsSortable:   colDef.IsSortable,
+		Rules:        colDef.ValidationRules(),
+		DefaultValue: colDef.DefaultValue,
+		Auto:         colDef.Auto,
+		Required:     colDef.IsRequired(),
+	}
+	if colDef.Type == "lookup" && colDef.LookupModule != nil {
+		lookup := &LookupSchema{
+			ModuleID:        colDef.LookupModuleID,
+			DisplayField:    colDef.LookupDisplayField,
+			DisplayTemplate: colDef.LookupDisplayTemplate,
+			Fields:          colDef.LookupFields,
+			Filters:         colDef.LookupFilters,
+		}
+		if pk := s.dataset.getPrimaryKeyColumn(colDef.LookupModule); pk != nil {
+			lookup.ValueField = pk.DBColumnName
+		}
+		col.Lookup = lookup
+	}
+	for _, choice := range colDef.Choices {
+		col.Choices = append(col.Choices, ChoiceSchema{Value: choice.Value, Label: localize(choice.Label, choice.LabelI18n, lang)})
+	}
+	return col
+}
+
+// BuildModuleSchema returns the module definition as seen by the given user, with labels in lang:
+// bez kolona koje ne sme da čita, podmodula koje ne sme da vidi i akcija koje ne sme da pokrene.
+func (s *APIServer) BuildModuleSchema(moduleDef *ModuleDefinition, user *User, lang string) *ModuleSchema {
+	schema := &ModuleSchema{
+		ID:           moduleDef.ID,
+		Name:         localize(moduleDef.Name, moduleDef.NameI18n, lang),
+		Type:         moduleDef.Type,
+		Description:  localize(moduleDef.Description, moduleDef.DescriptionI18n, lang),
+		DisplayField: moduleDef.DisplayField,
+		Columns:      []ColumnSchema{},
+		SubModules:   []SubModuleSchema{},
+		Operations:   []string{},
+		UniqueKeys:   moduleDef.UniqueKeys,
+		SoftDelete:   moduleDef.HasSoftDelete(),
+		Versioned:    moduleDef.HasVersion(),
+	}
+	if pk := s.dataset.getPrimaryKeyColumn(moduleDef); pk != nil {
+		schema.PrimaryKey = pk.DBColumnName
+	}
+
+	operations := []string{PermRead}
+	if moduleDef.Type == "table" {
+		operations = append(operations, PermCreate, PermUpdate, PermDelete)
+	}
+	for _, op := range operations {
+		if moduleDef.UserCan(user, op) {
+			schema.Operations = append(schema.Operations, op)
+		}
+	}
+	canWrite := moduleDef.Type == "table" && (moduleDef.UserCan(user, PermCreate) || moduleDef.UserCan(user, PermUpdate))
+
+	for i := range moduleDef.Columns {
+		colDef := &moduleDef.Columns[i]
+		if !colDef.UserCanRead(user) {
+			continue
+		}
+		col := s.columnSchema(colDef, canWrite, lang)
+		if col.Lookup != nil {
+			col.Lookup.SearchURL = fmt.Sprintf("/api/modules/%s/lookups/%s", moduleDef.ID, colDef.DBColumnName)
+		}
+		schema.Columns = append(schema.Columns, col)
+	}
+
+	for _, sub := range moduleDef.SubModules {
+		if sub.TargetModule == nil || !sub.TargetModule.UserCan(user, PermRead) {
+			continue
+		}
+		schema.SubModules = append(schema.SubModules, SubModuleSchema{
+			ModuleID:             sub.TargetModuleID,
+			DisplayName:          localize(sub.DisplayName, sub.DisplayNameI18n, lang),
+			DisplayOrder:         sub.DisplayOrder,
+			ChildForeignKeyField: sub.ChildForeignKeyField,
+		})
+	}
+	sort.SliceStable(schema.SubModules, func(i, j int) bool {
+		return schema.SubModules[i].DisplayOrder < schema.SubModules[j].DisplayOrder
+	})
+
+	for _, action := range s.Actions.ListAllowed(moduleDef.ID, user) {
+		actionSchema := ActionSchema{Name: action.Name, Description: action.Description, OnRecord: action.OnRecord}
+		for i := range action.Params {
+			actionSchema.Params = append(actionSchema.Params, s.columnSchema(&action.Params[i], true, lang))
+		}
+		schema.Actions = append(schema.Actions, actionSchema)
+	}
+	return schema
+}
+
+// GetModuleSchema handles GET /api/modules/{moduleID}/schema. ETag je heš sadržaja, pa
+// se menja sa definicijom modula, dozvolama korisnika i jezikom (Accept-Language).
+func (s *APIServer) GetModuleSchema(w http.ResponseWriter, req *http.Request) {
+	moduleID := mux.Vars(req)["moduleID"]
+
+	moduleDef := s.config.GetModuleByID(moduleID)
+	if moduleDef == nil {
+		writeError(w, fmt.Sprintf("Modul sa ID '%s' nije pronađen.", moduleID), http.StatusNotFound)
+		return
+	}
+	if !s.authorize(w, req, moduleDef, PermRead) {
+		return
+	}
+
+	body, err := json.Marshal(s.BuildModuleSchema(moduleDef, UserFromContext(req.Context()), languageFromContext(req.Context())))
+	if err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju šeme modula '%s': %v", moduleID, err)
+		writeError(w, internalErrorMessage, http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16]))
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Vary", strings.Join([]string{userIDHeader, userRolesHeader, "Accept-Language"}, ", "))
+	if inm := req.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+	log.Printf("INFO: Vraćena šema modula '%s'.", moduleID)
+}
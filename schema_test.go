@@ -0,0 +1,130 @@
+// schema_test.go
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testSchemaServer pravi server bez baze sa modulom zaposlenih, podmodulom i akcijama.
+func testSchemaServer(t *testing.T) *APIServer {
+	t.Helper()
+	reviews := &ModuleDefinition{ID: "test_reviews", Name: "Ocene", Type: "table", DBTableName: "test_reviews",
+		Permissions: map[string][]string{PermRead: {"hr"}},
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true},
+			{DBColumnName: "employee_id", Name: "Zaposleni", Type: "integer"},
+		}}
+	employees := testEmployeesModule()
+	employees.NameI18n = map[string]string{"en": "Employees"}
+	employees.Permissions = map[string][]string{PermCreate: {"hr"}, PermUpdate: {"hr"}, PermDelete: {"admin"}}
+	employees.SubModules = []SubModuleDefinition{{ID: "reviews", DisplayName: "Ocene", TargetModuleID: reviews.ID, TargetModule: reviews, ChildForeignKeyField: "employee_id"}}
+	cfg := &AppConfig{Modules: map[string]*ModuleDefinition{employees.ID: employees, reviews.ID: reviews}, Rules: NewRuleRegistry()}
+	s := NewAPIServer(cfg, &SQLDataset{config: cfg, Hooks: NewHookRegistry(), Broker: NewChangeBroker()})
+
+	noop := func(ctx context.Context, call *ActionCall) (interface{}, error) { return nil, nil }
+	for _, action := range []ActionDefinition{
+		{Name: "izvestaj", Handler: noop},
+		{Name: "otpusti", Handler: noop, Roles: []string{"admin"}},
+	} {
+		if err := s.Actions.Register(employees.ID, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestBuildModuleSchemaFiltersByPermissions(t *testing.T) {
+	s := testSchemaServer(t)
+	module := s.config.GetModuleByID("test_employees")
+
+	tests := []struct {
+		user       *User
+		operations string
+		columns    string
+		subModules string
+		actions    string
+		editable   bool
+	}{
+		{&User{ID: "ana", Roles: []string{"staff"}}, "read", "id,name,version", "", "izvestaj", false},
+		{&User{ID: "hr", Roles: []string{"hr"}}, "read,create,update", "id,name,salary,version", "test_reviews", "izvestaj", true},
+		{&User{ID: "root", Roles: []string{"admin"}}, "read,delete", "id,name,version", "", "izvestaj,otpusti", false},
+	}
+	for _, tt := range tests {
+		schema := s.BuildModuleSchema(module, tt.user, sourceLanguage)
+
+		var columns, subModules, actions []string
+		editable := false
+		for _, col := range schema.Columns {
+			columns = append(columns, col.Field)
+			if col.Field == "name" {
+				editable = col.IsEditable
+			}
+		}
+		for _, sub := range schema.SubModules {
+			subModules = append(subModules, sub.ModuleID)
+		}
+		for _, action := range schema.Actions {
+			actions = append(actions, action.Name)
+		}
+
+		got := []string{strings.Join(schema.Operations, ","), strings.Join(columns, ","), strings.Join(subModules, ","), strings.Join(actions, ",")}
+		want := []string{tt.operations, tt.columns, tt.subModules, tt.actions}
+		for i, label := range []string{"operacije", "kolone", "podmoduli", "akcije"} {
+			if got[i] != want[i] {
+				t.Errorf("%s: %s %q, očekivano %q", tt.user.ID, label, got[i], want[i])
+			}
+		}
+		if editable != tt.editable {
+			t.Errorf("%s: kolona name is_editable = %v, očekivano %v", tt.user.ID, editable, tt.editable)
+		}
+		if schema.PrimaryKey != "id" || !schema.Versioned || schema.SoftDelete {
+			t.Errorf("%s: primarni ključ %q, versioned %v, soft_delete %v", tt.user.ID, schema.PrimaryKey, schema.Versioned, schema.SoftDelete)
+		}
+	}
+}
+
+func TestGetModuleSchemaETag(t *testing.T) {
+	s := testSchemaServer(t)
+	path := "/api/modules/test_employees/schema"
+
+	rec := serveTest(s, http.MethodGet, path, "", "staff", nil)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("šema: status %d, ETag %q", rec.Code, etag)
+	}
+	if again := serveTest(s, http.MethodGet, path, "", "staff", nil); again.Header().Get("ETag") != etag {
+		t.Errorf("ETag nije stabilan: %q, pa %q", etag, again.Header().Get("ETag"))
+	}
+
+	tests := []struct {
+		name   string
+		roles  string
+		header map[string]string
+		status int
+	}{
+		{"isti ETag", "staff", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"lista ETag-ova", "staff", map[string]string{"If-None-Match": `"drugi", ` + etag}, http.StatusNotModified},
+		{"zastareo ETag", "staff", map[string]string{"If-None-Match": `"drugi"`}, http.StatusOK},
+		{"druge dozvole", "hr", map[string]string{"If-None-Match": etag}, http.StatusOK},
+		{"drugi jezik", "staff", map[string]string{"If-None-Match": etag, "Accept-Language": "en"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := serveTest(s, http.MethodGet, path, "", tt.roles, tt.header)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, očekivano %d", tt.name, rec.Code, tt.status)
+		}
+		if rec.Code == http.StatusNotModified && rec.Body.Len() > 0 {
+			t.Errorf("%s: 304 sa telom %s", tt.name, rec.Body)
+		}
+	}
+
+	if rec := serveTest(s, http.MethodGet, "/api/modules/test_reviews/schema", "", "staff", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("šema modula bez dozvole za čitanje: status %d, očekivano 403", rec.Code)
+	}
+	if rec := serveTest(s, http.MethodGet, "/api/modules/nepostojeci/schema", "", "staff", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("šema nepostojećeg modula: status %d, očekivano 404", rec.Code)
+	}
+}
@@ -0,0 +1,178 @@
+// softdelete.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+)
+
+// AuditRestore je operacija kojom se vraća logički obrisan zapis.
+const AuditRestore = "restore"
+
+// DeletedScope controls whether soft-deleted rows are visible to a read.
+type DeletedScope int
+
+const (
+	ScopeActive      DeletedScope = iota // Samo neobrisani zapisi (podrazumevano)
+	ScopeWithDeleted                     // I obrisani i neobrisani zapisi
+	ScopeOnlyDeleted                     // Samo obrisani zapisi ("kanta")
+)
+
+// deletedScopeFromQuery čita _with_deleted/_only_deleted parametre upita.
+func deletedScopeFromQuery(queryParams url.Values) DeletedScope {
+	if flagParam(queryParams, "_only_deleted") {
+		return ScopeOnlyDeleted
+	}
+	if flagParam(queryParams, "_with_deleted") {
+		return ScopeWithDeleted
+	}
+	return ScopeActive
+}
+
+// flagParam čita logički parametar upita; parametar bez vrednosti (npr. "?_with_deleted") je true.
+func flagParam(queryParams url.Values, name string) bool {
+	values, ok := queryParams[name]
+	if !ok {
+		return false
+	}
+	if len(values) == 0 || values[0] == "" {
+		return true
+	}
+	b, err := strconv.ParseBool(values[0])
+	return err == nil && b
+}
+
+// HasSoftDelete reports whether the module marks rows as deleted instead of removing them.
+func (m *ModuleDefinition) HasSoftDelete() bool {
+	return m.DeletedAtColumn != ""
+}
+
+// softDeleteCondition vraća WHERE uslov za traženi opseg, ili prazan string ako
+// modul nema soft delete ili opseg uključuje sve zapise.
+func softDeleteCondition(moduleDef *ModuleDefinition, scope DeletedScope) string {
+	if !moduleDef.HasSoftDelete() {
+		return ""
+	}
+	switch scope {
+	case ScopeOnlyDeleted:
+		return fmt.Sprintf("%s IS NOT NULL", moduleDef.DeletedAtColumn)
+	case ScopeWithDeleted:
+		return ""
+	default:
+		return fmt.Sprintf("%s IS NULL", moduleDef.DeletedAtColumn)
+	}
+}
+
+// andSoftDeleteCondition dodaje uslov za opseg na postojeći WHERE uslov.
+func andSoftDeleteCondition(where string, moduleDef *ModuleDefinition, scope DeletedScope) string {
+	if cond := softDeleteCondition(moduleDef, scope); cond != "" {
+		return where + " AND " + cond
+	}
+	return where
+}
+
+// softDeleteSetClause vraća SET izraze za soft delete; ID korisnika (ako modul
+// ima DeletedByColumn) dodaje se u args.
+func softDeleteSetClause(ctx context.Context, moduleDef *ModuleDefinition, args *[]interface{}) string {
+	setClauses := fmt.Sprintf("%s = now()", moduleDef.DeletedAtColumn)
+	if moduleDef.DeletedByColumn != "" {
+		var userID interface{}
+		if id := userIDFromContext(ctx); id != "" {
+			userID = id
+		}
+		*args = append(*args, userID)
+		setClauses += fmt.Sprintf(", %s = $%d", moduleDef.DeletedByColumn, len(*args))
+	}
+	if clause := versionSetClause(moduleDef); clause != "" {
+		setClauses += ", " + clause
+	}
+	return setClauses
+}
+
+// softDeleteRecordTx označava zapis kao obrisan umesto da ga ukloni iz tabele.
+func (s *SQLDataset) softDeleteRecordTx(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, recordID string, ifMatch string) error {
+	before, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeActive)
+	if err == sql.ErrNoRows {
+		return errRecordNotFound("zapis sa ID '%s' nije pronađen ili obrisan u modulu '%s'", recordID, moduleDef.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(moduleDef, recordID, before, ifMatch); err != nil {
+		return err
+	}
+	if err := s.Hooks.runBeforeDelete(ctx, moduleDef, before); err != nil {
+		return err
+	}
+
+	args := []interface{}{recordID}
+	setClauses := softDeleteSetClause(ctx, moduleDef, &args)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $1%s RETURNING *",
+		moduleDef.DBTableName,
+		setClauses,
+		pkCol.DBColumnName,
+		versionCondition(moduleDef, before, &args),
+	)
+
+	log.Printf("DEBUG: Executing soft DELETE query: %s with values: %v", query, args)
+
+	after, err := queryRecord(ctx, tx, query, args...)
+	if err != nil {
+		return fmt.Errorf("greška pri izvršavanju soft DELETE upita za modul '%s', ID '%s': %w", moduleDef.Name, recordID, err)
+	}
+
+	if err := s.Hooks.runAfterDelete(ctx, moduleDef, after); err != nil {
+		return err
+	}
+
+	return s.recordChange(ctx, tx, moduleDef, recordID, AuditDelete, before, after)
+}
+
+// RestoreRecord undeletes a soft-deleted record.
+func (s *SQLDataset) RestoreRecord(ctx context.Context, moduleDef *ModuleDefinition, recordID string) error {
+	if moduleDef.Type != "table" || !moduleDef.HasSoftDelete() {
+		return fmt.Errorf("vraćanje obrisanih zapisa nije podržano za modul '%s'", moduleDef.Name)
+	}
+
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return fmt.Errorf("modul '%s' nema definisan primarni ključ za vraćanje zapisa", moduleDef.Name)
+	}
+
+	setClauses := fmt.Sprintf("%s = NULL", moduleDef.DeletedAtColumn)
+	if moduleDef.DeletedByColumn != "" {
+		setClauses += fmt.Sprintf(", %s = NULL", moduleDef.DeletedByColumn)
+	}
+	if clause := versionSetClause(moduleDef); clause != "" {
+		setClauses += ", " + clause
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $1 RETURNING *",
+		moduleDef.DBTableName,
+		setClauses,
+		pkCol.DBColumnName,
+	)
+
+	log.Printf("DEBUG: Executing RESTORE query: %s with ID: %s", query, recordID)
+
+	return s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		before, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeOnlyDeleted)
+		if err == sql.ErrNoRows {
+			return errRecordNotFound("obrisan zapis sa ID '%s' nije pronađen u modulu '%s'", recordID, moduleDef.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		after, err := queryRecord(ctx, tx, query, recordID)
+		if err != nil {
+			return fmt.Errorf("greška pri izvršavanju RESTORE upita za modul '%s', ID '%s': %w", moduleDef.Name, recordID, err)
+		}
+
+		return s.recordChange(ctx, tx, moduleDef, recordID, AuditRestore, before, after)
+	})
+}
@@ -0,0 +1,93 @@
+// softdelete_test.go
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSoftDeleteScopesAndRestore(t *testing.T) {
+	notes := &ModuleDefinition{ID: "test_sd_notes", Name: "Beleške", Type: "table", DBTableName: "test_sd_notes", DeletedAtColumn: "deleted_at",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "customer_id", Name: "Kupac", Type: "integer", IsVisible: true},
+			{DBColumnName: "text", Name: "Tekst", Type: "string", IsVisible: true},
+		}}
+	customers := &ModuleDefinition{ID: "test_sd_customers", Name: "Kupci", Type: "table", DBTableName: "test_sd_customers", DeletedAtColumn: "deleted_at",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "name", Name: "Naziv", Type: "string", IsVisible: true},
+		},
+		SubModules: []SubModuleDefinition{{ID: "notes", DisplayName: "Beleške", TargetModuleID: notes.ID, ChildForeignKeyField: "customer_id"}}}
+	orders := &ModuleDefinition{ID: "test_sd_orders", Name: "Porudžbine", Type: "table", DBTableName: "test_sd_orders",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true},
+			{DBColumnName: "customer_id", Name: "Kupac", Type: "lookup", IsVisible: true, LookupModuleID: customers.ID, LookupModule: customers, LookupDisplayField: "name"},
+		}}
+	ds := newTestDataset(t, notes, customers, orders)
+	createTestTable(t, ds, customers.DBTableName, "id SERIAL PRIMARY KEY, name TEXT, deleted_at TIMESTAMPTZ")
+	createTestTable(t, ds, notes.DBTableName, "id SERIAL PRIMARY KEY, customer_id INT, text TEXT, deleted_at TIMESTAMPTZ")
+	createTestTable(t, ds, orders.DBTableName, "id SERIAL PRIMARY KEY, customer_id INT")
+	if _, err := ds.db.Exec(`INSERT INTO test_sd_customers (id, name) VALUES (1, 'Ana'), (2, 'Boris');
+		INSERT INTO test_sd_notes (id, customer_id, text) VALUES (1, 1, 'vidljiva'), (2, 1, 'uklonjena');
+		INSERT INTO test_sd_orders (customer_id) VALUES (2)`); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAPIServer(ds.config, ds)
+
+	for _, path := range []string{"/api/modules/test_sd_customers/2", "/api/modules/test_sd_notes/2"} {
+		if rec := serveTest(s, http.MethodDelete, path, "", "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("DELETE %s: status %d, telo %s", path, rec.Code, rec.Body)
+		}
+	}
+	var deleted int
+	if err := ds.db.QueryRow("SELECT count(*) FROM test_sd_customers WHERE deleted_at IS NOT NULL").Scan(&deleted); err != nil || deleted != 1 {
+		t.Errorf("soft delete je trajno obrisao zapis: %d, %v", deleted, err)
+	}
+
+	tests := []struct {
+		path     string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{"/api/modules/test_sd_customers/2", http.StatusNotFound, []string{`"not_found"`}, nil},
+		{"/api/modules/test_sd_customers/2?_with_deleted", http.StatusOK, []string{"Boris"}, nil},
+		{"/api/modules/test_sd_customers/1?_only_deleted", http.StatusNotFound, nil, nil},
+		{"/api/modules/test_sd_customers", http.StatusOK, []string{"Ana"}, []string{"Boris"}},
+		{"/api/modules/test_sd_customers?_only_deleted", http.StatusOK, []string{"Boris"}, []string{"Ana"}},
+		{"/api/modules/test_sd_customers?_with_deleted", http.StatusOK, []string{"Ana", "Boris"}, nil},
+		{"/api/modules/test_sd_customers/1", http.StatusOK, []string{"vidljiva"}, []string{"uklonjena"}},
+		{"/api/modules/test_sd_orders/lookups/customer_id?q=Bor", http.StatusOK, nil, []string{"Boris"}},
+		{"/api/modules/test_sd_orders/1", http.StatusOK, nil, []string{"Boris"}},
+	}
+	for _, tt := range tests {
+		rec := serveTest(s, http.MethodGet, tt.path, "", "", nil)
+		body := rec.Body.String()
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, očekivano %d; telo %s", tt.path, rec.Code, tt.status, body)
+			continue
+		}
+		for _, want := range tt.contains {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s: nedostaje %q u %s", tt.path, want, body)
+			}
+		}
+		for _, unwanted := range tt.excludes {
+			if strings.Contains(body, unwanted) {
+				t.Errorf("%s: ne sme da sadrži %q: %s", tt.path, unwanted, body)
+			}
+		}
+	}
+
+	if rec := serveTest(s, http.MethodPost, "/api/modules/test_sd_customers/2/restore", "", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("restore: status %d, telo %s", rec.Code, rec.Body)
+	}
+	if rec := serveTest(s, http.MethodGet, "/api/modules/test_sd_customers/2", "", "", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Boris") {
+		t.Errorf("posle restore: status %d, telo %s", rec.Code, rec.Body)
+	}
+	if rec := serveTest(s, http.MethodPost, "/api/modules/test_sd_customers/2/restore", "", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("restore aktivnog zapisa: status %d, očekivano 404", rec.Code)
+	}
+}
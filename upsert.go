@@ -0,0 +1,329 @@
+// upsert.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Ishodi upsert-a za pojedinačni zapis.
+const (
+	UpsertInserted = "inserted"
+	UpsertUpdated  = "updated"
+)
+
+// upsertInsertedColumn je pomoćna kolona u RETURNING-u koja kaže da li je red upisan ili izmenjen.
+const upsertInsertedColumn = "_upsert_inserted"
+
+// validateUniqueKeys uklanja unique ključeve koji referenciraju nepostojeće kolone.
+func validateUniqueKeys(moduleDef *ModuleDefinition) {
+	valid := moduleDef.UniqueKeys[:0]
+	for _, key := range moduleDef.UniqueKeys {
+		ok := len(key) > 0
+		for _, col := range key {
+			if getColumnByDBName(moduleDef.Columns, col) == nil {
+				log.Printf("WARNING: Unique ključ %v modula '%s' sadrži nepostojeću kolonu '%s', preskačem ga.", key, moduleDef.ID, col)
+				ok = false
+				break
+			}
+		}
+		if ok {
+			valid = append(valid, key)
+		}
+	}
+	moduleDef.UniqueKeys = valid
+}
+
+// uniqueKey vraća deklarisani unique ključ koji odgovara traženim kolonama (redosled nije bitan),
+// ili prvi deklarisani ključ ako kolone nisu navedene.
+func (m *ModuleDefinition) uniqueKey(cols []string) ([]string, error) {
+	if len(m.UniqueKeys) == 0 {
+		return nil, NewValidationError("key", fmt.Sprintf("modul '%s' nema deklarisan unique ključ (unique_keys)", m.ID))
+	}
+	if len(cols) == 0 {
+		return m.UniqueKeys[0], nil
+	}
+	wanted := slices.Clone(cols)
+	slices.Sort(wanted)
+	for _, key := range m.UniqueKeys {
+		sorted := slices.Clone(key)
+		slices.Sort(sorted)
+		if slices.Equal(sorted, wanted) {
+			return key, nil
+		}
+	}
+	return nil, NewValidationError("key", fmt.Sprintf("%v nije deklarisan unique ključ modula '%s'", cols, m.ID))
+}
+
+// lockRecordByKey čita i zaključava zapis po vrednostima unique ključa (uključujući soft-obrisane).
+// Vraća sql.ErrNoRows ako zapis ne postoji.
+func (s *SQLDataset) lockRecordByKey(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, key []string, payload map[string]interface{}) (map[string]interface{}, error) {
+	conditions := make([]string, len(key))
+	args := make([]interface{}, len(key))
+	for i, col := range key {
+		conditions[i] = fmt.Sprintf("%s = $%d", col, i+1)
+		args[i] = payload[col]
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s FOR UPDATE", moduleDef.DBTableName, strings.Join(conditions, " AND "))
+	record, err := queryRecord(ctx, tx, query, args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("greška pri čitanju zapisa po ključu %v u modulu '%s': %w", key, moduleDef.Name, err)
+	}
+	return record, err
+}
+
+// requireVisibleByKey odbija upsert zapisa koji postoji, ali je van row-level filtera korisnika.
+// Greška se prijavljuje na ključu, bez otkrivanja sadržaja zapisa.
+func (s *SQLDataset) requireVisibleByKey(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, key []string, recordID interface{}) error {
+	_, err := s.lockRecord(ctx, tx, moduleDef, pkCol, recordID, ScopeWithDeleted)
+	if err == sql.ErrNoRows {
+		return NewValidationError(key[0], "zapis sa ovom vrednošću ključa nije dostupan")
+	}
+	return err
+}
+
+// errUpsertDeleted je greška upsert-a čiji ključ pripada soft-obrisanom zapisu.
+func errUpsertDeleted(key []string) error {
+	return NewValidationError(key[0], "zapis sa ovom vrednošću ključa je obrisan; vratite ga preko /restore pre izmene")
+}
+
+// buildUpsertQuery pravi INSERT ... ON CONFLICT (ključ) DO UPDATE. Pri konfliktu se menjaju samo
+// polja poslata u payload-u (bez ključa), automatske kolone izmene i verzija. Soft-obrisan zapis
+// se ne menja (upit ne vraća red); vraća se samo eksplicitno, preko /restore.
+func buildUpsertQuery(ctx context.Context, moduleDef *ModuleDefinition, key []string, payload map[string]interface{}) (string, []interface{}, error) {
+	cols, vals, err := insertValues(ctx, moduleDef, payload, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	for _, col := range key {
+		if !slices.Contains(cols, col) {
+			return "", nil, NewValidationError(col, "kolona ključa se ne može upisati (nije editable)")
+		}
+	}
+
+	placeholders := make([]string, len(vals))
+	for i := range vals {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	setClauses := []string{}
+	for _, col := range cols {
+		if slices.Contains(key, col) {
+			continue
+		}
+		colDef := getColumnByDBName(moduleDef.Columns, col)
+		if colDef.IsAuto() {
+			if colDef.Auto == AutoCreatedAt || colDef.Auto == AutoCreatedBy {
+				continue
+			}
+		} else if _, ok := payload[col]; !ok {
+			continue // default_value važi samo za nove zapise
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+	}
+	if moduleDef.HasVersion() {
+		// Kolona mora biti kvalifikovana tabelom, jer je u DO UPDATE vidljiv i EXCLUDED
+		if colDef := getColumnByDBName(moduleDef.Columns, moduleDef.VersionColumn); colDef != nil && colDef.Type == "integer" {
+			setClauses = append(setClauses, fmt.Sprintf("%s = %s.%s + 1", moduleDef.VersionColumn, moduleDef.DBTableName, moduleDef.VersionColumn))
+		} else {
+			setClauses = append(setClauses, fmt.Sprintf("%s = now()", moduleDef.VersionColumn))
+		}
+	}
+	if len(setClauses) == 0 {
+		// DO NOTHING ne vraća postojeći red, pa se ključ "menja" u istu vrednost
+		setClauses = append(setClauses, fmt.Sprintf("%s = EXCLUDED.%s", key[0], key[0]))
+	}
+
+	conflictWhere := ""
+	if moduleDef.HasSoftDelete() {
+		conflictWhere = fmt.Sprintf(" WHERE %s.%s IS NULL", moduleDef.DBTableName, moduleDef.DeletedAtColumn)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s%s RETURNING *, (xmax = 0) AS %s",
+		moduleDef.DBTableName,
+		strings.Join(cols, ", "),
+		strings.Join(placeholders, ", "),
+		strings.Join(key, ", "),
+		strings.Join(setClauses, ", "),
+		conflictWhere,
+		upsertInsertedColumn,
+	)
+	return query, vals, nil
+}
+
+// upsertRecordTx upisuje ili menja zapis po unique ključu u postojećoj transakciji,
+// uz hook-ove i audit odgovarajuće operacije. Vraća red i ishod (UpsertInserted/UpsertUpdated).
+func (s *SQLDataset) upsertRecordTx(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, pkCol *ColumnDefinition, key []string, payload map[string]interface{}) (map[string]interface{}, string, error) {
+	for _, col := range key {
+		if payload[col] == nil {
+			return nil, "", NewValidationError(col, "vrednost ključa je obavezna")
+		}
+	}
+
+	before, err := s.lockRecordByKey(ctx, tx, moduleDef, key, payload)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", err
+	}
+	recordID := ""
+	if before != nil {
+		recordID = fmt.Sprint(before[pkCol.DBColumnName])
+		if err := s.requireVisibleByKey(ctx, tx, moduleDef, pkCol, key, before[pkCol.DBColumnName]); err != nil {
+			return nil, "", err
+		}
+		if moduleDef.HasSoftDelete() && before[moduleDef.DeletedAtColumn] != nil {
+			return nil, "", errUpsertDeleted(key)
+		}
+	}
+	if err := s.validateRecord(ctx, validationTarget{Module: moduleDef, RecordID: recordID}, payload); err != nil {
+		return nil, "", err
+	}
+	if before != nil {
+		err = s.beforeUpdate(ctx, moduleDef, recordID, before, payload)
+	} else {
+		err = s.beforeCreate(ctx, moduleDef, payload)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	query, vals, err := buildUpsertQuery(ctx, moduleDef, key, payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	log.Printf("DEBUG: Executing UPSERT query: %s with values: %v", query, vals)
+
+	after, err := queryRecord(ctx, tx, query, vals...)
+	if err == sql.ErrNoRows {
+		return nil, "", errUpsertDeleted(key) // Zapis je u međuvremenu upisan i soft-obrisan
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("greška pri izvršavanju UPSERT upita za modul '%s': %w", moduleDef.Name, err)
+	}
+	inserted, _ := after[upsertInsertedColumn].(bool)
+	delete(after, upsertInsertedColumn)
+	afterID := after[pkCol.DBColumnName]
+
+	if inserted {
+		if err := s.Hooks.runAfterCreate(ctx, moduleDef, after); err != nil {
+			return nil, "", err
+		}
+		if err := s.recordChange(ctx, tx, moduleDef, afterID, AuditCreate, nil, after); err != nil {
+			return nil, "", err
+		}
+		return after, UpsertInserted, nil
+	}
+
+	if before == nil {
+		// Zapis je upisan u međuvremenu (konkurentna transakcija); stanje pre izmene nije poznato
+		log.Printf("WARNING: Upsert u modulu '%s' izmenio je zapis '%v' koji nije postojao pri čitanju.", moduleDef.ID, afterID)
+		if err := s.requireVisibleByKey(ctx, tx, moduleDef, pkCol, key, afterID); err != nil {
+			return nil, "", err
+		}
+		before = map[string]interface{}{}
+	}
+	if err := s.Hooks.runAfterUpdate(ctx, moduleDef, before, after); err != nil {
+		return nil, "", err
+	}
+	if err := s.recordChange(ctx, tx, moduleDef, afterID, AuditUpdate, before, after); err != nil {
+		return nil, "", err
+	}
+	return after, UpsertUpdated, nil
+}
+
+// UpsertRecords inserts or updates records by a unique key. U atomic režimu greška bilo
+// kog zapisa poništava sve; u best_effort režimu svaki zapis se upisuje u svom savepoint-u.
+// Zapis čiji ključ pripada soft-obrisanom zapisu je greška validacije (obrisan zapis se ne vraća prećutno).
+func (s *SQLDataset) UpsertRecords(ctx context.Context, moduleDef *ModuleDefinition, keyCols []string, records []map[string]interface{}, mode string) (*BulkResult, error) {
+	pkCol := s.getPrimaryKeyColumn(moduleDef)
+	if pkCol == nil {
+		return nil, fmt.Errorf("modul '%s' nema definisan primarni ključ", moduleDef.Name)
+	}
+	key, err := moduleDef.uniqueKey(keyCols)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newBulkResult(mode, len(records))
+	for i, payload := range records {
+		// Statička provera pre transakcije; pravila koja čitaju bazu (unique, exists) se proveravaju
+		// u upsertRecordTx, kada je poznato da li zapis sa ključem postoji
+		if err := validatePayload(payload, moduleDef.Columns, s.config); err != nil {
+			result.fail(i, err)
+		}
+	}
+	if mode == BulkModeAtomic && result.hasErrors() {
+		result.finish()
+		return result, nil
+	}
+
+	err = s.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for i, payload := range records {
+			if result.Items[i].Status == BulkStatusError {
+				continue
+			}
+			upsert := func() error {
+				record, action, err := s.upsertRecordTx(ctx, tx, moduleDef, pkCol, key, payload)
+				if err == nil {
+					result.Items[i].ID = record[pkCol.DBColumnName]
+					result.Items[i].Action = action
+				}
+				return err
+			}
+			if mode == BulkModeBestEffort {
+				if err := withSavepoint(ctx, tx, upsert); err != nil {
+					result.fail(i, err)
+				}
+				continue
+			}
+			if err := upsert(); err != nil {
+				result.fail(i, err)
+				return errBulkRolledBack
+			}
+		}
+		return nil
+	})
+	if err == errBulkRolledBack {
+		for i := range result.Items {
+			result.Items[i].ID = nil // Upisani redovi su poništeni
+			result.Items[i].Action = ""
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	result.finish()
+	return result, nil
+}
+
+// UpsertModuleRecords handles POST /api/modules/{moduleID}/upsert with
+// {"key": ["code"], "records": [...], "mode": "atomic"}. Zahteva dozvole create i update.
+func (s *APIServer) UpsertModuleRecords(w http.ResponseWriter, req *http.Request) {
+	moduleDef := s.bulkModule(w, req, PermCreate)
+	if moduleDef == nil || !s.authorize(w, req, moduleDef, PermUpdate) {
+		return
+	}
+	body, ok := decodeBulkRequest(w, req)
+	if !ok {
+		return
+	}
+	if len(body.Records) == 0 {
+		writeError(w, "Polje 'records' mora sadržati bar jedan zapis.", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.dataset.UpsertRecords(req.Context(), moduleDef, body.Key, body.Records, body.Mode)
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, fmt.Sprintf("Greška pri upsert-u zapisa za modul '%s'", moduleDef.ID), err)
+		return
+	}
+	writeBulkResult(w, moduleDef, "upsert", result)
+}
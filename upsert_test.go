@@ -0,0 +1,65 @@
+// upsert_test.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestUpsertConflicts(t *testing.T) {
+	module := &ModuleDefinition{ID: "test_products", Name: "Proizvodi", Type: "table", DBTableName: "test_products",
+		DeletedAtColumn: "deleted_at", VersionColumn: "version", UniqueKeys: [][]string{{"code"}},
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true, IsReadOnly: true},
+			{DBColumnName: "code", Name: "Šifra", Type: "string", IsVisible: true, IsEditable: true},
+			{DBColumnName: "name", Name: "Naziv", Type: "string", IsVisible: true, IsEditable: true},
+			{DBColumnName: "version", Name: "Verzija", Type: "integer", IsReadOnly: true},
+		}}
+	ds := newTestDataset(t, module)
+	createTestTable(t, ds, module.DBTableName, "id SERIAL PRIMARY KEY, code TEXT NOT NULL UNIQUE, name TEXT, deleted_at TIMESTAMPTZ, version INT NOT NULL DEFAULT 1")
+	s := NewAPIServer(ds.config, ds)
+
+	upsert := func(body string, wantStatus int) BulkResult {
+		t.Helper()
+		rec := serveTest(s, http.MethodPost, "/api/modules/test_products/upsert", body, "", nil)
+		if rec.Code != wantStatus {
+			t.Fatalf("upsert %s: status %d, očekivano %d; telo %s", body, rec.Code, wantStatus, rec.Body)
+		}
+		var result BulkResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := upsert(`{"key": ["code"], "records": [{"code": "A", "name": "prvi"}]}`, http.StatusOK)
+	if result.Items[0].Action != UpsertInserted {
+		t.Errorf("prvi upis: %+v", result.Items[0])
+	}
+	result = upsert(`{"key": ["code"], "records": [{"code": "A", "name": "izmenjen"}, {"code": "B", "name": "drugi"}]}`, http.StatusOK)
+	if result.Items[0].Action != UpsertUpdated || result.Items[1].Action != UpsertInserted {
+		t.Errorf("konflikt na A: %+v", result.Items)
+	}
+	var name string
+	var version int
+	if err := ds.db.QueryRow("SELECT name, version FROM test_products WHERE code = 'A'").Scan(&name, &version); err != nil || name != "izmenjen" || version != 2 {
+		t.Errorf("A posle izmene: name=%q version=%d, %v", name, version, err)
+	}
+
+	if _, err := ds.db.Exec("UPDATE test_products SET deleted_at = now() WHERE code = 'A'"); err != nil {
+		t.Fatal(err)
+	}
+	result = upsert(`{"key": ["code"], "records": [{"code": "A", "name": "oživljen"}]}`, http.StatusUnprocessableEntity)
+	if result.Items[0].Status != BulkStatusError || result.Items[0].Field != "code" {
+		t.Errorf("konflikt sa obrisanim zapisom: %+v", result.Items[0])
+	}
+	result = upsert(`{"key": ["code"], "mode": "best_effort", "records": [{"code": "A", "name": "oživljen"}, {"code": "C", "name": "treći"}]}`, http.StatusOK)
+	if result.Failed != 1 || result.Items[1].Action != UpsertInserted {
+		t.Errorf("best_effort sa obrisanim zapisom: %+v", result)
+	}
+	var deleted bool
+	if err := ds.db.QueryRow("SELECT deleted_at IS NOT NULL, name FROM test_products WHERE code = 'A'").Scan(&deleted, &name); err != nil || !deleted || name != "izmenjen" {
+		t.Errorf("obrisan zapis je izmenjen: deleted=%v name=%q, %v", deleted, name, err)
+	}
+}
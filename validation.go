@@ -2,137 +2,366 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
-	// "regexp" // Više nije direktno potreban ovde, jer koristimo config.GetCompiledRegex
 )
 
-// validatePayload validates the incoming JSON payload against module column definitions.
-// Ova funkcija sada prima *AppConfig kao 'config' argument.
+// ValidationError is a user-facing validation failure. Server hooks return it
+// to abort a write with a message that is shown to the client.
+type ValidationError struct {
+	Field   string                 `json:"field,omitempty"`  // DB kolona na koju se greška odnosi (opciono)
+	Rule    string                 `json:"rule,omitempty"`   // Pravilo koje nije ispunjeno (npr. "required", "min")
+	Params  map[string]interface{} `json:"params,omitempty"` // Parametri pravila (npr. {"min": 5})
+	Message string                 `json:"message"`
+
+	key     string            // Ključ poruke u katalogu (prazno = poruka se ne prevodi)
+	column  *ColumnDefinition // Kolona čiji se (prevedeni) naziv umeće u poruku
+	related *ColumnDefinition // Drugo polje pravila koja porede polja (parametar "other")
+	module  *ModuleDefinition // Modul na koji upućuje pravilo (parametar "module")
+}
+
+// NewValidationError creates a ValidationError for the given field.
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Field: field, Message: message}
+}
+
+// newValidationError pravi grešku polja sa porukom iz kataloga na izvornom jeziku.
+func newValidationError(field, rule, key string, params map[string]interface{}) *ValidationError {
+	e := &ValidationError{Field: field, Rule: rule, Params: params, key: key}
+	e.Message, _ = e.localizedMessage(sourceLanguage)
+	return e
+}
+
+// localizedMessage formira poruku greške na datom jeziku; false ako greška nema ključ ili prevod.
+func (e *ValidationError) localizedMessage(lang string) (string, bool) {
+	if e.key == "" {
+		return "", false
+	}
+	params := map[string]interface{}{"field": e.Field}
+	for name, value := range e.Params {
+		params[name] = value
+	}
+	if e.column != nil {
+		params["field"] = localize(e.column.Name, e.column.NameI18n, lang)
+	}
+	if e.related != nil {
+		params["other"] = localize(e.related.Name, e.related.NameI18n, lang)
+	}
+	if e.module != nil {
+		params["module_name"] = localize(e.module.Name, e.module.NameI18n, lang)
+	}
+	return messages.Format(lang, e.key, params)
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("polje '%s': %s", e.Field, e.Message)
+	}
+	return e.Message
+}
+
+// ValidationErrors collects every failed rule of a payload, so clients can show all errors at once.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validatePayload validates the incoming JSON payload against column definitions, bez pravila
+// koja čitaju bazu. Ne prekida se na prvoj grešci: vraća ValidationErrors sa svim neispunjenim
+// pravilima, ili nil. Poruke dolaze iz kataloga (i18n.go), pa se mogu prevesti na jezik klijenta.
 func validatePayload(payload map[string]interface{}, columns []ColumnDefinition, config *AppConfig) error {
-	for _, colDef := range columns {
+	run := &validationRun{config: config, target: validationTarget{Columns: columns}, payload: payload}
+	return run.validate(context.Background())
+}
+
+// validationTarget opisuje zapis koji validateRecord proverava.
+type validationTarget struct {
+	Module   *ModuleDefinition
+	Columns  []ColumnDefinition // Podrazumevano kolone modula (za akcije: parametri)
+	RecordID string             // ID zapisa koji se menja; prazno pri kreiranju
+	Partial  bool               // Delimična izmena: validiraju se poslata polja, a ostala se čitaju iz postojećeg zapisa
+}
+
+// validateRecord validates a payload including cross-field and database rules (unique, exists).
+func (s *SQLDataset) validateRecord(ctx context.Context, target validationTarget, payload map[string]interface{}) error {
+	if target.Columns == nil && target.Module != nil {
+		target.Columns = target.Module.Columns
+	}
+	run := &validationRun{config: s.config, dataset: s, target: target, payload: payload}
+	return run.validate(ctx)
+}
+
+// validationRun je jedna validacija payload-a; postojeći zapis se čita najviše jednom, i to
+// samo ako neko pravilo traži polje koje nije poslato.
+type validationRun struct {
+	config  *AppConfig
+	dataset *SQLDataset // nil = pravila koja čitaju bazu se preskaču
+	target  validationTarget
+	payload map[string]interface{}
+
+	existing       map[string]interface{}
+	existingLoaded bool
+}
+
+// existingValue vraća vrednost polja iz postojećeg zapisa (samo kod delimične izmene).
+func (r *validationRun) existingValue(ctx context.Context, name string) (interface{}, bool) {
+	if r == nil || !r.target.Partial || r.dataset == nil || r.target.Module == nil || r.target.RecordID == "" {
+		return nil, false
+	}
+	if !r.existingLoaded {
+		r.existingLoaded = true
+		if pkCol := r.dataset.getPrimaryKeyColumn(r.target.Module); pkCol != nil {
+			query := fmt.Sprintf("SELECT * FROM %s WHERE %s = $1", r.target.Module.DBTableName, pkCol.DBColumnName)
+			record, err := queryRecord(ctx, r.dataset.conn(ctx), query, r.target.RecordID)
+			if err != nil && err != sql.ErrNoRows {
+				log.Printf("WARNING: Greška pri čitanju zapisa '%s' za validaciju u modulu '%s': %v", r.target.RecordID, r.target.Module.ID, err)
+			}
+			r.existing = record
+		}
+	}
+	v, ok := r.existing[name]
+	return v, ok
+}
+
+// column vraća kolonu po DB imenu među kolonama koje se validiraju.
+func (r *validationRun) column(name string) *ColumnDefinition {
+	for i := range r.target.Columns {
+		if r.target.Columns[i].DBColumnName == name {
+			return &r.target.Columns[i]
+		}
+	}
+	return nil
+}
+
+// validate proverava sve kolone i skuplja greške.
+func (r *validationRun) validate(ctx context.Context) error {
+	var errs ValidationErrors
+	for i := range r.target.Columns {
+		colDef := &r.target.Columns[i]
 		// Preskoči kolone koje nisu editable (npr. automatski generisani ID-evi)
 		// i primarne ključeve ako nisu deo payload-a (ili ako se ne očekuje da ih klijent šalje za kreiranje)
 		// Bitno je da se primarni ključ validira SAMO ako je poslat.
 		// Ako je IsReadOnly true, ta kolona se ne može menjati, pa je preskačemo za validaciju payload-a.
-		if colDef.IsReadOnly {
+		// Automatske kolone (created_at, updated_by...) popunjava server, klijent ih ne šalje.
+		if colDef.IsReadOnly || colDef.IsAuto() {
 			continue
 		}
 
-		val, exists := payload[colDef.DBColumnName] // Validira po DBColumnName, a ne po ID-u kolone
+		val, exists := r.payload[colDef.DBColumnName] // Validira po DBColumnName, a ne po ID-u kolone
+		if exists && colDef.Type == "lookup" {
+			val = r.normalizeLookup(colDef, val)
+		}
+		var colErrs ValidationErrors
+		var err error
+		if r.target.Partial && !exists {
+			colErrs, err = r.checkUnchangedColumn(ctx, colDef)
+		} else {
+			colErrs, err = r.checkColumn(ctx, colDef, val)
+		}
+		if err != nil {
+			return err
+		}
+		errs = append(errs, colErrs...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// normalizeLookup prihvata lookup vrednost i u obliku koji vraća čitanje ({"id": ..., "name": ...})
+// i u payload-u je zamenjuje samim ID-em, koji se upisuje u bazu.
+func (r *validationRun) normalizeLookup(colDef *ColumnDefinition, val interface{}) interface{} {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return val
+	}
+	id, ok := obj["id"]
+	if !ok {
+		return val
+	}
+	r.payload[colDef.DBColumnName] = id
+	return id
+}
+
+// validLookupValue proverava da li vrednost odgovara tipu primarnog ključa lookup modula.
+func validLookupValue(colDef *ColumnDefinition, val interface{}) bool {
+	keyType := "integer"
+	if colDef.LookupModule != nil {
+		for _, col := range colDef.LookupModule.Columns {
+			if col.IsPrimaryKey {
+				keyType = col.Type
+			}
+		}
+	}
+	switch v := val.(type) {
+	case float64:
+		return keyType != "string" && (keyType != "integer" || v == float64(int64(v)))
+	case int64, int:
+		return keyType != "string"
+	case string:
+		return keyType != "integer" && keyType != "float"
+	}
+	return false
+}
+
+// columnRules vraća pravila kolone; lookup kolone uvek proveravaju da referencirani zapis postoji.
+func columnRules(colDef *ColumnDefinition) []ValidationRule {
+	rules := colDef.ValidationRules()
+	if colDef.Type != "lookup" || colDef.LookupModule == nil {
+		return rules
+	}
+	for _, rule := range rules {
+		if rule.Name == "exists" {
+			return rules
+		}
+	}
+	return append(rules, ValidationRule{Name: "exists"})
+}
+
+// checkColumn proverava poslatu (ili izostavljenu, kod punog upisa) vrednost kolone.
+func (r *validationRun) checkColumn(ctx context.Context, colDef *ColumnDefinition, val interface{}) (ValidationErrors, error) {
+	var errs ValidationErrors
+	fail := func(rule, key string, params map[string]interface{}) {
+		errs = append(errs, r.newError(colDef, rule, key, params))
+	}
+	rules := columnRules(colDef)
+
+	// --- Prazna vrednost (nije poslata ili je JSON null) ---
+	// Izvršavaju se samo pravila za prazne vrednosti (required, required_if...)
+	if val == nil {
+		return r.applyRules(ctx, colDef, nil, rules, func(opts RuleOptions) bool { return opts.OnEmpty && !opts.Database })
+	}
 
-		// --- Provera obaveznih polja (required) ---
-		// Ako polje ne postoji u payload-u i obavezno je
-		if !exists && strings.Contains(colDef.Validation, "required") {
-			return fmt.Errorf("polje '%s' (DB kolona: %s) je obavezno", colDef.Name, colDef.DBColumnName)
+	// --- Validacija tipa (ako vrednost postoji i nije nil) ---
+	// Pogrešan tip preskače ostala pravila kolone, jer ona pretpostavljaju ispravan tip
+	typeParams := map[string]interface{}{"type": colDef.Type}
+	switch colDef.Type {
+	case "string":
+		if _, ok := val.(string); !ok {
+			fail("type", "validation.type.string", typeParams)
+			return errs, nil
 		}
-		// Ako polje postoji, ali je nil (JSON null), i obavezno je
-		if exists && val == nil && strings.Contains(colDef.Validation, "required") {
-			return fmt.Errorf("polje '%s' (DB kolona: %s) je obavezno i ne može biti prazno", colDef.Name, colDef.DBColumnName)
+	case "integer":
+		// JSON unmarshals brojeve kao float64 po defaultu; proverava se i da nije decimalni
+		if vFloat, ok := val.(float64); !ok || vFloat != float64(int(vFloat)) {
+			fail("type", "validation.type.integer", typeParams)
+			return errs, nil
 		}
+	case "float":
+		if _, ok := val.(float64); !ok {
+			fail("type", "validation.type.float", typeParams)
+			return errs, nil
+		}
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			fail("type", "validation.type.boolean", typeParams)
+			return errs, nil
+		}
+	case "lookup":
+		if !validLookupValue(colDef, val) {
+			fail("type", "validation.type.lookup", typeParams)
+			return errs, nil
+		}
+	// TODO: Dodaj još provera tipova za date, datetime, itd.
+	default:
+		// Ako tip nije eksplicitno obrađen, loguj upozorenje ili ga preskoči
+		log.Printf("INFO: Tip kolone '%s' ('%s') nije eksplicitno obrađen u validaciji. Primljen tip: %T", colDef.Name, colDef.Type, val)
+	}
 
-		// Ako polje ne postoji ili je nil, a NIJE obavezno, preskoči dalju validaciju za to polje
-		if !exists || val == nil {
-			continue
+	// --- Ponuđene vrednosti (choices) ---
+	if len(colDef.Choices) > 0 && !colDef.HasChoice(val) {
+		fail("choice", "validation.choice", map[string]interface{}{"choices": colDef.ChoiceValues()})
+		return errs, nil
+	}
+
+	// --- Pravila iz registra (min, max, regex, email, poređenje polja...) ---
+	ruleErrs, err := r.applyRules(ctx, colDef, val, rules, func(opts RuleOptions) bool { return !opts.Database })
+	if err != nil || len(ruleErrs) > 0 {
+		return ruleErrs, err
+	}
+	// --- Pravila koja čitaju bazu (unique, exists), samo za inače ispravnu vrednost ---
+	if r.dataset == nil {
+		return nil, nil
+	}
+	return r.applyRules(ctx, colDef, val, rules, func(opts RuleOptions) bool { return opts.Database })
+}
+
+// checkUnchangedColumn kod delimične izmene proverava kolonu koja nije poslata, ali je
+// poslato polje sa kojim je porede njena pravila (npr. poslat je samo start_date).
+func (r *validationRun) checkUnchangedColumn(ctx context.Context, colDef *ColumnDefinition) (ValidationErrors, error) {
+	var rules []ValidationRule
+	for _, rule := range colDef.ValidationRules() {
+		if _, sent := r.payload[rule.otherField()]; sent && rule.otherField() != "" {
+			rules = append(rules, rule)
 		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	val, _ := r.existingValue(ctx, colDef.DBColumnName)
+	return r.applyRules(ctx, colDef, val, rules, func(opts RuleOptions) bool {
+		return opts.CrossField && !opts.Database && (val != nil || opts.OnEmpty)
+	})
+}
 
-		// --- Validacija tipa (ako vrednost postoji i nije nil) ---
-		switch colDef.Type {
-		case "string":
-			if _, ok := val.(string); !ok {
-				return fmt.Errorf("polje '%s' (DB kolona: %s) mora biti string (primljen tip: %T)", colDef.Name, colDef.DBColumnName, val)
-			}
-		case "integer":
-			// JSON unmarshals brojeve kao float64 po defaultu
-			if vFloat, ok := val.(float64); !ok {
-				return fmt.Errorf("polje '%s' (DB kolona: %s) mora biti ceo broj (primljen tip: %T)", colDef.Name, colDef.DBColumnName, val)
-			} else if vFloat != float64(int(vFloat)) { // Provera da li je float64 zapravo ceo broj
-				return fmt.Errorf("polje '%s' (DB kolona: %s) mora biti ceo broj (nije decimalni)", colDef.Name, colDef.DBColumnName)
-			}
-		case "float":
-			if _, ok := val.(float64); !ok {
-				return fmt.Errorf("polje '%s' (DB kolona: %s) mora biti decimalni broj (primljen tip: %T)", colDef.Name, colDef.DBColumnName, val)
-			}
-		case "boolean":
-			if _, ok := val.(bool); !ok {
-				return fmt.Errorf("polje '%s' (DB kolona: %s) mora biti logička vrednost (true/false) (primljen tip: %T)", colDef.Name, colDef.DBColumnName, val)
-			}
-		// TODO: Dodaj još provera tipova za date, datetime, itd.
-		default:
-			// Ako tip nije eksplicitno obrađen, loguj upozorenje ili ga preskoči
-			log.Printf("INFO: Tip kolone '%s' ('%s') nije eksplicitno obrađen u validaciji. Primljen tip: %T", colDef.Name, colDef.Type, val)
-		}
-
-		// --- Pravila validacije (min, max, regex, email, itd.) ---
-		if colDef.Validation != "" {
-			rules := strings.Split(colDef.Validation, ",")
-			for _, rule := range rules {
-				rule = strings.TrimSpace(rule)
-				// "required" je već obrađen iznad
-				if rule == "required" {
-					continue
-				} else if strings.HasPrefix(rule, "min:") {
-					minValStr := strings.TrimPrefix(rule, "min:")
-					minVal, err := strconv.ParseFloat(minValStr, 64)
-					if err != nil {
-						log.Printf("ERROR: Greška pri parsiranju min validacije '%s' za '%s': %v", minValStr, colDef.Name, err)
-						// Ne prekidaj izvršenje, samo preskoči ovo pravilo
-						continue
-					}
-					// Provera za brojeve
-					if vFloat, ok := val.(float64); ok && vFloat < minVal {
-						return fmt.Errorf("polje '%s' mora biti najmanje %.2f", colDef.Name, minVal)
-					}
-					// Provera za stringove (dužina)
-					if vStr, ok := val.(string); ok && float64(len(vStr)) < minVal {
-						return fmt.Errorf("polje '%s' mora imati najmanje %d karaktera", colDef.Name, int(minVal))
-					}
-				} else if strings.HasPrefix(rule, "max:") {
-					maxValStr := strings.TrimPrefix(rule, "max:")
-					maxVal, err := strconv.ParseFloat(maxValStr, 64)
-					if err != nil {
-						log.Printf("ERROR: Greška pri parsiranju max validacije '%s' za '%s': %v", maxValStr, colDef.Name, err)
-						continue
-					}
-					// Provera za brojeve
-					if vFloat, ok := val.(float64); ok && vFloat > maxVal {
-						return fmt.Errorf("polje '%s' može biti najviše %.2f", colDef.Name, maxVal)
-					}
-					// Provera za stringove (dužina)
-					if vStr, ok := val.(string); ok && float64(len(vStr)) > maxVal {
-						return fmt.Errorf("polje '%s' može imati najviše %d karaktera", colDef.Name, int(maxVal))
-					}
-				} else if rule == "email" {
-					if vStr, ok := val.(string); ok {
-						// ISPRAVKA: Koristimo prekompilirani email regex
-						re, found := config.GetCompiledRegex("emailValidation") // Koristimo ključ "emailValidation"
-						if !found {
-							log.Printf("ERROR: Kompilirani email regex nije pronađen. Proverite AppConfig.CompileRegexes().")
-							// Ako regex nije pronađen, nastavi, ali loguj upozorenje.
-							continue
-						}
-						if !re.MatchString(vStr) {
-							return fmt.Errorf("polje '%s' mora biti validna email adresa", colDef.Name)
-						}
-					}
-				} else if strings.HasPrefix(rule, "regex:") {
-					pattern := strings.TrimPrefix(rule, "regex:")
-					if vStr, ok := val.(string); ok {
-						re, found := config.GetCompiledRegex(pattern) // Koristi pattern kao ključ
-						if !found {
-							log.Printf("ERROR: Regex '%s' nije kompilovan za kolonu '%s'. Proverite config.go CompileRegexes.", pattern, colDef.Name)
-							continue
-						}
-						if !re.MatchString(vStr) {
-							return fmt.Errorf("polje '%s' ne ispunjava zahtevani format (regex: %s)", colDef.Name, pattern)
-						}
-					}
-				}
-				// TODO: Dodaj još pravila validacije po potrebi
+// applyRules izvršava pravila kolone koja zadovoljavaju filter.
+func (r *validationRun) applyRules(ctx context.Context, colDef *ColumnDefinition, val interface{}, rules []ValidationRule, filter func(RuleOptions) bool) (ValidationErrors, error) {
+	var errs ValidationErrors
+	for _, rule := range rules {
+		if rule.Name == "required" {
+			if val == nil && filter(RuleOptions{OnEmpty: true}) {
+				errs = append(errs, r.newError(colDef, "required", "validation.required", nil))
 			}
+			continue
+		}
+		registered, ok := r.config.Rules.get(rule.Name)
+		if !ok {
+			log.Printf("WARNING: Nepoznato pravilo validacije '%s' za kolonu '%s'.", rule.Name, colDef.Name)
+			continue
+		}
+		if !filter(registered.opts) {
+			continue
+		}
+		rc := &RuleContext{
+			Context:  ctx,
+			Config:   r.config,
+			Dataset:  r.dataset,
+			Module:   r.target.Module,
+			RecordID: r.target.RecordID,
+			Payload:  r.payload,
+			Column:   colDef,
+			Value:    val,
+			Rule:     rule,
+			run:      r,
+		}
+		err := registered.fn(rc)
+		if failure, ok := err.(*ruleFailure); ok {
+			errs = append(errs, r.newError(colDef, rule.Name, failure.key, failure.params))
+		} else if err != nil {
+			return nil, fmt.Errorf("greška pri proveri pravila '%s' za kolonu '%s': %w", rule.Name, colDef.Name, err)
 		}
 	}
-	return nil
+	return errs, nil
+}
+
+// newError pravi grešku polja sa porukom na izvornom jeziku.
+func (r *validationRun) newError(colDef *ColumnDefinition, rule, key string, params map[string]interface{}) *ValidationError {
+	e := &ValidationError{Field: colDef.DBColumnName, Rule: rule, Params: params, key: key, column: colDef}
+	if other, ok := params["other"].(string); ok {
+		e.related = r.column(other)
+	}
+	if moduleID, ok := params["module"].(string); ok {
+		e.module = r.config.GetModuleByID(moduleID)
+	}
+	e.Message, _ = e.localizedMessage(sourceLanguage)
+	return e
 }
@@ -0,0 +1,810 @@
+// webhooks.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// Tabele webhook podsistema.
+const (
+	webhookSubscriptionsTable = "webhook_subscriptions"
+	webhookOutboxTable        = "webhook_outbox"
+	webhookDeliveriesTable    = "webhook_deliveries"
+)
+
+// Statusi stavke u webhook outbox-u.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead" // Iscrpljeni pokušaji (dead letter)
+)
+
+// Zaglavlja koja prate svaku webhook isporuku.
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// webhookEvents mapira audit operacije na imena webhook događaja.
+var webhookEvents = map[string]string{
+	AuditCreate:  "created",
+	AuditUpdate:  "updated",
+	AuditDelete:  "deleted",
+	AuditRestore: "restored",
+}
+
+// applyDefaults popunjava podrazumevane vrednosti za nepostavljena podešavanja.
+func (c *WebhookConfig) applyDefaults() {
+	if c.PollIntervalSeconds <= 0 {
+		c.PollIntervalSeconds = 5
+	}
+	if c.TimeoutSeconds <= 0 {
+		c.TimeoutSeconds = 10
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 8
+	}
+	if c.BackoffBaseSeconds <= 0 {
+		c.BackoffBaseSeconds = 10
+	}
+	if c.BackoffMaxSeconds <= 0 {
+		c.BackoffMaxSeconds = 3600
+	}
+}
+
+// WebhookSubscription is a target URL notified about changes of a module.
+// Subscribers receive only the columns the subscription's owner may read.
+type WebhookSubscription struct {
+	ID         int64     `json:"id"`
+	ModuleID   string    `json:"module_id"`
+	Events     []string  `json:"events"` // "created", "updated", "deleted", "restored" ili "*"
+	TargetURL  string    `json:"target_url"`
+	Secret     string    `json:"secret,omitempty"` // Koristi se za HMAC potpis; ne vraća se u listama
+	Active     bool      `json:"active"`
+	OwnerID    string    `json:"owner_id"`    // Korisnik koji je kreirao pretplatu; postavlja server
+	OwnerRoles []string  `json:"owner_roles"` // Uloge vlasnika u trenutku kreiranja; određuju vidljive kolone
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookEvent is the JSON body sent to subscribers.
+type WebhookEvent struct {
+	Event      string                 `json:"event"`
+	ModuleID   string                 `json:"module_id"`
+	RecordID   string                 `json:"record_id"`
+	UserID     string                 `json:"user_id,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Record     map[string]interface{} `json:"record"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+}
+
+// WebhookOutboxEntry is a queued delivery of one event to one subscription.
+type WebhookOutboxEntry struct {
+	ID             int64             `json:"id"`
+	SubscriptionID int64             `json:"subscription_id"`
+	Event          string            `json:"event"`
+	Payload        json.RawMessage   `json:"payload"`
+	Status         string            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	LastError      *string           `json:"last_error"`
+	CreatedAt      time.Time         `json:"created_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at"`
+	Deliveries     []WebhookDelivery `json:"deliveries"`
+}
+
+// WebhookDelivery is a single delivery attempt from the delivery log.
+type WebhookDelivery struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code"`
+	Error       *string   `json:"error"`
+	DurationMS  int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// ensureWebhookTables kreira tabele za pretplate, outbox i log isporuka ako ne postoje.
+func (s *SQLDataset) ensureWebhookTables() error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			module_id TEXT NOT NULL,
+			events TEXT[] NOT NULL DEFAULT '{*}',
+			target_url TEXT NOT NULL,
+			secret TEXT NOT NULL DEFAULT '',
+			active BOOLEAN NOT NULL DEFAULT true,
+			owner_id TEXT NOT NULL DEFAULT '',
+			owner_roles TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, webhookSubscriptionsTable),
+		// Starije instalacije nemaju kolone vlasnika; pretplate bez uloga vide samo neograničene kolone
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS owner_roles TEXT[] NOT NULL DEFAULT '{}'`, webhookSubscriptionsTable),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			subscription_id BIGINT NOT NULL REFERENCES %s (id) ON DELETE CASCADE,
+			event TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			status TEXT NOT NULL DEFAULT '%s',
+			attempts INT NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			last_error TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			delivered_at TIMESTAMPTZ
+		)`, webhookOutboxTable, webhookSubscriptionsTable, WebhookPending),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_pending_idx ON %s (next_attempt_at) WHERE status = '%s'", webhookOutboxTable, webhookOutboxTable, WebhookPending),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			outbox_id BIGINT NOT NULL REFERENCES %s (id) ON DELETE CASCADE,
+			attempt INT NOT NULL,
+			status_code INT,
+			error TEXT,
+			duration_ms BIGINT NOT NULL,
+			attempted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, webhookDeliveriesTable, webhookOutboxTable),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("greška pri kreiranju webhook tabela: %w", err)
+		}
+	}
+	return nil
+}
+
+// enqueueWebhooks upisuje događaj u outbox za svaku aktivnu pretplatu modula.
+// Upis je u istoj transakciji kao izmena, pa worker vidi događaj tek posle potvrde.
+// Svaka pretplata dobija samo kolone koje njen vlasnik sme da čita; pretplate čiji
+// vlasnik više ne sme da čita modul ili zapis (row-level filter) se preskaču.
+func (s *SQLDataset) enqueueWebhooks(ctx context.Context, tx *sql.Tx, moduleDef *ModuleDefinition, recordID interface{}, operation string, before, after map[string]interface{}) error {
+	event, ok := webhookEvents[operation]
+	if !ok {
+		return nil
+	}
+
+	query := fmt.Sprintf(`SELECT id, owner_id, owner_roles FROM %s
+		WHERE module_id = $1 AND active AND ($2 = ANY(events) OR '*' = ANY(events))`, webhookSubscriptionsTable)
+	rows, err := tx.QueryContext(ctx, query, moduleDef.ID, event)
+	if err != nil {
+		return fmt.Errorf("greška pri dohvatanju webhook pretplata za modul '%s': %w", moduleDef.ID, err)
+	}
+	type subscriber struct {
+		id    int64
+		owner *User
+	}
+	var subscribers []subscriber
+	for rows.Next() {
+		sub := subscriber{owner: &User{}}
+		if err := rows.Scan(&sub.id, &sub.owner.ID, pq.Array(&sub.owner.Roles)); err != nil {
+			rows.Close()
+			return fmt.Errorf("greška pri skeniranju webhook pretplate: %w", err)
+		}
+		subscribers = append(subscribers, sub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("greška nakon iteracije kroz webhook pretplate: %w", err)
+	}
+
+	occurredAt := time.Now().UTC()
+	insert := fmt.Sprintf("INSERT INTO %s (subscription_id, event, payload) VALUES ($1, $2, $3)", webhookOutboxTable)
+	for _, sub := range subscribers {
+		if !moduleDef.UserCan(sub.owner, PermRead) {
+			continue
+		}
+		// Kao kod SSE-a: vlasnik dobija samo zapise koji prolaze njegov row-level filter
+		// (proverava se stanje posle izmene u istoj transakciji)
+		visible, err := s.recordVisible(withTxContext(WithUser(ctx, sub.owner), tx), moduleDef, fmt.Sprint(recordID))
+		if err != nil {
+			return err
+		}
+		if !visible {
+			continue
+		}
+		body, err := json.Marshal(webhookEventFor(moduleDef, sub.owner, WebhookEvent{
+			Event:      event,
+			ModuleID:   moduleDef.ID,
+			RecordID:   fmt.Sprint(recordID),
+			UserID:     userIDFromContext(ctx),
+			OccurredAt: occurredAt,
+		}, before, after))
+		if err != nil {
+			return fmt.Errorf("greška pri serijalizaciji webhook događaja: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, insert, sub.id, event, string(body)); err != nil {
+			return fmt.Errorf("greška pri upisu webhook događaja za modul '%s': %w", moduleDef.ID, err)
+		}
+	}
+	return nil
+}
+
+// webhookEventFor popunjava zapis i izmene događaja, bez kolona koje vlasnik pretplate ne sme da čita.
+// Zapisi se kopiraju, jer before/after koriste i audit i SSE.
+func webhookEventFor(moduleDef *ModuleDefinition, owner *User, event WebhookEvent, before, after map[string]interface{}) WebhookEvent {
+	if before != nil {
+		before = maps.Clone(before)
+	}
+	if after != nil {
+		after = maps.Clone(after)
+	}
+	hideUnreadableColumns(moduleDef, owner, before, after)
+
+	event.Record = after
+	if event.Record == nil {
+		event.Record = before
+	}
+	event.Changes = diffRecords(before, after)
+	return event
+}
+
+// isPrivateAddress reports whether ip is a loopback, private, link-local or unspecified address.
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// errPrivateWebhookTarget se vraća kada bi isporuka išla na internu adresu (zaštita od SSRF).
+var errPrivateWebhookTarget = errors.New("isporuka na loopback ili privatnu adresu nije dozvoljena")
+
+// validateWebhookTarget proverava da je URL apsolutni http(s) URL čiji host nije interna adresa.
+// Host se razrešava preko DNS-a; worker ponovo proverava adresu pri svakom povezivanju.
+func validateWebhookTarget(ctx context.Context, target string, allowPrivate bool) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return NewValidationError("target_url", "mora biti apsolutni http(s) URL")
+	}
+	if allowPrivate {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return NewValidationError("target_url", fmt.Sprintf("host '%s' nije moguće razrešiti", u.Hostname()))
+	}
+	for _, addr := range addrs {
+		if isPrivateAddress(addr.IP) {
+			return NewValidationError("target_url", errPrivateWebhookTarget.Error())
+		}
+	}
+	return nil
+}
+
+// ListWebhookSubscriptions returns all subscriptions, without their secrets.
+func (s *SQLDataset) ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	query := fmt.Sprintf("SELECT id, module_id, events, target_url, active, owner_id, owner_roles, created_at FROM %s ORDER BY id", webhookSubscriptionsTable)
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri dohvatanju webhook pretplata: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make([]WebhookSubscription, 0)
+	for rows.Next() {
+		var sub WebhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.ModuleID, pq.Array(&sub.Events), &sub.TargetURL, &sub.Active, &sub.OwnerID, pq.Array(&sub.OwnerRoles), &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("greška pri skeniranju webhook pretplate: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("greška nakon iteracije kroz webhook pretplate: %w", err)
+	}
+	return subs, nil
+}
+
+// CreateWebhookSubscription stores a new subscription and returns it with its ID.
+func (s *SQLDataset) CreateWebhookSubscription(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, error) {
+	if s.config.GetModuleByID(sub.ModuleID) == nil {
+		return nil, NewValidationError("module_id", fmt.Sprintf("modul sa ID '%s' nije pronađen", sub.ModuleID))
+	}
+	if err := validateWebhookTarget(ctx, sub.TargetURL, s.config.Config.Webhooks.AllowPrivateTargets); err != nil {
+		return nil, err
+	}
+	if sub.OwnerRoles == nil {
+		sub.OwnerRoles = []string{}
+	}
+	if len(sub.Events) == 0 {
+		sub.Events = []string{"*"}
+	}
+	for _, event := range sub.Events {
+		if event == "*" {
+			continue
+		}
+		known := false
+		for _, name := range webhookEvents {
+			if name == event {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, NewValidationError("events", fmt.Sprintf("nepoznat događaj '%s'", event))
+		}
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (module_id, events, target_url, secret, owner_id, owner_roles)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, active, created_at`, webhookSubscriptionsTable)
+	err := s.db.QueryRowContext(ctx, query, sub.ModuleID, pq.Array(sub.Events), sub.TargetURL, sub.Secret, sub.OwnerID, pq.Array(sub.OwnerRoles)).
+		Scan(&sub.ID, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri kreiranju webhook pretplate: %w", err)
+	}
+	sub.Secret = ""
+	return &sub, nil
+}
+
+// DeleteWebhookSubscription removes a subscription together with its queued deliveries.
+func (s *SQLDataset) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", webhookSubscriptionsTable), id)
+	if err != nil {
+		return fmt.Errorf("greška pri brisanju webhook pretplate '%d': %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errRecordNotFound("webhook pretplata '%d' nije pronađena", id)
+	}
+	return nil
+}
+
+// ListWebhookOutbox returns the queued and finished deliveries of a subscription,
+// newest first, each with its delivery attempts.
+func (s *SQLDataset) ListWebhookOutbox(ctx context.Context, subscriptionID int64, status string, limit, offset int) ([]WebhookOutboxEntry, error) {
+	query := fmt.Sprintf(`SELECT id, subscription_id, event, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at
+		FROM %s WHERE subscription_id = $1`, webhookOutboxTable)
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	query += " ORDER BY id DESC"
+	if limit >= 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("greška pri dohvatanju webhook isporuka: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]WebhookOutboxEntry, 0)
+	index := make(map[int64]int)
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var e WebhookOutboxEntry
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.SubscriptionID, &e.Event, &payload, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.LastError, &e.CreatedAt, &e.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("greška pri skeniranju webhook isporuke: %w", err)
+		}
+		e.Payload = json.RawMessage(payload)
+		e.Deliveries = make([]WebhookDelivery, 0)
+		index[e.ID] = len(entries)
+		ids = append(ids, e.ID)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("greška nakon iteracije kroz webhook isporuke: %w", err)
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	logQuery := fmt.Sprintf(`SELECT outbox_id, attempt, status_code, error, duration_ms, attempted_at
+		FROM %s WHERE outbox_id = ANY($1) ORDER BY outbox_id, attempt`, webhookDeliveriesTable)
+	logRows, err := s.db.QueryContext(ctx, logQuery, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("greška pri dohvatanju loga webhook isporuka: %w", err)
+	}
+	defer logRows.Close()
+	for logRows.Next() {
+		var outboxID int64
+		var d WebhookDelivery
+		if err := logRows.Scan(&outboxID, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMS, &d.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("greška pri skeniranju loga webhook isporuke: %w", err)
+		}
+		entries[index[outboxID]].Deliveries = append(entries[index[outboxID]].Deliveries, d)
+	}
+	if err := logRows.Err(); err != nil {
+		return nil, fmt.Errorf("greška nakon iteracije kroz log webhook isporuka: %w", err)
+	}
+	return entries, nil
+}
+
+// RetryWebhookDelivery requeues a dead-lettered delivery for immediate retry.
+func (s *SQLDataset) RetryWebhookDelivery(ctx context.Context, subscriptionID, outboxID int64) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, attempts = 0, next_attempt_at = now()
+		WHERE id = $2 AND subscription_id = $3 AND status = $4`, webhookOutboxTable)
+	res, err := s.db.ExecContext(ctx, query, WebhookPending, outboxID, subscriptionID, WebhookDead)
+	if err != nil {
+		return fmt.Errorf("greška pri ponovnom zakazivanju webhook isporuke '%d': %w", outboxID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errRecordNotFound("dead letter isporuka '%d' nije pronađena za pretplatu '%d'", outboxID, subscriptionID)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the value of the X-Webhook-Signature header:
+// HMAC-SHA256 nad "<timestamp>.<body>" sa tajnom pretplate, u hex zapisu.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookWorker delivers queued webhook events in the background.
+type WebhookWorker struct {
+	dataset   *SQLDataset
+	config    WebhookConfig
+	client    *http.Client
+	batchSize int
+}
+
+// NewWebhookWorker creates a worker for the dataset's outbox.
+// Unless AllowPrivateTargets is set, the worker refuses to connect to internal addresses.
+func NewWebhookWorker(dataset *SQLDataset, config WebhookConfig) *WebhookWorker {
+	config.applyDefaults()
+	dialer := &net.Dialer{Timeout: time.Duration(config.TimeoutSeconds) * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.AllowPrivateTargets {
+		// Proxy bi zaobišao proveru adrese, pa se isporuka uvek povezuje direktno
+		transport.Proxy = nil
+		// Adresa se proverava tek posle DNS razrešavanja, pa ni DNS rebinding ni preusmerenje
+		// ne mogu da odvedu isporuku na internu adresu
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateAddress(ip) {
+				return errPrivateWebhookTarget
+			}
+			return nil
+		}
+	}
+	transport.DialContext = dialer.DialContext
+	return &WebhookWorker{
+		dataset:   dataset,
+		config:    config,
+		client:    &http.Client{Timeout: time.Duration(config.TimeoutSeconds) * time.Second, Transport: transport},
+		batchSize: 20,
+	}
+}
+
+// Run processes the outbox until ctx is cancelled.
+func (w *WebhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(w.config.PollIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	log.Println("INFO: Webhook worker pokrenut.")
+	for {
+		// Obrađuj dok ima spremnih isporuka, pa sačekaj sledeći tick
+		for {
+			n, err := w.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("ERROR: Greška u webhook worker-u: %v", err)
+				break
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("INFO: Webhook worker zaustavljen.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimedDelivery je stavka outbox-a preuzeta za isporuku.
+type claimedDelivery struct {
+	id        int64
+	event     string
+	payload   []byte
+	attempts  int
+	targetURL string
+	secret    string
+}
+
+// ProcessBatch claims up to one batch of due deliveries and attempts them.
+// Vraća broj obrađenih stavki.
+func (w *WebhookWorker) ProcessBatch(ctx context.Context) (int, error) {
+	// Stavke se "zakupljuju" pomeranjem next_attempt_at, pa ih drugi worker
+	// ne preuzima dok traje HTTP poziv, a posle pada procesa se ponovo isporučuju.
+	lease := w.config.TimeoutSeconds * 2
+	query := fmt.Sprintf(`UPDATE %s o SET next_attempt_at = now() + make_interval(secs => $1)
+		FROM %s sub
+		WHERE sub.id = o.subscription_id AND o.id IN (
+			SELECT id FROM %s WHERE status = $2 AND next_attempt_at <= now()
+			ORDER BY id LIMIT $3 FOR UPDATE SKIP LOCKED)
+		RETURNING o.id, o.event, o.payload, o.attempts, sub.target_url, sub.secret`,
+		webhookOutboxTable, webhookSubscriptionsTable, webhookOutboxTable)
+
+	rows, err := w.dataset.db.QueryContext(ctx, query, lease, WebhookPending, w.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("greška pri preuzimanju webhook isporuka: %w", err)
+	}
+	claimed := make([]claimedDelivery, 0)
+	for rows.Next() {
+		var d claimedDelivery
+		if err := rows.Scan(&d.id, &d.event, &d.payload, &d.attempts, &d.targetURL, &d.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("greška pri skeniranju webhook isporuke: %w", err)
+		}
+		claimed = append(claimed, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("greška nakon iteracije kroz webhook isporuke: %w", err)
+	}
+
+	for _, d := range claimed {
+		if err := w.deliver(ctx, d); err != nil {
+			log.Printf("ERROR: Greška pri beleženju webhook isporuke '%d': %v", d.id, err)
+		}
+	}
+	return len(claimed), nil
+}
+
+// deliver šalje jednu isporuku i beleži ishod u outbox i log isporuka.
+func (w *WebhookWorker) deliver(ctx context.Context, d claimedDelivery) error {
+	attempt := d.attempts + 1
+	started := time.Now()
+	statusCode, deliveryErr := w.send(ctx, d)
+	duration := time.Since(started).Milliseconds()
+
+	var errText, statusVal interface{}
+	if deliveryErr != nil {
+		errText = deliveryErr.Error()
+	}
+	if statusCode != 0 {
+		statusVal = statusCode
+	}
+
+	return w.dataset.withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		logQuery := fmt.Sprintf("INSERT INTO %s (outbox_id, attempt, status_code, error, duration_ms) VALUES ($1, $2, $3, $4, $5)", webhookDeliveriesTable)
+		if _, err := tx.ExecContext(ctx, logQuery, d.id, attempt, statusVal, errText, duration); err != nil {
+			return err
+		}
+
+		if deliveryErr == nil {
+			log.Printf("INFO: Webhook isporuka '%d' (%s) uspešna posle %d pokušaja.", d.id, d.event, attempt)
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET status = $1, attempts = $2, delivered_at = now(), last_error = NULL WHERE id = $3", webhookOutboxTable),
+				WebhookDelivered, attempt, d.id)
+			return err
+		}
+
+		if attempt >= w.config.MaxAttempts {
+			log.Printf("WARNING: Webhook isporuka '%d' prebačena u dead letter posle %d pokušaja: %v", d.id, attempt, deliveryErr)
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET status = $1, attempts = $2, last_error = $3 WHERE id = $4", webhookOutboxTable),
+				WebhookDead, attempt, errText, d.id)
+			return err
+		}
+
+		delay := w.backoff(attempt)
+		log.Printf("WARNING: Webhook isporuka '%d' neuspešna (pokušaj %d), sledeći za %v: %v", d.id, attempt, delay, deliveryErr)
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET attempts = $1, last_error = $2, next_attempt_at = now() + make_interval(secs => $3) WHERE id = $4", webhookOutboxTable),
+			attempt, errText, delay.Seconds(), d.id)
+		return err
+	})
+}
+
+// send izvršava HTTP POST; svaki odgovor van 2xx opsega smatra se neuspehom.
+func (w *WebhookWorker) send(ctx context.Context, d claimedDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.targetURL, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, d.event)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(d.id, 10))
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	if d.secret != "" {
+		req.Header.Set(webhookSignatureHeader, SignWebhookPayload(d.secret, timestamp, d.payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("odgovor %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff vraća kašnjenje pre sledećeg pokušaja: base * 2^(attempt-1), najviše max.
+func (w *WebhookWorker) backoff(attempt int) time.Duration {
+	delay := time.Duration(w.config.BackoffBaseSeconds) * time.Second
+	limit := time.Duration(w.config.BackoffMaxSeconds) * time.Second
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// authorizeWebhookAdmin proverava da li korisnik sme da upravlja webhook pretplatama.
+// Bez podešenih admin uloga upravljanje pretplatama nije dozvoljeno nikome.
+func (s *APIServer) authorizeWebhookAdmin(w http.ResponseWriter, req *http.Request) bool {
+	roles := s.config.Config.Webhooks.AdminRoles
+	if len(roles) > 0 && UserFromContext(req.Context()).HasAnyRole(roles) {
+		return true
+	}
+	writeError(w, "Nemate dozvolu za upravljanje webhook pretplatama.", http.StatusForbidden)
+	return false
+}
+
+// parseWebhookID čita numerički ID iz URL promenljive.
+func parseWebhookID(w http.ResponseWriter, req *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(req)[name], 10, 64)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Nevažeći ID '%s': %v", mux.Vars(req)[name], err), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// ListWebhooks handles requests to list webhook subscriptions.
+func (s *APIServer) ListWebhooks(w http.ResponseWriter, req *http.Request) {
+	if !s.authorizeWebhookAdmin(w, req) {
+		return
+	}
+	subs, err := s.dataset.ListWebhookSubscriptions(req.Context())
+	if err != nil {
+		writeInternalError(w, "Greška pri dohvatanju webhook pretplata", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(subs); err != nil {
+		writeInternalError(w, "Greška pri enkodiranju webhook pretplata", err)
+	}
+}
+
+// CreateWebhook handles requests to subscribe a URL to module changes.
+func (s *APIServer) CreateWebhook(w http.ResponseWriter, req *http.Request) {
+	if !s.authorizeWebhookAdmin(w, req) {
+		return
+	}
+	var sub WebhookSubscription
+	if err := json.NewDecoder(req.Body).Decode(&sub); err != nil {
+		writeError(w, fmt.Sprintf("Greška pri dekodiranju payload-a: %v", err), http.StatusBadRequest)
+		return
+	}
+	// Pretplata nasleđuje prava korisnika koji je kreira; klijent ih ne može sam postaviti
+	user := UserFromContext(req.Context())
+	sub.OwnerID, sub.OwnerRoles = user.ID, user.Roles
+	if moduleDef := s.config.GetModuleByID(sub.ModuleID); moduleDef != nil && !moduleDef.UserCan(user, PermRead) {
+		writeErrorCode(w, ErrCodeForbidden, fmt.Sprintf("Nemate dozvolu za čitanje modula '%s'.", sub.ModuleID), http.StatusForbidden)
+		return
+	}
+	created, err := s.dataset.CreateWebhookSubscription(req.Context(), sub)
+	if err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, "Greška pri kreiranju webhook pretplate", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora za CreateWebhook: %v", err)
+	}
+	log.Printf("INFO: Kreirana webhook pretplata '%d' za modul '%s' -> %s.", created.ID, created.ModuleID, created.TargetURL)
+}
+
+// DeleteWebhook handles requests to remove a webhook subscription.
+func (s *APIServer) DeleteWebhook(w http.ResponseWriter, req *http.Request) {
+	if !s.authorizeWebhookAdmin(w, req) {
+		return
+	}
+	id, ok := parseWebhookID(w, req, "webhookID")
+	if !ok {
+		return
+	}
+	if err := s.dataset.DeleteWebhookSubscription(req.Context(), id); err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, "Greška pri brisanju webhook pretplate", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Webhook pretplata uspešno obrisana"}); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora za DeleteWebhook: %v", err)
+	}
+}
+
+// GetWebhookDeliveries handles requests to browse the delivery log of a subscription
+// (optionally only dead letters with ?status=dead).
+func (s *APIServer) GetWebhookDeliveries(w http.ResponseWriter, req *http.Request) {
+	if !s.authorizeWebhookAdmin(w, req) {
+		return
+	}
+	id, ok := parseWebhookID(w, req, "webhookID")
+	if !ok {
+		return
+	}
+
+	query := req.URL.Query()
+	limit, offset := 50, 0
+	if v := query.Get("_limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l >= 0 {
+			limit = l
+		}
+	}
+	if v := query.Get("_offset"); v != "" {
+		if o, err := strconv.Atoi(v); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	entries, err := s.dataset.ListWebhookOutbox(req.Context(), id, query.Get("status"), limit, offset)
+	if err != nil {
+		writeInternalError(w, "Greška pri dohvatanju webhook isporuka", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		writeInternalError(w, "Greška pri enkodiranju webhook isporuka", err)
+	}
+}
+
+// RetryWebhookDelivery handles requests to requeue a dead-lettered delivery.
+func (s *APIServer) RetryWebhookDelivery(w http.ResponseWriter, req *http.Request) {
+	if !s.authorizeWebhookAdmin(w, req) {
+		return
+	}
+	subID, ok := parseWebhookID(w, req, "webhookID")
+	if !ok {
+		return
+	}
+	outboxID, ok := parseWebhookID(w, req, "deliveryID")
+	if !ok {
+		return
+	}
+	if err := s.dataset.RetryWebhookDelivery(req.Context(), subID, outboxID); err != nil {
+		if writeClientError(w, err) {
+			return
+		}
+		writeInternalError(w, "Greška pri ponovnom zakazivanju isporuke", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"message": "Isporuka ponovo zakazana"}); err != nil {
+		log.Printf("ERROR: Greška pri enkodiranju odgovora za RetryWebhookDelivery: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+// webhooks_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestWebhookWorkerSendSignsPayload(t *testing.T) {
+	var gotBody []byte
+	var gotHeader http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotBody, _ = io.ReadAll(req.Body)
+		gotHeader = req.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	worker := NewWebhookWorker(nil, WebhookConfig{AllowPrivateTargets: true})
+	payload := []byte(`{"event":"created","record_id":"7"}`)
+	status, err := worker.send(context.Background(), claimedDelivery{id: 42, event: "created", payload: payload, targetURL: receiver.URL, secret: "tajna"})
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send = %d, %v; očekivano 204 bez greške", status, err)
+	}
+	if string(gotBody) != string(payload) {
+		t.Errorf("telo = %s, očekivano %s", gotBody, payload)
+	}
+	if gotHeader.Get(webhookEventHeader) != "created" || gotHeader.Get(webhookDeliveryHeader) != "42" {
+		t.Errorf("neočekivana zaglavlja događaja: %v", gotHeader)
+	}
+	ts, err := strconv.ParseInt(gotHeader.Get(webhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("nevažeći timestamp: %v", err)
+	}
+	if want := SignWebhookPayload("tajna", ts, payload); gotHeader.Get(webhookSignatureHeader) != want {
+		t.Errorf("potpis = %q, očekivano %q", gotHeader.Get(webhookSignatureHeader), want)
+	}
+}
+
+func TestWebhookWorkerSendReportsReceiverErrors(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	worker := NewWebhookWorker(nil, WebhookConfig{AllowPrivateTargets: true})
+	status, err := worker.send(context.Background(), claimedDelivery{id: 1, event: "updated", payload: []byte(`{}`), targetURL: receiver.URL})
+	if err == nil || status != http.StatusServiceUnavailable {
+		t.Errorf("send = %d, %v; očekivana greška sa statusom 503", status, err)
+	}
+}
+
+func TestWebhookWorkerRefusesPrivateTargets(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	worker := NewWebhookWorker(nil, WebhookConfig{})
+	_, err := worker.send(context.Background(), claimedDelivery{id: 1, event: "created", payload: []byte(`{}`), targetURL: receiver.URL})
+	if !errors.Is(err, errPrivateWebhookTarget) {
+		t.Errorf("greška = %v, očekivano %v", err, errPrivateWebhookTarget)
+	}
+	if called {
+		t.Error("isporuka na loopback adresu ne sme da stigne do primaoca")
+	}
+}
+
+func TestValidateWebhookTarget(t *testing.T) {
+	tests := []struct {
+		target       string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"https://93.184.216.34/hook", false, false},
+		{"http://127.0.0.1:8080/hook", false, true},
+		{"http://10.1.2.3/hook", false, true},
+		{"http://169.254.169.254/latest/meta-data", false, true},
+		{"http://[::1]/hook", false, true},
+		{"http://0.0.0.0/hook", false, true},
+		{"http://127.0.0.1:8080/hook", true, false},
+		{"ftp://93.184.216.34/hook", false, true},
+		{"/relative", true, true},
+	}
+	for _, tt := range tests {
+		err := validateWebhookTarget(context.Background(), tt.target, tt.allowPrivate)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateWebhookTarget(%q, %v) = %v, očekivana greška: %v", tt.target, tt.allowPrivate, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookEventHidesUnreadableColumns(t *testing.T) {
+	module := &ModuleDefinition{ID: "employees", Columns: []ColumnDefinition{
+		{DBColumnName: "id", Type: "integer"},
+		{DBColumnName: "name", Type: "string"},
+		{DBColumnName: "salary", Type: "number", ReadRoles: []string{"hr"}},
+	}}
+	before := map[string]interface{}{"id": 1, "name": "Ana", "salary": 100}
+	after := map[string]interface{}{"id": 1, "name": "Ana M.", "salary": 200}
+
+	event := webhookEventFor(module, &User{ID: "ops", Roles: []string{"ops"}}, WebhookEvent{Event: "updated"}, before, after)
+	if _, ok := event.Record["salary"]; ok {
+		t.Errorf("zapis sadrži skrivenu kolonu: %v", event.Record)
+	}
+	if _, ok := event.Changes["salary"]; ok {
+		t.Errorf("izmene sadrže skrivenu kolonu: %v", event.Changes)
+	}
+	if _, ok := event.Changes["name"]; !ok {
+		t.Errorf("izmene ne sadrže vidljivu kolonu: %v", event.Changes)
+	}
+	if after["salary"] != 200 {
+		t.Error("originalni zapis ne sme da se menja")
+	}
+
+	event = webhookEventFor(module, &User{ID: "hr", Roles: []string{"hr"}}, WebhookEvent{Event: "deleted"}, before, nil)
+	if event.Record["salary"] != 100 {
+		t.Errorf("vlasnik sa ulogom hr mora da vidi platu: %v", event.Record)
+	}
+}
+
+func TestAuthorizeWebhookAdmin(t *testing.T) {
+	tests := []struct {
+		adminRoles []string
+		user       *User
+		want       bool
+	}{
+		{nil, nil, false},
+		{nil, &User{ID: "admin", Roles: []string{"admin"}}, false},
+		{[]string{"admin"}, nil, false},
+		{[]string{"admin"}, &User{ID: "ana", Roles: []string{"sales"}}, false},
+		{[]string{"admin"}, &User{ID: "admin", Roles: []string{"admin"}}, true},
+	}
+	for _, tt := range tests {
+		s := &APIServer{config: &AppConfig{Config: Config{Webhooks: WebhookConfig{AdminRoles: tt.adminRoles}}}}
+		req := httptest.NewRequest(http.MethodGet, "/api/webhooks", nil)
+		if tt.user != nil {
+			req = req.WithContext(WithUser(req.Context(), tt.user))
+		}
+		rec := httptest.NewRecorder()
+		if got := s.authorizeWebhookAdmin(rec, req); got != tt.want {
+			t.Errorf("authorizeWebhookAdmin(roles=%v, user=%v) = %v, očekivano %v", tt.adminRoles, tt.user, got, tt.want)
+		}
+		if !tt.want && rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, očekivano 403", rec.Code)
+		}
+	}
+}
+
+func TestWebhookOutboxRespectsOwnerRowFilter(t *testing.T) {
+	accounts := &ModuleDefinition{ID: "test_wh_accounts", Name: "Nalozi", Type: "table", DBTableName: "test_wh_accounts",
+		Columns: []ColumnDefinition{
+			{DBColumnName: "id", Name: "ID", Type: "integer", IsPrimaryKey: true, IsVisible: true, IsReadOnly: true},
+			{DBColumnName: "owner", Name: "Vlasnik", Type: "string", IsVisible: true, IsEditable: true},
+		}}
+	ds := newTestDataset(t, accounts)
+	ds.config.Config.Webhooks.AdminRoles = []string{"admin"}
+	createTestTable(t, ds, accounts.DBTableName, "id SERIAL PRIMARY KEY, owner TEXT NOT NULL")
+	ds.Hooks.RowFilter(accounts.ID, func(ctx context.Context, m *ModuleDefinition) (string, []interface{}, error) {
+		return "owner = $1", []interface{}{UserFromContext(ctx).ID}, nil
+	})
+	var marko, tester int64
+	for owner, id := range map[string]*int64{"marko": &marko, "tester": &tester} {
+		err := ds.db.QueryRow(`INSERT INTO webhook_subscriptions (module_id, events, target_url, secret, owner_id, owner_roles)
+			VALUES ($1, '{*}', 'https://example.com/hook', 'tajna', $2, '{}') RETURNING id`, accounts.ID, owner).Scan(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { ds.db.Exec("DELETE FROM webhook_subscriptions WHERE module_id = $1", accounts.ID) })
+	s := NewAPIServer(ds.config, ds)
+
+	// Zapis pripada korisniku "marko", pa ga pretplata korisnika "tester" ne sme dobiti
+	if rec := serveTest(s, http.MethodPost, "/api/modules/test_wh_accounts", `{"owner": "marko"}`, "", nil); rec.Code != http.StatusCreated {
+		t.Fatalf("kreiranje: status %d, telo %s", rec.Code, rec.Body)
+	}
+	for subID, want := range map[int64]int{marko: 1, tester: 0} {
+		var n int
+		if err := ds.db.QueryRow("SELECT count(*) FROM webhook_outbox WHERE subscription_id = $1", subID).Scan(&n); err != nil || n != want {
+			t.Errorf("pretplata %d: %d događaja, očekivano %d (%v)", subID, n, want, err)
+		}
+	}
+
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodDelete, "/api/webhooks/999999999"},
+		{http.MethodPost, "/api/webhooks/" + strconv.FormatInt(marko, 10) + "/deliveries/999999999/retry"},
+	} {
+		if rec := serveTest(s, tt.method, tt.path, "", "admin", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d, očekivano 404; telo %s", tt.method, tt.path, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// xlsx.go
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statički delovi XLSX paketa (jedan radni list, jedan stil za podebljano zaglavlje).
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter upisuje XLSX datoteku sa jednim radnim listom red po red, bez
+// držanja celog sadržaja u memoriji (stringovi se upisuju kao inline stringovi).
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+// newXLSXWriter upisuje statičke delove paketa i otvara radni list.
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(xlsxSheetName(sheetName)))},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("greška pri kreiranju XLSX dela '%s': %w", part.name, err)
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, fmt.Errorf("greška pri upisu XLSX dela '%s': %w", part.name, err)
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("greška pri kreiranju XLSX radnog lista: %w", err)
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zw: zw, sheet: sheet}, nil
+}
+
+// WriteHeader upisuje red zaglavlja podebljanim fontom.
+func (x *xlsxWriter) WriteHeader(headers []string) error {
+	cells := make([]interface{}, len(headers))
+	for i, h := range headers {
+		cells[i] = h
+	}
+	return x.writeRow(cells, 1)
+}
+
+// WriteRow upisuje jedan red podataka.
+func (x *xlsxWriter) WriteRow(cells []interface{}) error {
+	return x.writeRow(cells, 0)
+}
+
+func (x *xlsxWriter) writeRow(cells []interface{}, style int) error {
+	x.row++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+	for i, cell := range cells {
+		ref := xlsxColumnName(i) + strconv.Itoa(x.row)
+		styleAttr := ""
+		if style > 0 {
+			styleAttr = fmt.Sprintf(` s="%d"`, style)
+		}
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case bool:
+			val := "0"
+			if v {
+				val = "1"
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="b"%s><v>%s</v></c>`, ref, styleAttr, val)
+		case int, int32, int64, float32, float64:
+			fmt.Fprintf(&b, `<c r="%s"%s><v>%v</v></c>`, ref, styleAttr, v)
+		default:
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, xmlEscape(fmt.Sprint(v)))
+		}
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+// Close zatvara radni list i ZIP arhivu.
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// xlsxColumnName pretvara indeks kolone (od 0) u oznaku kolone (A, B, ..., Z, AA, ...).
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxSheetName uklanja znakove koje Excel ne dozvoljava u imenu lista i skraćuje ga na 31 znak.
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+// xmlEscape escapuje tekst za XML (nevažeći znakovi se zamenjuju sa U+FFFD).
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Ograničenja pri čitanju XLSX datoteke: ZIP kompresija dozvoljava da mala datoteka
+// opiše ogroman radni list, pa se redovi, kolone i dopunjene prazne ćelije ograničavaju.
+const (
+	xlsxMaxRows     = 100000    // Najveći broj reda (uključujući prazne redove)
+	xlsxMaxColumns  = 512       // Najveći broj kolona u redu
+	xlsxMaxCells    = 2000000   // Ukupno ćelija, zajedno sa dopunjenim praznim ćelijama
+	xlsxMaxPartSize = 128 << 20 // Najveća raspakovana veličina XML dela (radni list, deljeni stringovi, stilovi)
+)
+
+// xlsxCell je ćelija radnog lista pri čitanju.
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Style  int    `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+// xlsxRow je red radnog lista pri čitanju.
+type xlsxRow struct {
+	Index int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+// readXLSXRows čita prvi radni list XLSX datoteke kao tekstualne ćelije.
+// Prazni redovi se zadržavaju, da bi broj reda odgovarao onom u Excel-u. Numeričke ćelije
+// formatirane kao datum vraćaju se kao datum ("2006-01-02") ili vreme ("2006-01-02 15:04:05").
+func readXLSXRows(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("datoteka nije validan XLSX: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	shared, err := readXLSXSharedStrings(files["xl/sharedStrings.xml"])
+	if err != nil {
+		return nil, err
+	}
+	dateStyles := readXLSXDateStyles(files["xl/styles.xml"])
+
+	sheetFile := files[xlsxFirstSheetPath(files)]
+	if sheetFile == nil {
+		return nil, fmt.Errorf("XLSX datoteka nema radni list")
+	}
+	if sheetFile.UncompressedSize64 > xlsxMaxPartSize {
+		return nil, fmt.Errorf("XLSX radni list je veći od %d bajtova", xlsxMaxPartSize)
+	}
+	rc, err := sheetFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("greška pri otvaranju XLSX radnog lista: %w", err)
+	}
+	defer rc.Close()
+
+	rows := make([][]string, 0)
+	cells := 0
+	// Veličina iz ZIP zaglavlja može biti lažna, pa se i samo čitanje ograničava
+	dec := xml.NewDecoder(io.LimitReader(rc, xlsxMaxPartSize))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("greška pri čitanju XLSX radnog lista: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row xlsxRow
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("greška pri čitanju XLSX reda: %w", err)
+		}
+		if row.Index > xlsxMaxRows || len(rows) >= xlsxMaxRows {
+			return nil, fmt.Errorf("XLSX radni list ima više od %d redova", xlsxMaxRows)
+		}
+		for row.Index > len(rows)+1 { // Preskočeni (prazni) redovi
+			rows = append(rows, nil)
+		}
+
+		values := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			col := len(values)
+			if cell.Ref != "" {
+				col = xlsxColumnIndex(cell.Ref)
+			}
+			if col < 0 || col >= xlsxMaxColumns {
+				return nil, fmt.Errorf("ćelija '%s' je van dozvoljenih %d kolona", cell.Ref, xlsxMaxColumns)
+			}
+			for len(values) < col {
+				values = append(values, "")
+			}
+			values = append(values, xlsxCellText(cell, shared, dateStyles))
+		}
+		if cells += len(values); cells > xlsxMaxCells {
+			return nil, fmt.Errorf("XLSX radni list ima više od %d ćelija", xlsxMaxCells)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// xlsxCellText vraća tekstualnu vrednost ćelije prema njenom tipu.
+func xlsxCellText(cell xlsxCell, shared []string, dateStyles map[int]bool) string {
+	switch cell.Type {
+	case "s":
+		if idx, err := strconv.Atoi(cell.Value); err == nil && idx >= 0 && idx < len(shared) {
+			return shared[idx]
+		}
+		return ""
+	case "inlineStr":
+		text := cell.Inline.Text
+		for _, run := range cell.Inline.Runs {
+			text += run.Text
+		}
+		return text
+	case "b":
+		if cell.Value == "1" {
+			return "true"
+		}
+		return "false"
+	case "", "n":
+		if dateStyles[cell.Style] {
+			if serial, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+				t := excelSerialToTime(serial)
+				if serial == math.Trunc(serial) {
+					return t.Format(time.DateOnly)
+				}
+				return t.Format(time.DateTime)
+			}
+		}
+		return cell.Value
+	default: // str, e
+		return cell.Value
+	}
+}
+
+// readXLSXDateStyles vraća indekse stilova ćelija (cellXfs) čiji format broja prikazuje datum ili vreme.
+// Datoteka bez stilova ili sa nečitljivim stilovima nema datumske ćelije.
+func readXLSXDateStyles(f *zip.File) map[int]bool {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if readXLSXPart(f, &styles) != nil {
+		return nil
+	}
+	custom := make(map[int]bool, len(styles.NumFmts))
+	for _, fmtDef := range styles.NumFmts {
+		custom[fmtDef.ID] = xlsxIsDateFormat(fmtDef.Code)
+	}
+	dateStyles := make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || custom[id] {
+			dateStyles[i] = true
+		}
+	}
+	return dateStyles
+}
+
+// xlsxIsDateFormat proverava da li kod formata broja (npr. "dd.mm.yyyy") sadrži delove datuma ili
+// vremena, zanemarujući tekst pod navodnicima, escapovane znakove i sekcije u zagradama ([Red], [$-409]).
+func xlsxIsDateFormat(code string) bool {
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++ // Sledeći znak je literal (ili širina/popuna)
+		default:
+			if strings.ContainsRune("dmyhsDMYHS", rune(c)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readXLSXSharedStrings čita tabelu deljenih stringova (može da ne postoji).
+func readXLSXSharedStrings(f *zip.File) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if f.UncompressedSize64 > xlsxMaxPartSize {
+		return nil, fmt.Errorf("XLSX deljeni stringovi su veći od %d bajtova", xlsxMaxPartSize)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("greška pri otvaranju XLSX deljenih stringova: %w", err)
+	}
+	defer rc.Close()
+
+	var sst struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(rc, xlsxMaxPartSize)).Decode(&sst); err != nil {
+		return nil, fmt.Errorf("greška pri čitanju XLSX deljenih stringova: %w", err)
+	}
+	shared := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		text := item.Text
+		for _, run := range item.Runs {
+			text += run.Text
+		}
+		shared[i] = text
+	}
+	return shared, nil
+}
+
+// xlsxFirstSheetPath pronalazi putanju prvog radnog lista preko workbook.xml i njegovih veza.
+func xlsxFirstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if readXLSXPart(files["xl/workbook.xml"], &workbook) != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	if readXLSXPart(files["xl/_rels/workbook.xml.rels"], &rels) != nil {
+		return fallback
+	}
+	for _, rel := range rels.Items {
+		if rel.ID == workbook.Sheets[0].RelID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return "xl/" + rel.Target
+		}
+	}
+	return fallback
+}
+
+// readXLSXPart dekodira XML deo paketa u v.
+func readXLSXPart(f *zip.File, v interface{}) error {
+	if f == nil {
+		return fmt.Errorf("deo paketa ne postoji")
+	}
+	if f.UncompressedSize64 > xlsxMaxPartSize {
+		return fmt.Errorf("deo paketa '%s' je veći od %d bajtova", f.Name, xlsxMaxPartSize)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, xlsxMaxPartSize)).Decode(v)
+}
+
+// xlsxColumnIndex vraća indeks kolone (od 0) iz reference ćelije (npr. "AB12" -> 27).
+// Za referencu bez oznake kolone ili sa više od tri slova (van opsega Excel-a) vraća -1.
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for i, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		if i == 3 {
+			return -1
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
@@ -0,0 +1,143 @@
+// xlsx_test.go
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildXLSX pravi XLSX paket sa datim radnim listom (sadržaj sheetData) i stilovima.
+func buildXLSX(t *testing.T, sheetData, styles string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/worksheets/sheet1.xml": xlsxSheetStart + sheetData + xlsxSheetEnd,
+	}
+	if styles != "" {
+		parts["xl/styles.xml"] = styles
+	}
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSXRowsRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	x, err := newXLSXWriter(&buf, "Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.WriteHeader([]string{"name", "qty"})
+	x.WriteRow([]interface{}{"Ana", 3})
+	x.WriteRow([]interface{}{nil, 4.5})
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := readXLSXRows(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"name", "qty"}, {"Ana", "3"}, {"", "4.5"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("redovi = %q, očekivano %q", rows, want)
+	}
+}
+
+func TestReadXLSXRowsConvertsOnlyDateStyledCells(t *testing.T) {
+	styles := `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="dd\.mm\.yyyy hh:mm"/></numFmts>` +
+		`<cellXfs count="4"><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="4"/></cellXfs></styleSheet>`
+	data := buildXLSX(t, `<row r="1"><c r="A1"><v>45292</v></c><c r="B1" s="1"><v>45292</v></c><c r="C1" s="2"><v>45292.5</v></c><c r="D1" s="3"><v>45292</v></c></row>`, styles)
+
+	rows, err := readXLSXRows(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"45292", "2024-01-01", "2024-01-01 12:00:00", "45292"}
+	if !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("ćelije = %q, očekivano %q", rows[0], want)
+	}
+
+	// CSV vrednost koja liči na serijski broj ostaje tekst
+	if v, err := convertImportValue(&ColumnDefinition{Type: "date"}, "45292"); err != nil || v != "45292" {
+		t.Errorf("convertImportValue(date, 45292) = %v, %v", v, err)
+	}
+}
+
+func TestReadXLSXRowsRejectsOversizedSheets(t *testing.T) {
+	for name, sheet := range map[string]string{
+		"daleka kolona":   `<row r="1"><c r="XFD1"><v>1</v></c></row>`,
+		"dalek red":       `<row r="1048576"><c r="A1048576"><v>1</v></c></row>`,
+		"predugačka ref.": `<row r="1"><c r="ZZZZZZZZZZZZZZ1"><v>1</v></c></row>`,
+		"previše ćelija":  strings.Repeat(`<row><c r="SR1"><v>1</v></c></row>`, xlsxMaxCells/xlsxMaxColumns+2),
+	} {
+		data := buildXLSX(t, sheet, "")
+		if _, err := readXLSXRows(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("%s: očekivana greška", name)
+		}
+	}
+}
+
+func TestReadXLSXRowsRejectsOversizedSheetPart(t *testing.T) {
+	// Jedna ćelija sa ogromnim tekstom: malo u ZIP-u, ali raspakovano veće od xlsxMaxPartSize
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, xlsxSheetStart+`<row r="1"><c r="A1" t="inlineStr"><is><t>`)
+	if _, err := io.CopyN(f, repeatReader('a'), xlsxMaxPartSize); err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, `</t></is></c></row>`+xlsxSheetEnd)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readXLSXRows(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Error("očekivana greška za preveliki radni list")
+	}
+}
+
+// repeatReader beskonačno ponavlja isti bajt.
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestXLSXIsDateFormat(t *testing.T) {
+	tests := map[string]bool{
+		"dd.mm.yyyy":      true,
+		"h:mm AM/PM":      true,
+		"0.00":            false,
+		"#,##0 \"din\"":   false,
+		"[Red]0.00":       false,
+		"0.00\\d":         false,
+		"[$-409]d-mmm-yy": true,
+		"General":         false,
+		"\"Dan: \"0":      false,
+	}
+	for code, want := range tests {
+		if got := xlsxIsDateFormat(code); got != want {
+			t.Errorf("xlsxIsDateFormat(%q) = %v, očekivano %v", code, got, want)
+		}
+	}
+}